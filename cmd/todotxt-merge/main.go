@@ -0,0 +1,68 @@
+/*
+Command todotxt-merge is a git merge driver for todo.txt files.
+
+It performs a three-way merge of the tasks (see todo.MergeFiles) instead of a
+line based merge, so that, for example, one side completing a task and the
+other side changing its priority merge cleanly. Comments and blank lines are
+merged line by line, so that the lines which were not changed are kept.
+
+Usage:
+
+	todotxt-merge <base> <ours> <theirs>
+
+The merged result is written to <ours>. The exit status is 0 on a clean merge,
+1 if there are conflicts (the conflicting tasks are surrounded by comment lines
+in git's conflict marker style) and 2 on errors.
+
+To register it as a merge driver:
+
+	git config merge.todotxt.name "todo.txt three-way merge"
+	git config merge.todotxt.driver "todotxt-merge %O %A %B"
+	echo "todo.txt merge=todotxt" >> .gitattributes
+*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/KEINOS/go-todotxt/todo"
+)
+
+// Exit statuses of the command.
+const (
+	exitClean    = 0
+	exitConflict = 1
+	exitError    = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+// run merges the files given in args and returns the exit status.
+func run(args []string, stderr io.Writer) int {
+	const numArgs = 3
+
+	if len(args) != numArgs {
+		_, _ = fmt.Fprintln(stderr, "usage: todotxt-merge <base> <ours> <theirs>")
+
+		return exitError
+	}
+
+	result, err := todo.MergeFiles(args[0], args[1], args[2])
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, "todotxt-merge:", err)
+
+		return exitError
+	}
+
+	if result.HasConflicts() {
+		_, _ = fmt.Fprintf(stderr, "todotxt-merge: %d conflicting task(s) in %s\n", len(result.Conflicts), args[1])
+
+		return exitConflict
+	}
+
+	return exitClean
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testWriteFiles writes base, ours and theirs files and returns their paths.
+func testWriteFiles(t *testing.T, base, ours, theirs string) []string {
+	t.Helper()
+
+	pathDir := t.TempDir()
+	paths := []string{}
+
+	for i, contents := range []string{base, ours, theirs} {
+		path := filepath.Join(pathDir, []string{"base", "ours", "theirs"}[i]+".txt")
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+
+		paths = append(paths, path)
+	}
+
+	return paths
+}
+
+func Test_run_clean(t *testing.T) {
+	t.Parallel()
+
+	paths := testWriteFiles(t, "Call Mom\n", "(A) Call Mom\n", "Call Mom @Phone\n")
+
+	var stderr bytes.Buffer
+
+	require.Equal(t, exitClean, run(paths, &stderr))
+	require.Empty(t, stderr.String())
+
+	raw, err := os.ReadFile(paths[1])
+	require.NoError(t, err)
+	require.Contains(t, string(raw), "(A) Call Mom @Phone")
+}
+
+func Test_run_conflict(t *testing.T) {
+	t.Parallel()
+
+	paths := testWriteFiles(t, "Call Mom\n", "(A) Call Mom\n", "(B) Call Mom\n")
+
+	var stderr bytes.Buffer
+
+	require.Equal(t, exitConflict, run(paths, &stderr))
+	require.Contains(t, stderr.String(), "1 conflicting task(s)")
+}
+
+func Test_run_errors(t *testing.T) {
+	t.Parallel()
+
+	var stderr bytes.Buffer
+
+	require.Equal(t, exitError, run([]string{"only-one"}, &stderr))
+	require.Contains(t, stderr.String(), "usage:")
+
+	stderr.Reset()
+
+	require.Equal(t, exitError, run([]string{"unknown1", "unknown2", "unknown3"}, &stderr))
+	require.Contains(t, stderr.String(), "failed to open file")
+}
@@ -0,0 +1,292 @@
+/*
+Command todotxt-tui is a full-screen terminal UI to browse and edit a todo.txt
+file.
+
+Usage:
+
+	todotxt-tui [file]
+
+If no file is given, the todo.txt file located by TODO_DIR and TODO_FILE is
+used (see todo.OpenWorkspaceFromEnv).
+
+Keys:
+
+	j, k, arrows  move the selection (g, G, PgUp and PgDn as well)
+	/             filter the tasks by terms, "-term" to exclude
+	s             cycle the sort order
+	x, space      complete or reopen the task
+	p             set the priority: a letter, or "-" to remove it
+	e, enter      edit the task in place
+	n             add a task
+	u, U          undo or redo the last change
+	r             reload the file
+	q, ctrl-c     quit
+
+The tasks are highlighted by their segments with the todo.sh colour variables
+(see todo.Theme.WithEnv), unless NO_COLOR is set. Each change is saved at once
+with todo.UpdateLinesPath and todo.PatchLines, so that the comments and blank
+lines of the file, and the line numbers used by todo.sh, are kept. A change to
+a task modified meanwhile by another program is discarded instead of
+overwriting it. The file is watched with todo.WatchPath and reloaded when it
+changes on disk.
+
+The changes are recorded in the history file beside the todo.txt file (see
+todo.HistoryPath), shared with the todotxt command, so that the changes of
+both can be undone and redone.
+
+The raw terminal mode is supported on Linux, macOS and the BSDs.
+*/
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KEINOS/go-todotxt/todo"
+	"github.com/pkg/errors"
+)
+
+// Exit statuses of the command.
+const (
+	exitOK    = 0
+	exitError = 1
+)
+
+// ANSI sequences to control the screen.
+const (
+	ansiAltScreenOn  = "\033[?1049h"
+	ansiAltScreenOff = "\033[?1049l"
+	ansiClearScreen  = "\033[H\033[2J"
+	ansiClearLine    = "\033[K"
+	ansiHideCursor   = "\033[?25l"
+	ansiShowCursor   = "\033[?25h"
+	ansiWrapOff      = "\033[?7l"
+	ansiWrapOn       = "\033[?7h"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the TUI on the terminal of stdin and returns the exit status.
+func run(args []string, stdin *os.File, stdout, stderr io.Writer) int {
+	err := runTUI(args, stdin, stdout)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, "todotxt-tui:", err)
+
+		return exitError
+	}
+
+	return exitOK
+}
+
+// runTUI opens the file of args and runs the TUI until quit.
+func runTUI(args []string, stdin *os.File, stdout io.Writer) error {
+	if len(args) > 1 {
+		return errors.New("usage: todotxt-tui [file]")
+	}
+
+	path, err := todoPath(args)
+	if err != nil {
+		return err
+	}
+
+	renderer := todo.NewRenderer()
+	renderer.Theme = renderer.Theme.WithEnv(os.LookupEnv)
+
+	mdl, err := newModel(path, renderer)
+	if err != nil {
+		return err
+	}
+
+	restore, err := makeRaw(stdin.Fd())
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = restore() }()
+
+	out := bufio.NewWriter(stdout)
+
+	_, _ = out.WriteString(ansiAltScreenOn + ansiWrapOff)
+
+	defer func() {
+		_, _ = out.WriteString(ansiWrapOn + ansiShowCursor + ansiAltScreenOff)
+		_ = out.Flush()
+	}()
+
+	watcher, err := todo.WatchPath(path, todo.WatchOptions{})
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = watcher.Close() }()
+
+	keys := readKeys(stdin)
+
+	for !mdl.quit {
+		width, height := terminalSize(stdout)
+		if err := draw(out, mdl, width, height); err != nil {
+			return err
+		}
+
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+
+			mdl.handleKey(key)
+		case <-watcher.Events:
+			mdl.checkDisk()
+		}
+	}
+
+	return nil
+}
+
+// draw writes the screen of the model.
+func draw(out *bufio.Writer, mdl *model, width, height int) error {
+	lines := mdl.render(width, height)
+
+	_, _ = out.WriteString(ansiHideCursor + ansiClearScreen)
+
+	for i, line := range lines {
+		if i > 0 {
+			_, _ = out.WriteString("\r\n")
+		}
+
+		_, _ = out.WriteString(line + ansiClearLine)
+	}
+
+	if column := mdl.inputCursor(); column >= 0 {
+		_, _ = fmt.Fprintf(out, "\033[%d;%dH%s", len(lines), column+1, ansiShowCursor)
+	}
+
+	return errors.Wrap(out.Flush(), "failed to draw")
+}
+
+// readKeys reads the keys from the reader until it fails.
+func readKeys(reader io.Reader) <-chan string {
+	keys := make(chan string)
+
+	go func() {
+		defer close(keys)
+
+		const bufSize = 64
+
+		buf := make([]byte, bufSize)
+
+		for {
+			n, err := reader.Read(buf)
+			if err != nil {
+				return
+			}
+
+			for _, key := range decodeKeys(buf[:n]) {
+				keys <- key
+			}
+		}
+	}()
+
+	return keys
+}
+
+// todoPath returns the file of args or the todo file of the workspace.
+func todoPath(args []string) (string, error) {
+	if len(args) == 1 {
+		return args[0], nil
+	}
+
+	workspace, err := todo.OpenWorkspaceFromEnv()
+	if err != nil {
+		return "", err
+	}
+
+	return workspace.TodoFile, nil
+}
+
+// ----------------------------------------------------------------------------
+//  Keys
+// ----------------------------------------------------------------------------
+
+// escapeKeys are the escape sequences of the special keys, without the
+// leading escape.
+//
+//nolint:gochecknoglobals // table of escape sequences
+var escapeKeys = map[string]string{
+	"[A": keyUp, "[B": keyDown, "[C": keyRight, "[D": keyLeft,
+	"OA": keyUp, "OB": keyDown, "OC": keyRight, "OD": keyLeft,
+	"[H": keyHome, "[F": keyEnd, "OH": keyHome, "OF": keyEnd,
+	"[1~": keyHome, "[4~": keyEnd, "[7~": keyHome, "[8~": keyEnd,
+	"[3~": keyDelete, "[5~": keyPageUp, "[6~": keyPageDown,
+}
+
+// decodeKeys returns the keys of the input read from the terminal in raw mode.
+// Unknown escape sequences are ignored.
+func decodeKeys(data []byte) []string {
+	keys := []string{}
+	text := string(data)
+
+	for text != "" {
+		switch {
+		case text == "\033":
+			keys = append(keys, keyEscape)
+			text = ""
+		case strings.HasPrefix(text, "\033"):
+			seq := text[1:]
+			if seq[0] != '[' && seq[0] != 'O' {
+				keys = append(keys, keyEscape)
+				text = seq
+
+				continue
+			}
+
+			end := strings.IndexAny(seq[1:], "ABCDEFGHIJKLMNOPQRSTUVWXYZ~") + 1
+			if end == 0 {
+				text = "" // incomplete sequence
+
+				continue
+			}
+
+			if key, found := escapeKeys[seq[:end+1]]; found {
+				keys = append(keys, key)
+			}
+
+			text = seq[end+1:]
+		default:
+			key, size := decodeChar(text)
+			if key != "" {
+				keys = append(keys, key)
+			}
+
+			text = text[size:]
+		}
+	}
+
+	return keys
+}
+
+// decodeChar returns the key of the first character of the text and its size.
+// Other control characters are ignored.
+func decodeChar(text string) (string, int) {
+	switch text[0] {
+	case '\r', '\n':
+		return keyEnter, 1
+	case '\x7f', '\b':
+		return keyBackspace, 1
+	case '\x03':
+		return keyCtrlC, 1
+	}
+
+	char, size := utf8.DecodeRuneInString(text)
+	if char < ' ' || char == utf8.RuneError {
+		return "", size
+	}
+
+	return string(char), size
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_decodeKeys(t *testing.T) {
+	t.Parallel()
+
+	for input, expect := range map[string][]string{
+		"jk":             {"j", "k"},
+		"\033[A\033OB":   {keyUp, keyDown},
+		"\033[5~\033[6~": {keyPageUp, keyPageDown},
+		"\033[3~\033[H":  {keyDelete, keyHome},
+		"\033":           {keyEscape},
+		"\033x":          {keyEscape, "x"},
+		"\033[99~a":      {"a"},
+		"\033[1":         {},
+		"\r\x7f\x03":     {keyEnter, keyBackspace, keyCtrlC},
+		"é\x01\xff":      {"é"},
+	} {
+		require.Equal(t, expect, decodeKeys([]byte(input)), "input: %q", input)
+	}
+}
+
+func Test_draw(t *testing.T) {
+	t.Parallel()
+
+	mdl := testModel(t, "Call Mom\n")
+
+	var buf bytes.Buffer
+
+	out := bufio.NewWriter(&buf)
+
+	require.NoError(t, draw(out, mdl, 80, 4))
+	require.Equal(t, ansiHideCursor+ansiClearScreen+
+		strings.Join([]string{mdl.path + "  [1/1]  sort: file", "> 1 Call Mom", "", help}, ansiClearLine+"\r\n")+
+		ansiClearLine, buf.String())
+
+	buf.Reset()
+
+	testKeys(mdl, "/")
+	require.NoError(t, draw(out, mdl, 80, 4))
+	require.True(t, strings.HasSuffix(buf.String(), "\033[4;9H"+ansiShowCursor), "cursor should be on the input line")
+}
+
+func Test_run_errors(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+
+	require.Equal(t, exitError, run([]string{"a", "b"}, os.Stdin, &stdout, &stderr))
+	require.Contains(t, stderr.String(), "usage: todotxt-tui [file]")
+
+	stderr.Reset()
+
+	stdin, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+
+	defer stdin.Close()
+
+	require.Equal(t, exitError, run([]string{t.TempDir() + "/todo.txt"}, stdin, &stdout, &stderr))
+	require.Contains(t, stderr.String(), "not a terminal")
+	require.Empty(t, stdout.String())
+
+	width, height := terminalSize(&stdout)
+	require.Equal(t, []int{80, 24}, []int{width, height})
+}
@@ -0,0 +1,677 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/KEINOS/go-todotxt/todo"
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Constants
+// ----------------------------------------------------------------------------
+
+// Names of the special keys returned by decodeKeys. Other keys are the typed
+// characters themselves.
+const (
+	keyBackspace = "backspace"
+	keyCtrlC     = "ctrl-c"
+	keyDelete    = "delete"
+	keyDown      = "down"
+	keyEnd       = "end"
+	keyEnter     = "enter"
+	keyEscape    = "esc"
+	keyHome      = "home"
+	keyLeft      = "left"
+	keyPageDown  = "pgdn"
+	keyPageUp    = "pgup"
+	keyRight     = "right"
+	keyUp        = "up"
+)
+
+// help is the key help shown in the footer. It fits in 80 columns, the package
+// doc lists all the keys.
+const help = "j/k move  / filter  s sort  x done  p pri  e edit  n new  u/U undo/redo  q quit"
+
+// mode is the input mode of the model.
+type mode int
+
+// Input modes of the model.
+const (
+	modeNormal   mode = iota // modeNormal browses the tasks.
+	modeFilter               // modeFilter edits the filter.
+	modeEdit                 // modeEdit edits the selected task.
+	modeNew                  // modeNew edits a new task.
+	modePriority             // modePriority waits for the priority.
+)
+
+// sortOption is an order of the list.
+type sortOption struct {
+	name  string
+	flags []todo.TaskSortByType
+}
+
+// sortOptions are the orders cycled by the "s" key. The first one is the
+// order of the file.
+//
+//nolint:gochecknoglobals // table of sort options
+var sortOptions = []sortOption{
+	{name: "file", flags: []todo.TaskSortByType{todo.SortTaskIDAsc}},
+	{name: "priority", flags: []todo.TaskSortByType{todo.SortPriorityAsc, todo.SortDueDateAsc}},
+	{name: "due date", flags: []todo.TaskSortByType{todo.SortDueDateAsc, todo.SortPriorityAsc}},
+	{name: "created date", flags: []todo.TaskSortByType{todo.SortCreatedDateDesc}},
+	{name: "project", flags: []todo.TaskSortByType{todo.SortProjectAsc, todo.SortPriorityAsc}},
+	{name: "context", flags: []todo.TaskSortByType{todo.SortContextAsc, todo.SortPriorityAsc}},
+	{name: "text", flags: []todo.TaskSortByType{todo.SortTodoTextAsc}},
+}
+
+// ----------------------------------------------------------------------------
+//  Type: model
+// ----------------------------------------------------------------------------
+
+// model is the state of the TUI. It is independent of the terminal: keys are
+// given to handleKey and the screen is returned by render.
+type model struct {
+	path     string
+	renderer *todo.Renderer
+	tasks    todo.TaskList
+	stamp    todo.FileStamp
+	saved    []string // saved holds the lines of tasks as last loaded or saved.
+	view     []int    // view holds the indices in tasks of the listed tasks.
+	filter   string   // filter holds the terms of the listed tasks.
+	status   string   // status is the message shown in the footer.
+	input    []rune   // input is the text of the filter or edit line.
+	cursor   int      // cursor is the index in view of the selected task.
+	offset   int      // offset is the index in view of the first shown task.
+	inputPos int      // inputPos is the cursor position in input.
+	sortBy   int      // sortBy is the index in sortOptions.
+	height   int      // height is the number of rows of the list.
+	mode     mode
+	changed  bool // changed is set if the file changed on disk while editing.
+	quit     bool
+}
+
+// newModel returns a model of the todo.txt file, which is loaded.
+func newModel(path string, renderer *todo.Renderer) (*model, error) {
+	mdl := &model{
+		path:     path,
+		renderer: renderer,
+		tasks:    nil,
+		stamp:    todo.FileStamp{},
+		saved:    nil,
+		view:     nil,
+		filter:   "",
+		status:   "",
+		input:    nil,
+		cursor:   0,
+		offset:   0,
+		inputPos: 0,
+		sortBy:   0,
+		height:   0,
+		mode:     modeNormal,
+		changed:  false,
+		quit:     false,
+	}
+
+	return mdl, mdl.load()
+}
+
+// ----------------------------------------------------------------------------
+//  Methods: file
+// ----------------------------------------------------------------------------
+
+// checkDisk reloads the file if it was changed by another program, as notified
+// by the watcher of the file. The file is not reloaded while editing, it is
+// checked again once back to browsing.
+func (m *model) checkDisk() {
+	if m.mode != modeNormal {
+		m.changed = true
+
+		return
+	}
+
+	m.changed = false
+
+	stamp, err := todo.StampPath(m.path)
+	if err != nil || stamp.Equal(m.stamp) {
+		return
+	}
+
+	if err := m.load(); err != nil {
+		m.status = err.Error()
+
+		return
+	}
+
+	m.status = "Reloaded: file changed on disk"
+}
+
+// load loads the file and keeps the selection on the same task if possible.
+// A non-existing file is an empty list.
+func (m *model) load() error {
+	selected := ""
+	if task := m.selected(); task != nil {
+		selected = task.String()
+	}
+
+	stamp, err := todo.StampPath(m.path)
+	if err != nil {
+		return err
+	}
+
+	tasks := todo.NewTaskList()
+	m.view = nil // the indices are invalid from now on
+
+	if !stamp.IsZero() {
+		tasks, stamp, err = todo.LoadFromPathWithStamp(m.path)
+		if err != nil {
+			return err
+		}
+	}
+
+	m.tasks, m.stamp, m.saved = tasks, stamp, taskLines(tasks)
+	m.refresh()
+
+	for i, index := range m.view {
+		if m.tasks[index].String() == selected {
+			m.cursor = i
+		}
+	}
+
+	return nil
+}
+
+// save writes the tasks like write and records the change of the lines of the
+// file in the history file beside it, shared with the todotxt command which
+// records the lines of the file too.
+func (m *model) save(message string) {
+	before, after, ok := m.write(message)
+	if !ok {
+		return
+	}
+
+	pathHistory := todo.HistoryPath(m.path)
+
+	history, err := todo.LoadHistory(pathHistory)
+	if err == nil && history.Record(before, after) {
+		err = history.Save(pathHistory)
+	}
+
+	if err != nil {
+		m.status = err.Error()
+	}
+}
+
+// undoRedo undoes, or redoes, the last change recorded in the history file and
+// writes the tasks. A change conflicting with the tasks is dropped from the
+// history.
+func (m *model) undoRedo(redo bool) {
+	pathHistory := todo.HistoryPath(m.path)
+
+	history, err := todo.LoadHistory(pathHistory)
+	if err != nil {
+		m.status = err.Error()
+
+		return
+	}
+
+	apply, verb := history.Undo, "Undone"
+	if redo {
+		apply, verb = history.Redo, "Redone"
+	}
+
+	action, err := apply(&m.tasks)
+	if err != nil {
+		m.status = err.Error()
+
+		if errors.Is(err, todo.ErrHistoryConflict) {
+			if err := history.Save(pathHistory); err != nil {
+				m.status = err.Error()
+			}
+		}
+
+		return
+	}
+
+	m.view = nil // the indices are invalid from now on
+
+	if _, _, ok := m.write(verb + ": " + strings.ReplaceAll(action.String(), todo.NewLine, ", ")); !ok {
+		return
+	}
+
+	if err := history.Save(pathHistory); err != nil {
+		m.status = err.Error()
+	}
+}
+
+// write applies the changes of the tasks since loaded or saved onto the lines
+// of the file (see todo.PatchLines), so that its comments and blank lines are
+// kept, reloads it and returns the lines of the file before and after with ok
+// true. If a changed task was modified on disk meanwhile, the file is reloaded
+// and the change is discarded.
+func (m *model) write(message string) (before, after []string, ok bool) {
+	lines := taskLines(m.tasks)
+
+	err := todo.UpdateLinesPath(m.path, func(current []string) ([]string, error) {
+		patched, err := todo.PatchLines(current, m.saved, lines)
+		before, after = current, patched
+
+		return patched, err
+	})
+	if err == nil {
+		m.saved = lines // base of the next write if the reload fails
+
+		if err := m.load(); err != nil {
+			m.status = err.Error()
+
+			return before, after, true
+		}
+
+		m.status = message
+
+		return before, after, true
+	}
+
+	if !errors.Is(err, todo.ErrHistoryConflict) {
+		m.status = err.Error()
+
+		return nil, nil, false
+	}
+
+	if err := m.load(); err != nil {
+		m.status = err.Error()
+
+		return nil, nil, false
+	}
+
+	m.status = "File changed on disk: reloaded and the change discarded"
+
+	return nil, nil, false
+}
+
+// ----------------------------------------------------------------------------
+//  Methods: list
+// ----------------------------------------------------------------------------
+
+// refresh rebuilds the view by the filter and the sort order. The selection
+// stays on the same task if it is still listed.
+func (m *model) refresh() {
+	selectedID := 0
+	if task := m.selected(); task != nil {
+		selectedID = task.ID
+	}
+
+	listed := todo.TaskList{}
+	indexOf := map[int]int{}
+
+	for i, task := range m.tasks {
+		if matchTerms(task.String(), strings.Fields(m.filter)) {
+			listed = append(listed, task)
+			indexOf[task.ID] = i
+		}
+	}
+
+	option := sortOptions[m.sortBy]
+	_ = listed.Sort(option.flags[0], option.flags[1:]...) // known flags only
+
+	m.view = make([]int, len(listed))
+	for i, task := range listed {
+		m.view[i] = indexOf[task.ID]
+
+		if task.ID == selectedID {
+			m.cursor = i
+		}
+	}
+
+	m.moveTo(m.cursor)
+}
+
+// moveTo selects the task at the index of the view and scrolls to it.
+func (m *model) moveTo(cursor int) {
+	m.cursor = max(0, min(cursor, len(m.view)-1))
+
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+
+	if m.height > 0 && m.cursor >= m.offset+m.height {
+		m.offset = m.cursor - m.height + 1
+	}
+}
+
+// selected returns the selected task or nil if the view is empty.
+func (m *model) selected() *todo.Task {
+	if m.cursor >= len(m.view) {
+		return nil
+	}
+
+	return &m.tasks[m.view[m.cursor]]
+}
+
+// ----------------------------------------------------------------------------
+//  Methods: keys
+// ----------------------------------------------------------------------------
+
+// handleKey applies the key in the current mode.
+func (m *model) handleKey(key string) {
+	if key == keyCtrlC {
+		m.quit = true
+
+		return
+	}
+
+	switch m.mode {
+	case modeNormal:
+		m.handleNormalKey(key)
+	case modePriority:
+		m.handlePriorityKey(key)
+	case modeFilter, modeEdit, modeNew:
+		m.handleInputKey(key)
+	}
+
+	if m.changed && m.mode == modeNormal {
+		m.checkDisk()
+	}
+}
+
+// handleNormalKey applies the key while browsing.
+//
+//nolint:cyclop // one case per key binding
+func (m *model) handleNormalKey(key string) {
+	m.status = ""
+
+	switch key {
+	case "q":
+		m.quit = true
+	case "j", keyDown:
+		m.moveTo(m.cursor + 1)
+	case "k", keyUp:
+		m.moveTo(m.cursor - 1)
+	case "g", keyHome:
+		m.moveTo(0)
+	case "G", keyEnd:
+		m.moveTo(len(m.view) - 1)
+	case keyPageDown:
+		m.moveTo(m.cursor + max(1, m.height))
+	case keyPageUp:
+		m.moveTo(m.cursor - max(1, m.height))
+	case "/":
+		m.startInput(modeFilter, m.filter)
+	case "s":
+		m.sortBy = (m.sortBy + 1) % len(sortOptions)
+		m.refresh()
+		m.status = "Sorted by " + sortOptions[m.sortBy].name
+	case "r":
+		if err := m.load(); err != nil {
+			m.status = err.Error()
+		}
+	case "n":
+		m.startInput(modeNew, "")
+	case "u", "U":
+		m.undoRedo(key == "U")
+	case "x", " ", "e", keyEnter, "p":
+		m.handleTaskKey(key)
+	}
+}
+
+// handleTaskKey applies the key to the selected task.
+func (m *model) handleTaskKey(key string) {
+	task := m.selected()
+	if task == nil {
+		return
+	}
+
+	switch key {
+	case "x", " ":
+		if task.Completed {
+			task.Reopen()
+			m.save(fmt.Sprintf("Reopened task %d", task.ID))
+		} else {
+			task.Complete()
+			m.save(fmt.Sprintf("Completed task %d", task.ID))
+		}
+	case "e", keyEnter:
+		m.startInput(modeEdit, task.String())
+	case "p":
+		m.mode = modePriority
+	}
+}
+
+// handlePriorityKey sets the priority of the selected task. A letter sets it,
+// "-" or space removes it and any other key cancels.
+func (m *model) handlePriorityKey(key string) {
+	m.mode = modeNormal
+
+	task := m.selected()
+	priority := strings.ToUpper(key)
+
+	switch {
+	case task == nil:
+		return
+	case key == "-" || key == " ":
+		task.Priority = ""
+		m.save(fmt.Sprintf("Removed priority of task %d", task.ID))
+	case len(priority) == 1 && priority >= "A" && priority <= "Z":
+		task.Priority = priority
+		m.save(fmt.Sprintf("Prioritized task %d (%s)", task.ID, priority))
+	}
+}
+
+// handleInputKey edits the input line. Enter applies it and escape cancels.
+//
+//nolint:cyclop // one case per key binding
+func (m *model) handleInputKey(key string) {
+	switch key {
+	case keyEscape:
+		m.mode = modeNormal
+	case keyEnter:
+		m.applyInput(string(m.input))
+	case keyLeft:
+		m.inputPos = max(0, m.inputPos-1)
+	case keyRight:
+		m.inputPos = min(len(m.input), m.inputPos+1)
+	case keyHome:
+		m.inputPos = 0
+	case keyEnd:
+		m.inputPos = len(m.input)
+	case keyBackspace:
+		if m.inputPos > 0 {
+			m.input = append(m.input[:m.inputPos-1], m.input[m.inputPos:]...)
+			m.inputPos--
+		}
+	case keyDelete:
+		if m.inputPos < len(m.input) {
+			m.input = append(m.input[:m.inputPos], m.input[m.inputPos+1:]...)
+		}
+	default:
+		if runes := []rune(key); len(runes) == 1 {
+			m.input = append(m.input[:m.inputPos], append(runes, m.input[m.inputPos:]...)...)
+			m.inputPos++
+		}
+	}
+
+	if m.mode == modeFilter {
+		m.filter = string(m.input)
+		m.refresh()
+	}
+}
+
+// applyInput applies the text of the input line in the current mode. An
+// invalid task keeps the input line open with the error.
+func (m *model) applyInput(text string) {
+	if m.mode == modeFilter {
+		m.mode = modeNormal
+
+		return
+	}
+
+	if strings.TrimSpace(text) == "" {
+		m.mode = modeNormal
+
+		return
+	}
+
+	task, err := todo.ParseTask(text)
+	if err != nil {
+		m.status = "Invalid task: " + err.Error()
+
+		return
+	}
+
+	switch m.mode {
+	case modeNew:
+		m.tasks.AddTask(task)
+		m.mode = modeNormal
+		m.save(fmt.Sprintf("Added task %d", task.ID))
+	case modeEdit:
+		selected := m.selected()
+		task.ID = selected.ID
+		*selected = *task
+		m.mode = modeNormal
+		m.save(fmt.Sprintf("Updated task %d", task.ID))
+	case modeNormal, modeFilter, modePriority:
+	}
+}
+
+// startInput opens the input line of the mode with the text.
+func (m *model) startInput(mode mode, text string) {
+	m.mode = mode
+	m.input = []rune(text)
+	m.inputPos = len(m.input)
+	m.status = ""
+}
+
+// ----------------------------------------------------------------------------
+//  Methods: render
+// ----------------------------------------------------------------------------
+
+// render returns the lines of the screen of the given size: a header, the
+// tasks highlighted by the renderer and a footer.
+func (m *model) render(width, height int) []string {
+	const chromeRows = 2 // header and footer
+
+	m.height = max(1, height-chromeRows)
+	m.moveTo(m.cursor)
+
+	header := fmt.Sprintf("%s  [%d/%d]  sort: %s", m.path, len(m.view), len(m.tasks), sortOptions[m.sortBy].name)
+	if m.filter != "" {
+		header += "  filter: " + m.filter
+	}
+
+	lines := []string{truncate(header, width)}
+	numWidth := len(strconv.Itoa(len(m.tasks)))
+
+	for i := m.offset; i < len(m.view) && i < m.offset+m.height; i++ {
+		task := &m.tasks[m.view[i]]
+		marker := "  "
+
+		if i == m.cursor {
+			marker = "> "
+		}
+
+		number := m.renderer.RenderNumber(fmt.Sprintf("%0*d", numWidth, task.ID))
+		lines = append(lines, marker+number+" "+m.renderer.RenderTask(task))
+	}
+
+	for len(lines) < m.height+1 {
+		lines = append(lines, "")
+	}
+
+	return append(lines, truncate(m.footer(), width))
+}
+
+// footer returns the input line, the status or the key help.
+func (m *model) footer() string {
+	switch {
+	case m.mode == modePriority:
+		return m.prompt()
+	case m.mode != modeNormal && m.status != "":
+		return m.prompt() + string(m.input) + "  (" + m.status + ")"
+	case m.mode != modeNormal:
+		return m.prompt() + string(m.input)
+	case m.status != "":
+		return m.status
+	default:
+		return help
+	}
+}
+
+// inputCursor returns the column of the cursor on the input line, or -1 if
+// there is none.
+func (m *model) inputCursor() int {
+	if m.mode == modeNormal || m.mode == modePriority {
+		return -1
+	}
+
+	return len([]rune(m.prompt())) + m.inputPos
+}
+
+// prompt returns the prompt of the input line of the mode.
+func (m *model) prompt() string {
+	switch m.mode {
+	case modeFilter:
+		return "Filter: "
+	case modeEdit:
+		return "Edit: "
+	case modeNew:
+		return "New: "
+	case modePriority:
+		return "Priority (A-Z, - to remove): "
+	case modeNormal:
+	}
+
+	return ""
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// matchTerms returns true if the text contains all the terms, ignoring case.
+// A term starting with "-" excludes the texts containing the rest of it.
+func matchTerms(text string, terms []string) bool {
+	text = strings.ToLower(text)
+
+	for _, term := range terms {
+		term = strings.ToLower(term)
+
+		if term == "-" {
+			continue // being typed
+		}
+
+		if exclude := strings.TrimPrefix(term, "-"); exclude != term {
+			if strings.Contains(text, exclude) {
+				return false
+			}
+
+			continue
+		}
+
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// taskLines returns the tasks as todo.txt lines.
+func taskLines(tasks todo.TaskList) []string {
+	lines := make([]string, len(tasks))
+	for i, task := range tasks {
+		lines[i] = task.String()
+	}
+
+	return lines
+}
+
+// truncate cuts the text to the width in runes.
+func truncate(text string, width int) string {
+	if runes := []rune(text); width > 0 && len(runes) > width {
+		return string(runes[:width])
+	}
+
+	return text
+}
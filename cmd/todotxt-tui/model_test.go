@@ -0,0 +1,305 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KEINOS/go-todotxt/todo"
+	"github.com/stretchr/testify/require"
+)
+
+// testModel returns a model of a temporary file with the contents and the
+// colours disabled.
+func testModel(t *testing.T, contents string) *model {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), todo.DefaultTodoFile)
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+
+	renderer := todo.NewRenderer()
+	renderer.NoColor = true
+
+	mdl, err := newModel(path, renderer)
+	require.NoError(t, err)
+
+	return mdl
+}
+
+// testKeys sends the keys to the model. A key of several characters is a
+// special key, use testType to type text.
+func testKeys(mdl *model, keys ...string) {
+	for _, key := range keys {
+		mdl.handleKey(key)
+	}
+}
+
+// testType types the text into the model.
+func testType(mdl *model, text string) {
+	for _, char := range text {
+		mdl.handleKey(string(char))
+	}
+}
+
+// testFile returns the contents of the file of the model.
+func testFile(t *testing.T, mdl *model) string {
+	t.Helper()
+
+	raw, err := os.ReadFile(mdl.path)
+	require.NoError(t, err)
+
+	return strings.ReplaceAll(string(raw), todo.NewLine, "\n")
+}
+
+func Test_model_render(t *testing.T) {
+	t.Parallel()
+
+	mdl := testModel(t, "Call Mom @Phone\n(A) Pay bills\nBuy milk\nWalk dog\n")
+
+	require.Equal(t, []string{
+		mdl.path + "  [4/4]  sort: file",
+		"> 1 Call Mom @Phone",
+		"  2 (A) Pay bills",
+		"  3 Buy milk",
+		help,
+	}, mdl.render(200, 5))
+
+	// Scroll
+	testKeys(mdl, "j", keyDown, "j")
+
+	lines := mdl.render(200, 5)
+	require.Equal(t, []string{"  2 (A) Pay bills", "  3 Buy milk", "> 4 Walk dog"}, lines[1:4])
+
+	testKeys(mdl, "g")
+	require.Equal(t, "> 1 Call Mom @Phone", mdl.render(200, 5)[1])
+
+	testKeys(mdl, keyEnd, keyPageUp)
+	require.Equal(t, 0, mdl.cursor)
+
+	require.Len(t, []rune(mdl.render(4, 5)[0]), 4, "header should be cut to the width")
+	require.Equal(t, -1, mdl.inputCursor())
+
+	// Highlighting
+	mdl.renderer.NoColor = false
+	require.Contains(t, mdl.render(200, 5)[1], "\033[0;36m@Phone\033[0m")
+}
+
+func Test_model_filter_sort(t *testing.T) {
+	t.Parallel()
+
+	mdl := testModel(t, "Call Mom @Phone\n(B) Pay bills\n(A) Buy milk @Store\nWalk dog\n")
+
+	testKeys(mdl, "/")
+	testType(mdl, "@ -phone")
+	require.Equal(t, "Filter: @ -phone", mdl.render(200, 10)[9])
+	require.Equal(t, 16, mdl.inputCursor())
+
+	testKeys(mdl, keyEnter)
+
+	lines := mdl.render(200, 10)
+	require.Equal(t, mdl.path+"  [1/4]  sort: file  filter: @ -phone", lines[0])
+	require.Equal(t, "> 3 (A) Buy milk @Store", lines[1])
+
+	// Clear the filter and sort by priority
+	testKeys(mdl, "/", keyHome, keyDelete, keyEnd, keyBackspace, keyBackspace, keyBackspace,
+		keyBackspace, keyBackspace, keyBackspace, keyBackspace, keyEnter, "s")
+
+	lines = mdl.render(200, 10)
+	require.Equal(t, mdl.path+"  [4/4]  sort: priority", lines[0])
+	require.Equal(t, []string{"> 3 (A) Buy milk @Store", "  2 (B) Pay bills", "  1 Call Mom @Phone", "  4 Walk dog"},
+		lines[1:5], "selection should stay on the same task")
+	require.Equal(t, "Sorted by priority", lines[9])
+
+	for range sortOptions[1:] {
+		testKeys(mdl, "s")
+	}
+
+	require.Equal(t, "file", sortOptions[mdl.sortBy].name, "sort order should cycle")
+}
+
+func Test_model_edit(t *testing.T) {
+	t.Parallel()
+
+	mdl := testModel(t, "Call Mom\nBuy milk\n")
+
+	// Complete and reopen
+	testKeys(mdl, "x")
+	require.True(t, mdl.tasks[0].Completed)
+	require.Contains(t, testFile(t, mdl), "x ")
+	require.Equal(t, "Completed task 1", mdl.footer())
+
+	testKeys(mdl, " ")
+	require.Equal(t, "Call Mom\nBuy milk\n", testFile(t, mdl))
+
+	// Priority
+	testKeys(mdl, "j", "p", "b")
+	require.Equal(t, "Call Mom\n(B) Buy milk\n", testFile(t, mdl))
+
+	testKeys(mdl, "p", "-")
+	require.Equal(t, "Call Mom\nBuy milk\n", testFile(t, mdl))
+
+	testKeys(mdl, "p", "?")
+	require.Equal(t, modeNormal, mdl.mode, "other keys should cancel")
+
+	// Edit in place
+	testKeys(mdl, "e", keyLeft, keyLeft, keyLeft, keyLeft)
+	testType(mdl, "soy ")
+	require.Equal(t, "Edit: Buy soy milk", mdl.footer())
+	testKeys(mdl, keyEnter)
+	require.Equal(t, "Call Mom\nBuy soy milk\n", testFile(t, mdl))
+	require.Equal(t, 2, mdl.selected().ID)
+
+	// Invalid task keeps the input open
+	testKeys(mdl, keyEnter)
+	testType(mdl, " due:2024-02-31")
+	testKeys(mdl, keyEnter)
+	require.Equal(t, modeEdit, mdl.mode)
+	require.Contains(t, mdl.footer(), "Invalid task")
+
+	testKeys(mdl, keyEscape)
+	require.Equal(t, "Call Mom\nBuy soy milk\n", testFile(t, mdl))
+
+	// New task
+	testKeys(mdl, "n")
+	testType(mdl, "(C) Walk dog")
+	testKeys(mdl, keyEnter)
+	require.Equal(t, "Call Mom\nBuy soy milk\n(C) Walk dog\n", testFile(t, mdl))
+	require.Equal(t, "Added task 3", mdl.footer())
+
+	testKeys(mdl, "q")
+	require.True(t, mdl.quit)
+}
+
+func Test_model_save_keeps_lines(t *testing.T) {
+	t.Parallel()
+
+	mdl := testModel(t, "# Home\nCall Mom\n\nBuy milk\n# End\n")
+
+	testKeys(mdl, "j", "x")
+	require.Equal(t, "# Home\nCall Mom\n\nx "+mdl.tasks[1].CompletedDate.Format(todo.DateLayout)+" Buy milk\n# End\n",
+		testFile(t, mdl), "comments and blank lines should be kept")
+
+	// Changed by another program meanwhile
+	require.NoError(t, os.WriteFile(mdl.path, []byte("# Home\nCall Mom\n\n"+mdl.saved[1]+"\n# End\nWalk dog\n"), 0o600))
+
+	testKeys(mdl, "k", "p", "a")
+	require.Equal(t, "Prioritized task 1 (A)", mdl.footer())
+	require.Equal(t, "# Home\n(A) Call Mom\n\n"+mdl.saved[1]+"\n# End\nWalk dog\n", testFile(t, mdl),
+		"other changes should be kept")
+	require.Len(t, mdl.tasks, 3, "file should be reloaded")
+}
+
+func Test_model_save_noncanonical_lines(t *testing.T) {
+	t.Parallel()
+
+	mdl := testModel(t, "Buy +Shop milk\nCall @Phone Mom key:val soon\n")
+
+	testKeys(mdl, "p", "b")
+	require.Equal(t, "Prioritized task 1 (B)", mdl.footer())
+	require.Equal(t, "(B) Buy milk +Shop\nCall @Phone Mom key:val soon\n", testFile(t, mdl),
+		"task should be found in the file and the other lines kept as is")
+
+	testKeys(mdl, "j", "x")
+	require.Equal(t, "(B) Buy milk +Shop\nx "+mdl.tasks[1].CompletedDate.Format(todo.DateLayout)+" Call Mom soon @Phone key:val\n",
+		testFile(t, mdl))
+
+	history, err := todo.LoadHistory(todo.HistoryPath(mdl.path))
+	require.NoError(t, err)
+	require.Equal(t, "Buy +Shop milk", history.Done[0].Operations[0].Old, "lines of the file should be recorded")
+
+	// Undo of a change made by the todotxt command on the lines of the file
+	history.Record([]string{"(B) Buy milk +Shop", mdl.saved[1]}, []string{"(B) Buy milk +Shop", mdl.saved[1], "Walk +Dog now"})
+	require.NoError(t, history.Save(todo.HistoryPath(mdl.path)))
+	require.NoError(t, os.WriteFile(mdl.path, []byte("(B) Buy milk +Shop\n"+mdl.saved[1]+"\nWalk +Dog now\n"), 0o600))
+	require.NoError(t, mdl.load())
+
+	testKeys(mdl, "u")
+	require.Equal(t, "Undone: add: Walk +Dog now", mdl.footer())
+	require.Equal(t, "(B) Buy milk +Shop\n"+mdl.saved[1]+"\n", testFile(t, mdl))
+}
+
+func Test_model_undo_redo(t *testing.T) {
+	t.Parallel()
+
+	mdl := testModel(t, "Call Mom\nBuy milk\n")
+
+	testKeys(mdl, "j", "p", "a", "n")
+	testType(mdl, "Walk dog")
+	testKeys(mdl, keyEnter)
+	require.Equal(t, "Call Mom\n(A) Buy milk\nWalk dog\n", testFile(t, mdl))
+
+	testKeys(mdl, "u")
+	require.Equal(t, "Undone: add: Walk dog", mdl.footer())
+	testKeys(mdl, "u")
+	require.Equal(t, "Call Mom\nBuy milk\n", testFile(t, mdl))
+
+	testKeys(mdl, "u")
+	require.Equal(t, "nothing to undo", mdl.footer())
+
+	// Shared by the models of the file
+	other, err := newModel(mdl.path, mdl.renderer)
+	require.NoError(t, err)
+
+	testKeys(other, "U", "U", "U")
+	require.Equal(t, "nothing to redo", other.footer())
+	require.Equal(t, "Call Mom\n(A) Buy milk\nWalk dog\n", testFile(t, mdl))
+
+	mdl.checkDisk()
+	require.Len(t, mdl.tasks, 3)
+
+	// Conflict
+	require.NoError(t, os.WriteFile(mdl.path, []byte("Call Mom\n(B) Buy milk\nWalk dog\n"), 0o600))
+
+	mdl.checkDisk()
+	testKeys(mdl, "u", "u")
+	require.Contains(t, mdl.footer(), "task has been changed since")
+	require.Equal(t, "Call Mom\n(B) Buy milk\n", testFile(t, mdl))
+
+	testKeys(mdl, "u")
+	require.Equal(t, "nothing to undo", mdl.footer(), "conflicting change should be dropped")
+}
+
+func Test_model_reload(t *testing.T) {
+	t.Parallel()
+
+	mdl := testModel(t, "Call Mom\nBuy milk\n")
+
+	testKeys(mdl, "j")
+
+	// Changed on disk
+	require.NoError(t, os.WriteFile(mdl.path, []byte("Walk dog\nCall Mom\nBuy milk\n"), 0o600))
+
+	mdl.checkDisk()
+	require.Len(t, mdl.tasks, 3)
+	require.Equal(t, "Buy milk", mdl.selected().Todo, "selection should stay on the same task")
+	require.Equal(t, "Reloaded: file changed on disk", mdl.footer())
+
+	// Conflict
+	require.NoError(t, os.WriteFile(mdl.path, []byte("Walk dog\n"), 0o600))
+
+	testKeys(mdl, "x")
+	require.Equal(t, "File changed on disk: reloaded and the change discarded", mdl.footer())
+	require.Equal(t, "Walk dog\n", testFile(t, mdl))
+	require.Len(t, mdl.tasks, 1)
+
+	// Changed while editing
+	testKeys(mdl, "/")
+	require.NoError(t, os.WriteFile(mdl.path, []byte("Walk dog\nCall Mom\n"), 0o600))
+
+	mdl.checkDisk()
+	require.Len(t, mdl.tasks, 1, "file should not be reloaded while editing")
+
+	testKeys(mdl, keyEnter)
+	require.Len(t, mdl.tasks, 2, "file should be reloaded once back to browsing")
+
+	// Manual reload of a removed file
+	require.NoError(t, os.Remove(mdl.path))
+
+	testKeys(mdl, "r")
+	require.Empty(t, mdl.tasks)
+	require.Nil(t, mdl.selected())
+
+	testKeys(mdl, "x", "e")
+	require.Equal(t, modeNormal, mdl.mode, "task keys should be ignored on empty list")
+}
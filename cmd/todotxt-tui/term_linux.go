@@ -0,0 +1,12 @@
+//go:build linux
+// +build linux
+
+package main
+
+import "syscall"
+
+// Requests of ioctl(2) to get and set the terminal state.
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
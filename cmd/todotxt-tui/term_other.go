@@ -0,0 +1,23 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package main
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// makeRaw fails since the raw terminal mode is only supported on Linux, macOS
+// and the BSDs.
+func makeRaw(uintptr) (func() error, error) {
+	return nil, errors.New("terminal is not supported on this platform")
+}
+
+// terminalSize returns the default size of 80x24.
+func terminalSize(io.Writer) (int, int) {
+	const defaultWidth, defaultHeight = 80, 24
+
+	return defaultWidth, defaultHeight
+}
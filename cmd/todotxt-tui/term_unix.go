@@ -0,0 +1,76 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package main
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// makeRaw puts the terminal into raw mode and returns a function to restore
+// its previous state.
+func makeRaw(fd uintptr) (func() error, error) {
+	var state syscall.Termios
+
+	err := ioctl(fd, ioctlGetTermios, unsafe.Pointer(&state))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get terminal state, not a terminal?")
+	}
+
+	raw := state
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	err = ioctl(fd, ioctlSetTermios, unsafe.Pointer(&raw))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set terminal to raw mode")
+	}
+
+	return func() error {
+		return errors.Wrap(ioctl(fd, ioctlSetTermios, unsafe.Pointer(&state)), "failed to restore terminal")
+	}, nil
+}
+
+// terminalSize returns the size of the terminal of the writer, or 80x24 if it
+// is not a terminal.
+func terminalSize(writer io.Writer) (int, int) {
+	const defaultWidth, defaultHeight = 80, 24
+
+	file, ok := writer.(*os.File)
+	if !ok {
+		return defaultWidth, defaultHeight
+	}
+
+	var size struct {
+		Row, Col, X, Y uint16
+	}
+
+	err := ioctl(file.Fd(), syscall.TIOCGWINSZ, unsafe.Pointer(&size))
+	if err != nil || size.Row == 0 || size.Col == 0 {
+		return defaultWidth, defaultHeight
+	}
+
+	return int(size.Col), int(size.Row)
+}
+
+// ioctl calls ioctl(2) with a pointer argument.
+func ioctl(fd, request uintptr, arg unsafe.Pointer) error {
+	//nolint:gosec // the pointer refers to a struct of the size expected by the request
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
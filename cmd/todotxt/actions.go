@@ -0,0 +1,704 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KEINOS/go-todotxt/todo"
+	"github.com/pkg/errors"
+)
+
+// priorities are the valid priorities.
+const priorities = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+var (
+	// priorityRx matches the priority at the beginning of a line.
+	priorityRx = regexp.MustCompile(`^\(([A-Z])\) `) //nolint:gochecknoglobals // compiled once
+	// prefixRx matches the priority and the created date at the beginning of
+	// a line, which are kept by "prepend" and "replace".
+	prefixRx = regexp.MustCompile(`^(\([A-Z]\) )?(\d{4}-\d{2}-\d{2} )?`) //nolint:gochecknoglobals // compiled once
+)
+
+// ----------------------------------------------------------------------------
+//  Actions
+// ----------------------------------------------------------------------------
+
+// add appends a task to the todo file.
+func (a *app) add(args []string) error {
+	text := strings.NewReplacer("\r", " ", "\n", " ").Replace(strings.Join(args, " "))
+	if strings.TrimSpace(text) == "" {
+		return errors.New(`usage: todotxt add "TODO ITEM"`)
+	}
+
+	if a.dateOnAdd {
+		prefix := priorityRx.FindString(text)
+		text = prefix + a.today() + " " + strings.TrimPrefix(text, prefix)
+	}
+
+	return a.update(func(lines []string) ([]string, []string, error) {
+		lines = append(lines, text)
+		num := len(lines)
+
+		return lines, []string{fmt.Sprintf("%d %s", num, text), fmt.Sprintf("TODO: %d added.", num)}, nil
+	})
+}
+
+// appendText adds the text to the end of the task.
+func (a *app) appendText(args []string) error {
+	const minArgs = 2
+
+	if len(args) < minArgs {
+		return errors.New(`usage: todotxt append ITEM# "TEXT TO APPEND"`)
+	}
+
+	return a.updateTask(args[0], "append", func(line string) string {
+		return line + " " + strings.Join(args[1:], " ")
+	})
+}
+
+// archive moves the completed tasks to the done file, after the change of the
+// lines if any, like update. The change and the move are recorded to the
+// history as a single action, so that "do" is undone at once.
+func (a *app) archive(change func(lines []string) ([]string, []string, error)) error {
+	if err := a.updateArchive(change, true); err != nil {
+		return err
+	}
+
+	return a.print("TODO: " + a.workspace.TodoFile + " archived.")
+}
+
+// del deletes the task, or the term from the task.
+func (a *app) del(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: todotxt del ITEM# [TERM]")
+	}
+
+	if len(args) == 2 {
+		return a.deleteTerm(args[0], args[1])
+	}
+
+	lines, err := a.readLines()
+	if err != nil {
+		return err
+	}
+
+	num, err := taskNumber(lines, args[0], "del")
+	if err != nil {
+		return err
+	}
+
+	if !a.force && !a.confirm(fmt.Sprintf("Delete '%s'?  (y/n)", lines[num-1])) {
+		return a.print("TODO: No tasks were deleted.")
+	}
+
+	return a.update(func(lines []string) ([]string, []string, error) {
+		num, err := taskNumber(lines, args[0], "del")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		output := []string{fmt.Sprintf("%d %s", num, lines[num-1]), fmt.Sprintf("TODO: %d deleted.", num)}
+
+		if a.preserveLines {
+			lines[num-1] = ""
+		} else {
+			lines = append(lines[:num-1], lines[num:]...)
+		}
+
+		return lines, output, nil
+	})
+}
+
+// deleteTerm removes the term from the task as a whole word.
+func (a *app) deleteTerm(item, term string) error {
+	return a.update(func(lines []string) ([]string, []string, error) {
+		num, err := taskNumber(lines, item, "del")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line := lines[num-1]
+		padded := " " + line + " "
+
+		for strings.Contains(padded, " "+term+" ") {
+			padded = strings.Replace(padded, " "+term+" ", " ", 1)
+		}
+
+		updated := strings.TrimPrefix(strings.TrimSuffix(padded, " "), " ")
+		if updated == line {
+			return nil, []string{fmt.Sprintf("%d %s", num, line)},
+				errors.Errorf("TODO: '%s' not found; no removal done.", term)
+		}
+
+		lines[num-1] = updated
+
+		return lines, []string{fmt.Sprintf("%d %s", num, updated), fmt.Sprintf("TODO: Removed '%s' from task.", term)}, nil
+	})
+}
+
+// depri removes the priority of the tasks.
+func (a *app) depri(args []string) error {
+	items := splitItems(args)
+	if len(items) == 0 {
+		return errors.New("usage: todotxt depri ITEM#[, ITEM#, ITEM#, ...]")
+	}
+
+	return a.update(func(lines []string) ([]string, []string, error) {
+		output := []string{}
+
+		for _, item := range items {
+			num, err := taskNumber(lines, item, "depri")
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if !priorityRx.MatchString(lines[num-1]) {
+				output = append(output, fmt.Sprintf("TODO: %d is not prioritized.", num))
+
+				continue
+			}
+
+			lines[num-1] = priorityRx.ReplaceAllString(lines[num-1], "")
+			output = append(output, fmt.Sprintf("%d %s", num, lines[num-1]), fmt.Sprintf("TODO: %d deprioritized.", num))
+		}
+
+		return lines, output, nil
+	})
+}
+
+// do marks the tasks as done, and archives them if autoArchive is set.
+func (a *app) do(args []string) error {
+	items := splitItems(args)
+	if len(items) == 0 {
+		return errors.New("usage: todotxt do ITEM#[, ITEM#, ITEM#, ...]")
+	}
+
+	change := func(lines []string) ([]string, []string, error) {
+		output := []string{}
+
+		for _, item := range items {
+			num, err := taskNumber(lines, item, "do")
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if strings.HasPrefix(lines[num-1], "x ") {
+				output = append(output, fmt.Sprintf("TODO: %d is already marked done.", num))
+
+				continue
+			}
+
+			// Like todo.sh, the priority is removed once the task is done
+			lines[num-1] = "x " + a.today() + " " + priorityRx.ReplaceAllString(lines[num-1], "")
+			output = append(output, fmt.Sprintf("%d %s", num, lines[num-1]), fmt.Sprintf("TODO: %d marked as done.", num))
+		}
+
+		return lines, output, nil
+	}
+
+	if a.autoArchive {
+		return a.archive(change)
+	}
+
+	return a.update(change)
+}
+
+// list lists the tasks containing all the terms, sorted by text like todo.sh.
+func (a *app) list(terms []string) error {
+	lines, err := a.readLines()
+	if err != nil {
+		return err
+	}
+
+	type numberedLine struct {
+		num  int
+		text string
+	}
+
+	shown := []numberedLine{}
+	total := 0
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		total++
+
+		if matchTerms(line, terms) {
+			shown = append(shown, numberedLine{num: i + 1, text: line})
+		}
+	}
+
+	sort.SliceStable(shown, func(i, j int) bool {
+		return strings.ToLower(shown[i].text) < strings.ToLower(shown[j].text)
+	})
+
+	width := len(strconv.Itoa(len(lines)))
+
+	for _, line := range shown {
+		number := a.renderer.RenderNumber(fmt.Sprintf("%0*d", width, line.num))
+
+		err = a.print(number + " " + a.render(line.text))
+		if err != nil {
+			return err
+		}
+	}
+
+	return a.print("--", fmt.Sprintf("TODO: %d of %d tasks shown", len(shown), total))
+}
+
+// listTerms lists the contexts or the projects of the tasks containing all the
+// terms.
+func (a *app) listTerms(terms []string, contexts bool) error {
+	lines, err := a.readLines()
+	if err != nil {
+		return err
+	}
+
+	found := map[string]bool{}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" || !matchTerms(line, terms) {
+			continue
+		}
+
+		task, err := todo.ParseTask(line)
+		if err != nil {
+			return errors.Wrapf(err, "TODO: failed to parse task %d", i+1)
+		}
+
+		names, prefix := task.Projects, "+"
+		if contexts {
+			names, prefix = task.Contexts, "@"
+		}
+
+		for _, name := range names {
+			found[prefix+name] = true
+		}
+	}
+
+	result := make([]string, 0, len(found))
+	for name := range found {
+		result = append(result, name)
+	}
+
+	sort.Strings(result)
+
+	return a.print(result...)
+}
+
+// prepend adds the text to the beginning of the task, after its priority and
+// created date.
+func (a *app) prepend(args []string) error {
+	const minArgs = 2
+
+	if len(args) < minArgs {
+		return errors.New(`usage: todotxt prepend ITEM# "TEXT TO PREPEND"`)
+	}
+
+	return a.updateTask(args[0], "prepend", func(line string) string {
+		prefix := prefixRx.FindString(line)
+
+		return prefix + strings.Join(args[1:], " ") + " " + strings.TrimPrefix(line, prefix)
+	})
+}
+
+// pri sets the priority of the task.
+func (a *app) pri(args []string) error {
+	const numArgs = 2
+
+	if len(args) != numArgs || len(args[1]) != 1 || !strings.Contains(priorities, strings.ToUpper(args[1])) {
+		return errors.New("usage: todotxt pri ITEM# PRIORITY\nnote: PRIORITY must be anywhere from A to Z.")
+	}
+
+	priority := strings.ToUpper(args[1])
+
+	return a.update(func(lines []string) ([]string, []string, error) {
+		num, err := taskNumber(lines, args[0], "pri")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line := lines[num-1]
+		oldPriority := priorityRx.FindStringSubmatch(line)
+
+		if oldPriority != nil && oldPriority[1] == priority {
+			return lines, []string{fmt.Sprintf("%d %s", num, line),
+				fmt.Sprintf("TODO: %d already prioritized (%s).", num, priority)}, nil
+		}
+
+		lines[num-1] = "(" + priority + ") " + priorityRx.ReplaceAllString(line, "")
+		output := []string{fmt.Sprintf("%d %s", num, lines[num-1])}
+
+		if oldPriority != nil {
+			output = append(output, fmt.Sprintf("TODO: %d re-prioritized from (%s) to (%s).", num, oldPriority[1], priority))
+		} else {
+			output = append(output, fmt.Sprintf("TODO: %d prioritized (%s).", num, priority))
+		}
+
+		return lines, output, nil
+	})
+}
+
+// redo applies again the last N undone changes, 1 by default. See undo.
+func (a *app) redo(args []string) error {
+	return a.undoRedo(args, true)
+}
+
+// replace replaces the task. The priority and the created date are kept if the
+// new text has none.
+func (a *app) replace(args []string) error {
+	const minArgs = 2
+
+	if len(args) < minArgs {
+		return errors.New(`usage: todotxt replace ITEM# "UPDATED ITEM"`)
+	}
+
+	text := strings.Join(args[1:], " ")
+
+	return a.update(func(lines []string) ([]string, []string, error) {
+		num, err := taskNumber(lines, args[0], "replace")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		oldPrefix := prefixRx.FindStringSubmatch(lines[num-1])
+		newPrefix := prefixRx.FindStringSubmatch(text)
+		updated := strings.TrimPrefix(text, newPrefix[0])
+
+		for i := len(oldPrefix) - 1; i > 0; i-- {
+			if newPrefix[i] != "" {
+				updated = newPrefix[i] + updated
+			} else {
+				updated = oldPrefix[i] + updated
+			}
+		}
+
+		output := []string{
+			fmt.Sprintf("%d %s", num, lines[num-1]),
+			"TODO: Replaced task with:",
+			fmt.Sprintf("%d %s", num, updated),
+		}
+		lines[num-1] = updated
+
+		return lines, output, nil
+	})
+}
+
+// report archives the completed tasks and appends the counts to the report
+// file.
+func (a *app) report() error {
+	entry, err := a.workspace.Report()
+	if err != nil {
+		return err
+	}
+
+	return a.print(entry.String(), "TODO: Report file updated.")
+}
+
+// undo reverts the last N changes made by the actions, 1 by default. The
+// changes are recorded in the history file beside the todo file, so they can
+// be undone by later runs.
+func (a *app) undo(args []string) error {
+	return a.undoRedo(args, false)
+}
+
+// ----------------------------------------------------------------------------
+//  Helpers
+// ----------------------------------------------------------------------------
+
+// confirm prints the question and returns true if the answer is "y".
+func (a *app) confirm(question string) bool {
+	if a.print(question) != nil {
+		return false
+	}
+
+	answer, _ := bufio.NewReader(a.stdin).ReadString('\n')
+
+	return strings.TrimSpace(answer) == "y"
+}
+
+// readLines reads the lines of the todo file. A non-existing file has no lines.
+func (a *app) readLines() ([]string, error) {
+	raw, err := os.ReadFile(a.workspace.TodoFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read file: "+a.workspace.TodoFile)
+	}
+
+	text := strings.TrimSuffix(strings.ReplaceAll(string(raw), "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil, nil
+	}
+
+	return strings.Split(text, "\n"), nil
+}
+
+// moveArchived removes the archived lines of an undone action from the done
+// file, or appends them again to it on redo.
+func (a *app) moveArchived(archived []string, redo bool) error {
+	return todo.UpdateLinesPath(a.workspace.DoneFile, func(lines []string) ([]string, error) {
+		if redo {
+			return append(lines, archived...), nil
+		}
+
+		for _, line := range archived {
+			for i := len(lines) - 1; i >= 0; i-- {
+				if strings.TrimSpace(lines[i]) == line {
+					lines = slices.Delete(lines, i, i+1)
+
+					break
+				}
+			}
+		}
+
+		return lines, nil
+	})
+}
+
+// record records the change of the lines of the todo file, and the lines moved
+// to the done file if any, to its history file.
+func (a *app) record(before, after, archived []string) error {
+	pathHistory := todo.HistoryPath(a.workspace.TodoFile)
+
+	history, err := todo.LoadHistory(pathHistory)
+	if err != nil {
+		return err
+	}
+
+	history.Clock = a.workspace.Clock
+
+	if !history.RecordArchive(before, after, archived) {
+		return nil
+	}
+
+	return history.Save(pathHistory)
+}
+
+// render returns the line styled by the renderer. The line is returned as is
+// in plain mode or if it is not a valid task.
+func (a *app) render(line string) string {
+	if a.renderer.NoColor {
+		return line
+	}
+
+	task, err := todo.ParseTask(line)
+	if err != nil {
+		return line
+	}
+
+	return a.renderer.RenderTask(task)
+}
+
+// today returns the current date in todo.txt format.
+func (a *app) today() string {
+	return a.workspace.Clock.Now().Format(todo.DateLayout)
+}
+
+// update applies the change to the lines of the todo file under lock and
+// records it to the history. The output of the change is printed, even if it
+// fails, in which case the file is left untouched.
+func (a *app) update(change func(lines []string) ([]string, []string, error)) error {
+	return a.updateArchive(change, false)
+}
+
+// updateArchive is like update, but also archives the completed tasks if
+// archive is set, under the locks of the todo and done files (see
+// todo.ArchiveLinesPath). The change is optional if archive is set.
+func (a *app) updateArchive(change func(lines []string) ([]string, []string, error), archive bool) error {
+	var (
+		output    []string
+		errChange error
+		update    func(lines []string) ([]string, error)
+	)
+
+	if change != nil {
+		update = func(lines []string) ([]string, error) {
+			lines, output, errChange = change(lines)
+
+			return lines, errChange
+		}
+	}
+
+	var err error
+
+	if archive {
+		opts := todo.ArchiveOptions{Clock: nil, OlderThanDays: 0, Deduplicate: false}
+		_, err = todo.ArchiveLinesPath(a.workspace.TodoFile, a.workspace.DoneFile, opts, update, a.record)
+	} else {
+		err = todo.UpdateLinesPath(a.workspace.TodoFile, func(lines []string) ([]string, error) {
+			before := slices.Clone(lines)
+
+			lines, err := update(lines)
+			if err != nil {
+				return nil, err
+			}
+
+			return lines, a.record(before, lines, nil)
+		})
+	}
+
+	if errPrint := a.print(output...); err == nil {
+		err = errPrint
+	}
+
+	if errChange != nil {
+		return errChange // without the wrapping of the update
+	}
+
+	return err
+}
+
+// undoRedo undoes, or redoes, the number of actions given in args from the
+// history file under the lock of the todo file. An action conflicting with the
+// current tasks is skipped and dropped from the history.
+func (a *app) undoRedo(args []string, redo bool) error {
+	name, verb := "undo", "Undone"
+	if redo {
+		name, verb = "redo", "Redone"
+	}
+
+	count := 1
+
+	if len(args) > 1 {
+		return errors.Errorf("usage: todotxt %s [N]", name)
+	}
+
+	if len(args) == 1 {
+		num, err := strconv.Atoi(args[0])
+		if err != nil || num < 1 {
+			return errors.Errorf("usage: todotxt %s [N]", name)
+		}
+
+		count = num
+	}
+
+	pathHistory := todo.HistoryPath(a.workspace.TodoFile)
+
+	var output []string
+
+	err := todo.UpdateLinesPath(a.workspace.TodoFile, func(lines []string) ([]string, error) {
+		history, err := todo.LoadHistory(pathHistory)
+		if err != nil {
+			return nil, err
+		}
+
+		history.Clock = a.workspace.Clock
+
+		apply := history.UndoLines
+		if redo {
+			apply = history.RedoLines
+		}
+
+		for range count {
+			updated, action, err := apply(lines)
+
+			switch {
+			case errors.Is(err, todo.ErrNothingToUndo), errors.Is(err, todo.ErrNothingToRedo):
+				output = append(output, fmt.Sprintf("TODO: Nothing to %s.", name))
+
+				return lines, history.Save(pathHistory)
+			case errors.Is(err, todo.ErrHistoryConflict):
+				output = append(output, "TODO: Skipped, "+err.Error()+".")
+
+				continue
+			case err != nil:
+				return nil, err
+			}
+
+			lines = updated
+
+			if len(action.Archived) > 0 {
+				if err := a.moveArchived(action.Archived, redo); err != nil {
+					return nil, err
+				}
+			}
+
+			for _, op := range action.Operations {
+				output = append(output, "TODO: "+verb+" "+op.String())
+			}
+		}
+
+		return lines, history.Save(pathHistory)
+	})
+
+	if errPrint := a.print(output...); err == nil {
+		err = errPrint
+	}
+
+	return err
+}
+
+// updateTask replaces the line of the task by the result of edit and prints
+// it.
+func (a *app) updateTask(item, action string, edit func(line string) string) error {
+	return a.update(func(lines []string) ([]string, []string, error) {
+		num, err := taskNumber(lines, item, action)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		lines[num-1] = edit(lines[num-1])
+
+		return lines, []string{fmt.Sprintf("%d %s", num, lines[num-1])}, nil
+	})
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// matchTerms returns true if the line contains all the terms, ignoring case.
+// A term starting with "-" excludes the lines containing the rest of it.
+func matchTerms(line string, terms []string) bool {
+	line = strings.ToLower(line)
+
+	for _, term := range terms {
+		term = strings.ToLower(term)
+
+		if exclude := strings.TrimPrefix(term, "-"); exclude != term && exclude != "" {
+			if strings.Contains(line, exclude) {
+				return false
+			}
+
+			continue
+		}
+
+		if !strings.Contains(line, term) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitItems returns the task numbers of the arguments, which are separated by
+// spaces or commas.
+func splitItems(args []string) []string {
+	return strings.Fields(strings.ReplaceAll(strings.Join(args, " "), ",", " "))
+}
+
+// taskNumber returns the line number of the task. It fails if the item is not
+// a number or if there is no task at the line.
+func taskNumber(lines []string, item, action string) (int, error) {
+	num, err := strconv.Atoi(item)
+	if err != nil || num < 1 {
+		return 0, errors.Errorf("usage: todotxt %s ITEM#", action)
+	}
+
+	if num > len(lines) || strings.TrimSpace(lines[num-1]) == "" {
+		return 0, errors.Errorf("TODO: No task %d.", num)
+	}
+
+	return num, nil
+}
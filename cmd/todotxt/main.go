@@ -0,0 +1,271 @@
+/*
+Command todotxt is a todo.txt command-line tool compatible with todo.sh.
+
+It implements the core actions of todo.sh with the same line numbers, messages
+and exit statuses, so that it can replace the bash script without changing
+habits or scripts.
+
+Usage:
+
+	todotxt [-afhpt] action [task_number] [task_description]
+
+Actions:
+
+	add|a "THING I NEED TO DO +project @context"
+	append|app ITEM# "TEXT TO APPEND"
+	archive
+	del|rm ITEM# [TERM]
+	depri|dp ITEM#[, ITEM#, ...]
+	do ITEM#[, ITEM#, ...]
+	help
+	list|ls [TERM...]
+	listcon|lsc [TERM...]
+	listproj|lsp [TERM...]
+	prepend|prep ITEM# "TEXT TO PREPEND"
+	pri|p ITEM# PRIORITY
+	redo [N]
+	replace ITEM# "UPDATED TODO"
+	report
+	undo [N]
+
+Options:
+
+	-a  Don't auto-archive tasks automatically on completion.
+	-f  Forces actions without confirmation.
+	-h  Displays this help.
+	-p  Plain mode turns off colors.
+	-t  Prepends the current date to a task automatically when it's added.
+
+Like todo.sh, the tasks are referred by their line number in todo.txt. Deleted
+tasks leave a blank line, so the numbers of the other tasks do not change until
+the next archive.
+
+The changes made by the actions are recorded in a history file beside the todo
+file (see todo.HistoryPath), so that "undo 3" reverts the last three of them,
+even from separate runs, and "redo" applies them again. Undoing "archive", or
+"do" with its auto-archive, moves the tasks back from the done file. The
+archive made by "report" is not recorded.
+
+The files are located by TODO_DIR, TODO_FILE, DONE_FILE and REPORT_FILE (see
+todo.OpenWorkspaceFromEnv). The variables TODOTXT_AUTO_ARCHIVE,
+TODOTXT_DATE_ON_ADD, TODOTXT_DEFAULT_ACTION, TODOTXT_FORCE, TODOTXT_PLAIN,
+TODOTXT_PRESERVE_LINE_NUMBERS and the colour variables of todo.sh (see
+todo.Theme.WithEnv) are supported as well. NO_COLOR disables the colours.
+
+The exit status is 0 on success and 1 on errors, such as an unknown task
+number.
+*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/KEINOS/go-todotxt/todo"
+	"github.com/pkg/errors"
+)
+
+// Exit statuses of the command, the same as todo.sh.
+const (
+	exitOK    = 0
+	exitError = 1
+)
+
+// usage is the short help of the command.
+const usage = `Usage: todotxt [-afhpt] action [task_number] [task_description]
+
+Actions:
+  add|a "THING I NEED TO DO +project @context"
+  append|app ITEM# "TEXT TO APPEND"
+  archive
+  del|rm ITEM# [TERM]
+  depri|dp ITEM#[, ITEM#, ...]
+  do ITEM#[, ITEM#, ...]
+  help
+  list|ls [TERM...]
+  listcon|lsc [TERM...]
+  listproj|lsp [TERM...]
+  prepend|prep ITEM# "TEXT TO PREPEND"
+  pri|p ITEM# PRIORITY
+  redo [N]
+  replace ITEM# "UPDATED TODO"
+  report
+  undo [N]
+
+Options:
+  -a  Don't auto-archive tasks automatically on completion.
+  -f  Forces actions without confirmation.
+  -h  Displays this help.
+  -p  Plain mode turns off colors.
+  -t  Prepends the current date to a task automatically when it's added.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the action given in args on the workspace located by the
+// environment variables and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	workspace, err := todo.OpenWorkspaceFromEnv()
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, "todotxt:", err)
+
+		return exitError
+	}
+
+	cli := newApp(workspace, stdin, stdout)
+	cli.loadEnv(os.LookupEnv)
+
+	err = cli.run(args)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+
+		return exitError
+	}
+
+	return exitOK
+}
+
+// ----------------------------------------------------------------------------
+//  Type: app
+// ----------------------------------------------------------------------------
+
+// app holds the settings of the command.
+type app struct {
+	workspace     *todo.Workspace
+	renderer      *todo.Renderer
+	stdin         io.Reader
+	stdout        io.Writer
+	defaultAction string
+	autoArchive   bool // autoArchive archives the todo file after "do".
+	dateOnAdd     bool // dateOnAdd prepends the created date on "add".
+	force         bool // force deletes without confirmation.
+	preserveLines bool // preserveLines leaves a blank line on "del".
+}
+
+// newApp returns an app of the workspace with the defaults of todo.sh.
+func newApp(workspace *todo.Workspace, stdin io.Reader, stdout io.Writer) *app {
+	renderer := todo.NewRenderer()
+	renderer.Clock = workspace.Clock
+
+	return &app{
+		workspace:     workspace,
+		renderer:      renderer,
+		stdin:         stdin,
+		stdout:        stdout,
+		defaultAction: "",
+		autoArchive:   true,
+		dateOnAdd:     false,
+		force:         false,
+		preserveLines: true,
+	}
+}
+
+// loadEnv applies the TODOTXT_* and the colour variables of todo.sh.
+func (a *app) loadEnv(lookup func(key string) (string, bool)) {
+	for env, field := range map[string]*bool{
+		"TODOTXT_AUTO_ARCHIVE":          &a.autoArchive,
+		"TODOTXT_DATE_ON_ADD":           &a.dateOnAdd,
+		"TODOTXT_FORCE":                 &a.force,
+		"TODOTXT_PRESERVE_LINE_NUMBERS": &a.preserveLines,
+	} {
+		if value, found := lookup(env); found && value != "" {
+			*field = value == "1"
+		}
+	}
+
+	if value, found := lookup("TODOTXT_PLAIN"); found && value == "1" {
+		a.renderer.NoColor = true
+	}
+
+	if value, found := lookup("TODOTXT_DEFAULT_ACTION"); found {
+		a.defaultAction = value
+	}
+
+	a.renderer.Theme = a.renderer.Theme.WithEnv(lookup)
+}
+
+// run parses the options and runs the action of args.
+func (a *app) run(args []string) error {
+	for len(args) > 0 && len(args[0]) > 1 && strings.HasPrefix(args[0], "-") {
+		for _, option := range args[0][1:] {
+			switch option {
+			case 'a':
+				a.autoArchive = false
+			case 'f':
+				a.force = true
+			case 'h':
+				return a.print(usage)
+			case 'p':
+				a.renderer.NoColor = true
+			case 't':
+				a.dateOnAdd = true
+			default:
+				return errors.Errorf("todotxt: invalid option -- '%c'\n%s", option, usage)
+			}
+		}
+
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		if a.defaultAction == "" {
+			return errors.New(usage)
+		}
+
+		args = strings.Fields(a.defaultAction)
+	}
+
+	action, found := a.actions()[args[0]]
+	if !found {
+		return errors.New(usage)
+	}
+
+	return action(args[1:])
+}
+
+// actions returns the actions by name, including the aliases of todo.sh.
+func (a *app) actions() map[string]func(args []string) error {
+	return map[string]func(args []string) error{
+		"add":      a.add,
+		"a":        a.add,
+		"append":   a.appendText,
+		"app":      a.appendText,
+		"archive":  func([]string) error { return a.archive(nil) },
+		"del":      a.del,
+		"rm":       a.del,
+		"depri":    a.depri,
+		"dp":       a.depri,
+		"do":       a.do,
+		"help":     func([]string) error { return a.print(usage) },
+		"list":     a.list,
+		"ls":       a.list,
+		"listcon":  func(args []string) error { return a.listTerms(args, true) },
+		"lsc":      func(args []string) error { return a.listTerms(args, true) },
+		"listproj": func(args []string) error { return a.listTerms(args, false) },
+		"lsp":      func(args []string) error { return a.listTerms(args, false) },
+		"prepend":  a.prepend,
+		"prep":     a.prepend,
+		"pri":      a.pri,
+		"p":        a.pri,
+		"redo":     a.redo,
+		"replace":  a.replace,
+		"report":   func([]string) error { return a.report() },
+		"undo":     a.undo,
+	}
+}
+
+// print writes the lines to stdout.
+func (a *app) print(lines ...string) error {
+	for _, line := range lines {
+		_, err := fmt.Fprintln(a.stdout, strings.TrimSuffix(line, "\n"))
+		if err != nil {
+			return errors.Wrap(err, "failed to write output")
+		}
+	}
+
+	return nil
+}
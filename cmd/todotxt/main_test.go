@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KEINOS/go-todotxt/todo"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock returns a fixed time.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c fakeClock) Now() time.Time {
+	return c.now
+}
+
+// testApp returns an app on a temporary workspace with the todo file, a fake
+// clock on 2024-01-16 and the colours disabled.
+func testApp(t *testing.T, contents string) (*app, *bytes.Buffer) {
+	t.Helper()
+
+	workspace, err := todo.OpenWorkspace(t.TempDir())
+	require.NoError(t, err)
+
+	workspace.Clock = fakeClock{now: time.Date(2024, 1, 16, 10, 0, 0, 0, time.Local)}
+
+	if contents != "" {
+		require.NoError(t, os.WriteFile(workspace.TodoFile, []byte(contents), 0o600))
+	}
+
+	var stdout bytes.Buffer
+
+	cli := newApp(workspace, strings.NewReader(""), &stdout)
+	cli.renderer.NoColor = true
+
+	return cli, &stdout
+}
+
+// testRead returns the contents of the file.
+func testRead(t *testing.T, path string) string {
+	t.Helper()
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	return strings.ReplaceAll(string(raw), todo.NewLine, "\n")
+}
+
+func Test_app_add(t *testing.T) {
+	t.Parallel()
+
+	cli, stdout := testApp(t, "Call Mom\n\nBuy milk\n")
+
+	require.NoError(t, cli.run([]string{"add", "(A)", "Pay", "bills"}))
+	require.Equal(t, "4 (A) Pay bills\nTODO: 4 added.\n", stdout.String())
+
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"-t", "a", "(B) Walk dog"}))
+	require.Equal(t, "5 (B) 2024-01-16 Walk dog\nTODO: 5 added.\n", stdout.String())
+
+	require.Equal(t, "Call Mom\n\nBuy milk\n(A) Pay bills\n(B) 2024-01-16 Walk dog\n", testRead(t, cli.workspace.TodoFile))
+
+	require.EqualError(t, cli.run([]string{"add"}), `usage: todotxt add "TODO ITEM"`)
+}
+
+func Test_app_list(t *testing.T) {
+	t.Parallel()
+
+	cli, stdout := testApp(t, "Call Mom @Phone +Family\n(B) Read book\n\n"+
+		"x 2024-01-10 Buy seeds +Garden\n(A) Plan garden +Garden\nbuy milk @Store\n"+
+		"Fix fence\nWalk dog\nWater plants\nClean desk @Home\n")
+
+	require.NoError(t, cli.run([]string{"ls"}))
+	require.Equal(t, `05 (A) Plan garden +Garden
+02 (B) Read book
+06 buy milk @Store
+01 Call Mom @Phone +Family
+10 Clean desk @Home
+07 Fix fence
+08 Walk dog
+09 Water plants
+04 x 2024-01-10 Buy seeds +Garden
+--
+TODO: 9 of 9 tasks shown
+`, stdout.String())
+
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"list", "GARDEN", "-x 2024"}))
+	require.Equal(t, "05 (A) Plan garden +Garden\n--\nTODO: 1 of 9 tasks shown\n", stdout.String())
+
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"lsp"}))
+	require.Equal(t, "+Family\n+Garden\n", stdout.String())
+
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"lsc", "-phone"}))
+	require.Equal(t, "@Home\n@Store\n", stdout.String())
+
+	// Colours
+	stdout.Reset()
+
+	cli.renderer.NoColor = false
+
+	require.NoError(t, cli.run([]string{"ls", "Plan"}))
+	require.Contains(t, stdout.String(), "\033[1;33m(A) Plan garden")
+
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"-p", "ls", "Plan"}))
+	require.NotContains(t, stdout.String(), "\033[")
+}
+
+func Test_app_do(t *testing.T) {
+	t.Parallel()
+
+	cli, stdout := testApp(t, "(A) Call Mom\nx 2024-01-10 Buy seeds\nWalk dog\n")
+
+	require.NoError(t, cli.run([]string{"-a", "do", "1,", "2"}))
+	require.Equal(t, "1 x 2024-01-16 Call Mom\nTODO: 1 marked as done.\n"+
+		"TODO: 2 is already marked done.\n", stdout.String())
+	require.Equal(t, "x 2024-01-16 Call Mom\nx 2024-01-10 Buy seeds\nWalk dog\n", testRead(t, cli.workspace.TodoFile))
+
+	// Auto-archive
+	stdout.Reset()
+
+	cli.autoArchive = true
+
+	require.NoError(t, cli.run([]string{"do", "3"}))
+	require.Equal(t, "3 x 2024-01-16 Walk dog\nTODO: 3 marked as done.\n"+
+		"TODO: "+cli.workspace.TodoFile+" archived.\n", stdout.String())
+	require.Empty(t, testRead(t, cli.workspace.TodoFile))
+	require.Equal(t, "x 2024-01-16 Call Mom\nx 2024-01-10 Buy seeds\nx 2024-01-16 Walk dog\n",
+		testRead(t, cli.workspace.DoneFile))
+}
+
+func Test_app_pri_depri(t *testing.T) {
+	t.Parallel()
+
+	cli, stdout := testApp(t, "Call Mom\n(B) Read book\n")
+
+	require.NoError(t, cli.run([]string{"pri", "1", "a"}))
+	require.NoError(t, cli.run([]string{"p", "2", "C"}))
+	require.NoError(t, cli.run([]string{"pri", "2", "C"}))
+	require.Equal(t, "1 (A) Call Mom\nTODO: 1 prioritized (A).\n"+
+		"2 (C) Read book\nTODO: 2 re-prioritized from (B) to (C).\n"+
+		"2 (C) Read book\nTODO: 2 already prioritized (C).\n", stdout.String())
+
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"depri", "1", "2"}))
+	require.NoError(t, cli.run([]string{"dp", "1"}))
+	require.Equal(t, "1 Call Mom\nTODO: 1 deprioritized.\n2 Read book\nTODO: 2 deprioritized.\n"+
+		"TODO: 1 is not prioritized.\n", stdout.String())
+
+	require.ErrorContains(t, cli.run([]string{"pri", "1", "AA"}), "PRIORITY must be anywhere from A to Z")
+}
+
+func Test_app_del(t *testing.T) {
+	t.Parallel()
+
+	cli, stdout := testApp(t, "Call Mom @Phone\nBuy milk\nWalk dog\n")
+
+	// Confirmation
+	cli.stdin = strings.NewReader("n\n")
+
+	require.NoError(t, cli.run([]string{"del", "2"}))
+	require.Equal(t, "Delete 'Buy milk'?  (y/n)\nTODO: No tasks were deleted.\n", stdout.String())
+
+	stdout.Reset()
+
+	cli.stdin = strings.NewReader("y\n")
+
+	require.NoError(t, cli.run([]string{"rm", "2"}))
+	require.Equal(t, "Delete 'Buy milk'?  (y/n)\n2 Buy milk\nTODO: 2 deleted.\n", stdout.String())
+	require.Equal(t, "Call Mom @Phone\n\nWalk dog\n", testRead(t, cli.workspace.TodoFile),
+		"deleted task should leave a blank line")
+
+	// Line numbers are kept
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"ls"}))
+	require.Equal(t, "1 Call Mom @Phone\n3 Walk dog\n--\nTODO: 2 of 2 tasks shown\n", stdout.String())
+
+	require.EqualError(t, cli.run([]string{"del", "2"}), "TODO: No task 2.")
+
+	// Term
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"del", "1", "@Phone"}))
+	require.Equal(t, "1 Call Mom\nTODO: Removed '@Phone' from task.\n", stdout.String())
+
+	stdout.Reset()
+
+	require.EqualError(t, cli.run([]string{"del", "1", "Mo"}), "TODO: 'Mo' not found; no removal done.")
+	require.Equal(t, "1 Call Mom\n", stdout.String())
+
+	// Without preserving line numbers
+	cli.preserveLines = false
+
+	require.NoError(t, cli.run([]string{"-f", "del", "1"}))
+	require.Equal(t, "\nWalk dog\n", testRead(t, cli.workspace.TodoFile))
+}
+
+func Test_app_append_prepend_replace(t *testing.T) {
+	t.Parallel()
+
+	cli, stdout := testApp(t, "(A) 2024-01-01 Call Mom\nBuy milk\n")
+
+	require.NoError(t, cli.run([]string{"append", "2", "@Store"}))
+	require.NoError(t, cli.run([]string{"prep", "1", "Urgent:"}))
+	require.Equal(t, "2 Buy milk @Store\n1 (A) 2024-01-01 Urgent: Call Mom\n", stdout.String())
+
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"replace", "1", "Call", "Dad"}))
+	require.NoError(t, cli.run([]string{"replace", "2", "(C) Buy eggs"}))
+	require.Equal(t, "1 (A) 2024-01-01 Urgent: Call Mom\nTODO: Replaced task with:\n1 (A) 2024-01-01 Call Dad\n"+
+		"2 Buy milk @Store\nTODO: Replaced task with:\n2 (C) Buy eggs\n", stdout.String())
+
+	require.Equal(t, "(A) 2024-01-01 Call Dad\n(C) Buy eggs\n", testRead(t, cli.workspace.TodoFile))
+
+	require.EqualError(t, cli.run([]string{"append", "3", "text"}), "TODO: No task 3.")
+	require.EqualError(t, cli.run([]string{"prepend", "one", "text"}), "usage: todotxt prepend ITEM#")
+	require.ErrorContains(t, cli.run([]string{"replace", "1"}), "usage: todotxt replace")
+}
+
+func Test_app_archive_report(t *testing.T) {
+	t.Parallel()
+
+	cli, stdout := testApp(t, "x 2024-01-15 Call Mom\n\nBuy milk\n")
+
+	require.NoError(t, cli.run([]string{"archive"}))
+	require.Equal(t, "TODO: "+cli.workspace.TodoFile+" archived.\n", stdout.String())
+	require.Equal(t, "Buy milk\n", testRead(t, cli.workspace.TodoFile), "archive should remove blank lines")
+
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"report"}))
+	require.Equal(t, "2024-01-16T10:00:00 1 1\nTODO: Report file updated.\n", stdout.String())
+	require.Equal(t, "2024-01-16T10:00:00 1 1\n", testRead(t, cli.workspace.ReportFile))
+}
+
+func Test_app_undo_redo(t *testing.T) {
+	t.Parallel()
+
+	cli, stdout := testApp(t, "Call Mom\nBuy milk\n")
+
+	require.NoError(t, cli.run([]string{"pri", "1", "A"}))
+	require.NoError(t, cli.run([]string{"-f", "del", "2"}))
+	require.NoError(t, cli.run([]string{"add", "Walk dog"}))
+	require.Equal(t, "(A) Call Mom\n\nWalk dog\n", testRead(t, cli.workspace.TodoFile))
+	require.FileExists(t, todo.HistoryPath(cli.workspace.TodoFile))
+
+	// Separate run
+	stdout.Reset()
+
+	cli = newApp(cli.workspace, strings.NewReader(""), stdout)
+
+	require.NoError(t, cli.run([]string{"undo", "2"}))
+	require.Equal(t, "TODO: Undone add: Walk dog\nTODO: Undone remove: Buy milk\n", stdout.String())
+	require.Equal(t, "(A) Call Mom\nBuy milk\n", testRead(t, cli.workspace.TodoFile))
+
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"redo"}))
+	require.NoError(t, cli.run([]string{"undo", "5"}))
+	require.Equal(t, "TODO: Redone remove: Buy milk\nTODO: Undone remove: Buy milk\n"+
+		"TODO: Undone priority: (A) Call Mom\nTODO: Nothing to undo.\n", stdout.String())
+	require.Equal(t, "Call Mom\nBuy milk\n", testRead(t, cli.workspace.TodoFile))
+
+	// A new change clears the changes to redo
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"redo"}))
+	require.NoError(t, cli.run([]string{"append", "2", "@Store"}))
+	require.NoError(t, cli.run([]string{"redo"}))
+	require.Equal(t, "TODO: Redone priority: (A) Call Mom\n2 Buy milk @Store\nTODO: Nothing to redo.\n", stdout.String())
+
+	// Conflict
+	stdout.Reset()
+
+	require.NoError(t, os.WriteFile(cli.workspace.TodoFile, []byte("(A) Call Mom\nBuy oat milk\n"), 0o600))
+	require.NoError(t, cli.run([]string{"undo", "2"}))
+	require.Equal(t, "TODO: Skipped, can not undo \"edit: Buy milk @Store\": task has been changed since.\n"+
+		"TODO: Undone priority: (A) Call Mom\n", stdout.String())
+	require.Equal(t, "Call Mom\nBuy oat milk\n", testRead(t, cli.workspace.TodoFile))
+
+	require.EqualError(t, cli.run([]string{"undo", "0"}), "usage: todotxt undo [N]")
+	require.EqualError(t, cli.run([]string{"redo", "1", "2"}), "usage: todotxt redo [N]")
+}
+
+func Test_app_undo_redo_archive(t *testing.T) {
+	t.Parallel()
+
+	cli, stdout := testApp(t, "(A) Call Mom\nWalk dog\n")
+
+	require.NoError(t, os.WriteFile(cli.workspace.DoneFile, []byte("x 2024-01-10 Buy seeds\n"), 0o600))
+
+	// Auto-archive is undone with the completion
+	require.NoError(t, cli.run([]string{"do", "1"}))
+	require.Equal(t, "Walk dog\n", testRead(t, cli.workspace.TodoFile))
+
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"undo"}))
+	require.Equal(t, "TODO: Undone remove: (A) Call Mom\n", stdout.String())
+	require.Equal(t, "(A) Call Mom\nWalk dog\n", testRead(t, cli.workspace.TodoFile))
+	require.Equal(t, "x 2024-01-10 Buy seeds\n", testRead(t, cli.workspace.DoneFile),
+		"archived task should be removed from the done file")
+
+	require.NoError(t, cli.run([]string{"redo"}))
+	require.Equal(t, "\nWalk dog\n", testRead(t, cli.workspace.TodoFile))
+	require.Equal(t, "x 2024-01-10 Buy seeds\nx 2024-01-16 Call Mom\n", testRead(t, cli.workspace.DoneFile))
+
+	// Archive alone
+	require.NoError(t, cli.run([]string{"-a", "do", "2"}))
+	require.NoError(t, cli.run([]string{"archive"}))
+	require.Empty(t, testRead(t, cli.workspace.TodoFile))
+
+	stdout.Reset()
+
+	require.NoError(t, cli.run([]string{"undo"}))
+	require.Equal(t, "TODO: Undone remove: x 2024-01-16 Walk dog\n", stdout.String())
+	require.Equal(t, "x 2024-01-16 Walk dog\n", testRead(t, cli.workspace.TodoFile))
+	require.Equal(t, "x 2024-01-10 Buy seeds\nx 2024-01-16 Call Mom\n", testRead(t, cli.workspace.DoneFile))
+}
+
+func Test_app_usage(t *testing.T) {
+	t.Parallel()
+
+	cli, stdout := testApp(t, "")
+
+	require.NoError(t, cli.run([]string{"-h"}))
+	require.Equal(t, usage, stdout.String())
+
+	require.EqualError(t, cli.run(nil), usage)
+	require.EqualError(t, cli.run([]string{"unknown"}), usage)
+	require.ErrorContains(t, cli.run([]string{"-x", "ls"}), "invalid option -- 'x'")
+
+	// Default action
+	stdout.Reset()
+
+	cli.loadEnv(func(key string) (string, bool) {
+		return map[string]string{"TODOTXT_DEFAULT_ACTION": "ls", "TODOTXT_AUTO_ARCHIVE": "0"}[key], true
+	})
+
+	require.NoError(t, cli.run(nil))
+	require.Equal(t, "--\nTODO: 0 of 0 tasks shown\n", stdout.String())
+	require.False(t, cli.autoArchive)
+}
+
+//nolint:paralleltest // t.Setenv can not be used with t.Parallel
+func Test_run(t *testing.T) {
+	pathDir := t.TempDir()
+
+	t.Setenv("TODO_DIR", pathDir)
+	t.Setenv("TODO_FILE", "")
+	t.Setenv("NO_COLOR", "1")
+
+	var stdout, stderr bytes.Buffer
+
+	require.Equal(t, exitOK, run([]string{"add", "Call Mom"}, strings.NewReader(""), &stdout, &stderr))
+	require.Equal(t, "1 Call Mom\nTODO: 1 added.\n", stdout.String())
+	require.FileExists(t, filepath.Join(pathDir, todo.DefaultTodoFile))
+
+	require.Equal(t, exitError, run([]string{"do", "2"}, strings.NewReader(""), &stdout, &stderr))
+	require.Equal(t, "TODO: No task 2.\n", stderr.String())
+
+	stderr.Reset()
+
+	t.Setenv("TODO_DIR", filepath.Join(pathDir, "unknown"))
+
+	require.Equal(t, exitError, run([]string{"ls"}, strings.NewReader(""), &stdout, &stderr))
+	require.Contains(t, stderr.String(), "failed to open workspace")
+}
@@ -0,0 +1,289 @@
+package todo
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: ArchiveOptions
+// ----------------------------------------------------------------------------
+
+// ArchiveOptions are the options for archiving completed tasks. The zero value
+// archives all completed tasks, like the "archive" command of todo.sh.
+type ArchiveOptions struct {
+	// Clock is used to calculate OlderThanDays. Defaults to the real time.
+	Clock Clock
+	// OlderThanDays archives only tasks completed at least the given number of
+	// days ago. Tasks without a completed date are kept if set. Zero archives
+	// all completed tasks.
+	OlderThanDays int
+	// Deduplicate skips tasks which are already in the done list. They are
+	// still removed from the todo list.
+	Deduplicate bool
+}
+
+// isArchivable returns true if the task should be moved to the done list.
+func (opts ArchiveOptions) isArchivable(task *Task) bool {
+	if !task.Completed {
+		return false
+	}
+
+	if opts.OlderThanDays <= 0 {
+		return true
+	}
+
+	if !task.HasCompletedDate() {
+		return false
+	}
+
+	clock := opts.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	now := clock.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, task.CompletedDate.Location())
+
+	return !task.CompletedDate.After(today.AddDate(0, 0, -opts.OlderThanDays))
+}
+
+// ----------------------------------------------------------------------------
+//  TaskList.Archive()
+// ----------------------------------------------------------------------------
+
+// Archive moves the completed tasks of the TaskList to the done TaskList and
+// returns the moved tasks. See ArchiveOptions for the conditions.
+//
+// The moved tasks are appended to done via AddTask, so their IDs are set to
+// follow the tasks in done. To archive files, use ArchivePath instead.
+func (tasklist *TaskList) Archive(done *TaskList, opts ArchiveOptions) TaskList {
+	archived := TaskList{}
+	kept := TaskList{}
+	seen := map[string]bool{}
+
+	for _, task := range *done {
+		seen[task.String()] = true
+	}
+
+	for _, task := range *tasklist {
+		if !opts.isArchivable(&task) {
+			kept = append(kept, task)
+
+			continue
+		}
+
+		archived = append(archived, task)
+
+		if opts.Deduplicate && seen[task.String()] {
+			continue
+		}
+
+		seen[task.String()] = true
+
+		done.AddTask(&task)
+	}
+
+	*tasklist = kept
+
+	return archived
+}
+
+// ----------------------------------------------------------------------------
+//  Public functions
+// ----------------------------------------------------------------------------
+
+// ArchivePath moves the completed tasks from the todo file to the done file
+// (most likely "todo.txt" and "done.txt") and returns the archived tasks.
+//
+// It follows the "archive" command of todo.sh: blank lines are removed from the
+// todo file, completed tasks are appended to the done file as they are, and
+// any other line, including comments, is kept in the todo file untouched. The
+// IDs of the returned tasks are the task IDs in the todo file as LoadFromPath
+// would have assigned.
+//
+// Both files are locked during the operation and replaced atomically. If the
+// todo file fails to be written, the done file is restored, so that tasks are
+// neither lost nor duplicated.
+func ArchivePath(todoPath, donePath string, opts ArchiveOptions) (TaskList, error) {
+	return ArchiveLinesPath(todoPath, donePath, opts, nil, nil)
+}
+
+// ArchiveLinesPath is like ArchivePath, but the lines of the todo file are
+// first changed by update, as UpdateLinesPath does, such as to complete tasks
+// before archiving them. A non-existing todo file has no lines if update is
+// given.
+//
+// Before the files are written, commit is called with the lines of the todo
+// file before the update and after the archive, and the lines appended to the
+// done file. As both files are locked from the read to the write, they are the
+// whole change of the files, such as to record it to a History. If update or
+// commit returns an error, the files are left untouched. Both functions may be
+// nil.
+func ArchiveLinesPath(todoPath, donePath string, opts ArchiveOptions,
+	update func(lines []string) ([]string, error), commit func(before, after, appended []string) error,
+) (TaskList, error) {
+	unlock, err := lockPaths(todoPath, donePath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = unlock() }()
+
+	before, err := readLines(todoPath)
+	if err != nil && (update == nil || !errors.Is(err, os.ErrNotExist)) {
+		return nil, err
+	}
+
+	todoLines := slices.Clone(before)
+
+	if update != nil {
+		todoLines, err = update(todoLines)
+		if err != nil {
+			return nil, errors.Wrap(err, "update of the task list canceled")
+		}
+	}
+
+	//nolint:gosec // donePath is provided by user, same as LoadFromPath
+	doneRaw, err := os.ReadFile(donePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrap(err, "failed to read file: "+donePath)
+	}
+
+	archived, keptLines, toAppend, err := archiveLines(todoLines, splitLines(doneRaw), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if commit != nil {
+		if err := commit(before, keptLines, toAppend); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(toAppend) > 0 {
+		err = writeFileAtomic(donePath, appendLines(doneRaw, toAppend))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if slices.Equal(keptLines, before) {
+		return archived, nil // nothing to change in the todo file
+	}
+
+	err = writeFileAtomic(todoPath, []byte(joinLines(keptLines)))
+	if err != nil {
+		// Restore the done file to avoid duplicated tasks
+		if doneRaw == nil {
+			_ = os.Remove(donePath)
+		} else {
+			_ = writeFileAtomic(donePath, doneRaw)
+		}
+
+		return nil, err
+	}
+
+	return archived, nil
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// appendLines returns the data of a file with the lines appended, after a line
+// ending if the data has no trailing one.
+func appendLines(data []byte, lines []string) []byte {
+	text := string(data)
+	if isNotEmpty(text) && !strings.HasSuffix(text, "\n") {
+		text += NewLine
+	}
+
+	return []byte(text + joinLines(lines))
+}
+
+// archiveLines splits the lines of the todo file into the archived tasks, the
+// lines to keep in the todo file and the lines to append to the done file.
+func archiveLines(todoLines, doneLines []string, opts ArchiveOptions) (TaskList, []string, []string, error) {
+	archived := TaskList{}
+	keptLines := []string{}
+	toAppend := []string{}
+	seen := map[string]bool{}
+	taskID := 0
+
+	for _, line := range doneLines {
+		seen[strings.Trim(line, whitespaces)] = true
+	}
+
+	for _, line := range todoLines {
+		text := strings.Trim(line, whitespaces)
+
+		switch {
+		case isEmpty(text):
+			continue // defragment blank lines
+		case IgnoreComments && strings.HasPrefix(text, "#"):
+			keptLines = append(keptLines, line)
+
+			continue
+		}
+
+		taskID++
+
+		task, err := ParseTask(text)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if !opts.isArchivable(task) {
+			keptLines = append(keptLines, line)
+
+			continue
+		}
+
+		task.ID = taskID
+		archived = append(archived, *task)
+
+		if opts.Deduplicate && seen[text] {
+			continue
+		}
+
+		seen[text] = true
+		toAppend = append(toAppend, text)
+	}
+
+	return archived, keptLines, toAppend, nil
+}
+
+// joinLines joins the lines with NewLine, including a trailing one.
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return emptyStr
+	}
+
+	return strings.Join(lines, NewLine) + NewLine
+}
+
+// readLines reads all lines of the file.
+func readLines(filename string) ([]string, error) {
+	//nolint:gosec // filename is provided by user, same as LoadFromPath
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read file: "+filename)
+	}
+
+	return splitLines(raw), nil
+}
+
+// splitLines splits the data into lines without the line endings.
+func splitLines(data []byte) []string {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if isEmpty(text) {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
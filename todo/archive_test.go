@@ -0,0 +1,226 @@
+package todo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// testArchiveFiles writes the todo and done files to a temporary directory and
+// returns their paths. An empty done string means no done file.
+func testArchiveFiles(t *testing.T, todoTxt, doneTxt string) (string, string) {
+	t.Helper()
+
+	pathDir := t.TempDir()
+	todoPath := filepath.Join(pathDir, "todo.txt")
+	donePath := filepath.Join(pathDir, "done.txt")
+
+	require.NoError(t, os.WriteFile(todoPath, []byte(todoTxt), PermReadWrite))
+
+	if doneTxt != "" {
+		require.NoError(t, os.WriteFile(donePath, []byte(doneTxt), PermReadWrite))
+	}
+
+	return todoPath, donePath
+}
+
+// testReadFile returns the contents of the file with "\n" line endings.
+func testReadFile(t *testing.T, path string) string {
+	t.Helper()
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	return strings.ReplaceAll(string(raw), "\r\n", "\n")
+}
+
+// ----------------------------------------------------------------------------
+//  TaskList.Archive()
+// ----------------------------------------------------------------------------
+
+func TestTaskList_Archive(t *testing.T) {
+	t.Parallel()
+
+	tasklist := testMustLoad(t, `
+		(A) Call Mom
+		x 2024-01-01 Pick up milk
+		x 2024-01-09 Water plants
+		x Download Todo.txt mobile app
+	`)
+	done := testMustLoad(t, "x 2024-01-01 Pick up milk")
+
+	clock := &fakeClock{now: time.Date(2024, 1, 10, 15, 0, 0, 0, time.Local)}
+
+	// Only tasks completed 3 days ago or before, without duplicates
+	archived := tasklist.Archive(&done, ArchiveOptions{Clock: clock, OlderThanDays: 3, Deduplicate: true})
+
+	checkTaskListOrder(t, archived, []string{"x 2024-01-01 Pick up milk"})
+	checkTaskListOrder(t, tasklist, []string{
+		"(A) Call Mom", "x 2024-01-09 Water plants", "x Download Todo.txt mobile app",
+	})
+	checkTaskListOrder(t, done, []string{"x 2024-01-01 Pick up milk"})
+
+	// All completed tasks
+	archived = tasklist.Archive(&done, ArchiveOptions{})
+
+	require.Len(t, archived, 2)
+	checkTaskListOrder(t, tasklist, []string{"(A) Call Mom"})
+	checkTaskListOrder(t, done, []string{
+		"x 2024-01-01 Pick up milk", "x 2024-01-09 Water plants", "x Download Todo.txt mobile app",
+	})
+	require.Equal(t, 3, done[2].ID, "archived tasks should be numbered in the done list")
+}
+
+func TestArchiveOptions_isArchivable_default_clock(t *testing.T) {
+	t.Parallel()
+
+	task, err := ParseTask("x " + time.Now().Format(DateLayout) + " Pick up milk")
+	require.NoError(t, err)
+
+	require.False(t, ArchiveOptions{OlderThanDays: 1}.isArchivable(task), "task completed today is not old enough")
+	require.True(t, ArchiveOptions{}.isArchivable(task))
+}
+
+// ----------------------------------------------------------------------------
+//  ArchivePath()
+// ----------------------------------------------------------------------------
+
+func TestArchivePath(t *testing.T) {
+	t.Parallel()
+
+	todoPath, donePath := testArchiveFiles(t,
+		"# My tasks\n(A) Call Mom\n\nx 2024-01-01 Pick up milk\n  x Water plants\n\n# x is not a task\nPlan garden\n",
+		"x 2023-12-31 Old task", // without trailing new line
+	)
+
+	archived, err := ArchivePath(todoPath, donePath, ArchiveOptions{})
+	require.NoError(t, err)
+
+	checkTaskListOrder(t, archived, []string{"x 2024-01-01 Pick up milk", "x Water plants"})
+	require.Equal(t, 2, archived[0].ID)
+	require.Equal(t, 3, archived[1].ID)
+
+	require.Equal(t, "# My tasks\n(A) Call Mom\n# x is not a task\nPlan garden\n", testReadFile(t, todoPath),
+		"completed tasks and blank lines should be removed, comments kept")
+	require.Equal(t, "x 2023-12-31 Old task\nx 2024-01-01 Pick up milk\nx Water plants\n", testReadFile(t, donePath),
+		"completed tasks should be appended as they are")
+}
+
+func TestArchivePath_deduplicate(t *testing.T) {
+	t.Parallel()
+
+	todoPath, donePath := testArchiveFiles(t,
+		"x Pick up milk\nx Pick up milk\nx Water plants\n",
+		"x Pick up milk\n",
+	)
+
+	archived, err := ArchivePath(todoPath, donePath, ArchiveOptions{Deduplicate: true})
+	require.NoError(t, err)
+	require.Len(t, archived, 3)
+
+	require.Empty(t, testReadFile(t, todoPath))
+	require.Equal(t, "x Pick up milk\nx Water plants\n", testReadFile(t, donePath))
+}
+
+func TestArchivePath_nothing_to_archive(t *testing.T) {
+	t.Parallel()
+
+	todoPath, donePath := testArchiveFiles(t, "(A) Call Mom\n\nPlan garden\n", "")
+
+	archived, err := ArchivePath(todoPath, donePath, ArchiveOptions{})
+	require.NoError(t, err)
+	require.Empty(t, archived)
+
+	require.Equal(t, "(A) Call Mom\nPlan garden\n", testReadFile(t, todoPath), "blank lines should be removed")
+	require.NoFileExists(t, donePath, "done file should not be created without tasks to archive")
+}
+
+func TestArchivePath_errors(t *testing.T) {
+	t.Parallel()
+
+	pathDir := t.TempDir()
+
+	// Missing todo file
+	_, err := ArchivePath(filepath.Join(pathDir, "todo.txt"), filepath.Join(pathDir, "done.txt"), ArchiveOptions{})
+	require.Error(t, err)
+
+	// Invalid task
+	_, err = ArchivePath(testCopyToTemp(t, testInputTasklistDueDateError), filepath.Join(pathDir, "done.txt"), ArchiveOptions{})
+	require.Error(t, err)
+
+	// Lock errors
+	_, err = ArchivePath("/path/to/unknown/dir/todo.txt", filepath.Join(pathDir, "done.txt"), ArchiveOptions{})
+	require.Error(t, err)
+
+	todoPath, _ := testArchiveFiles(t, "x Pick up milk\n", "")
+
+	_, err = ArchivePath(todoPath, "/path/to/unknown/dir/done.txt", ArchiveOptions{})
+	require.Error(t, err)
+
+	// Done file is a directory
+	_, err = ArchivePath(todoPath, pathDir, ArchiveOptions{})
+	require.Error(t, err)
+}
+
+// ----------------------------------------------------------------------------
+//  ArchiveLinesPath()
+// ----------------------------------------------------------------------------
+
+func TestArchiveLinesPath(t *testing.T) {
+	t.Parallel()
+
+	todoPath, donePath := testArchiveFiles(t, "Call Mom\n\nPick up milk\n", "x Old task\n")
+
+	complete := func(lines []string) ([]string, error) {
+		lines[2] = "x " + lines[2]
+
+		return lines, nil
+	}
+
+	var before, after, appended []string
+
+	archived, err := ArchiveLinesPath(todoPath, donePath, ArchiveOptions{}, complete,
+		func(linesBefore, linesAfter, linesAppended []string) error {
+			require.Equal(t, "Call Mom\n\nPick up milk\n", testReadFile(t, todoPath), "files should not be written yet")
+
+			before, after, appended = linesBefore, linesAfter, linesAppended
+
+			return nil
+		})
+	require.NoError(t, err)
+	checkTaskListOrder(t, archived, []string{"x Pick up milk"})
+
+	require.Equal(t, []string{"Call Mom", "", "Pick up milk"}, before, "lines should be the ones before the update")
+	require.Equal(t, []string{"Call Mom"}, after)
+	require.Equal(t, []string{"x Pick up milk"}, appended)
+	require.Equal(t, "Call Mom\n", testReadFile(t, todoPath))
+	require.Equal(t, "x Old task\nx Pick up milk\n", testReadFile(t, donePath))
+
+	// Errors leave the files untouched
+	_, err = ArchiveLinesPath(todoPath, donePath, ArchiveOptions{}, func([]string) ([]string, error) {
+		return nil, errors.New("forced error")
+	}, nil)
+	require.ErrorContains(t, err, "forced error")
+
+	addDone := func(lines []string) ([]string, error) { return append(lines, "x Water plants"), nil }
+
+	_, err = ArchiveLinesPath(todoPath, donePath, ArchiveOptions{}, addDone, func(_, _, _ []string) error {
+		return errors.New("forced error")
+	})
+	require.ErrorContains(t, err, "forced error")
+	require.Equal(t, "Call Mom\n", testReadFile(t, todoPath))
+	require.Equal(t, "x Old task\nx Pick up milk\n", testReadFile(t, donePath))
+
+	// Missing todo file with update
+	pathDir := t.TempDir()
+
+	archived, err = ArchiveLinesPath(filepath.Join(pathDir, "todo.txt"), filepath.Join(pathDir, "done.txt"),
+		ArchiveOptions{}, func(lines []string) ([]string, error) { return lines, nil }, nil)
+	require.NoError(t, err)
+	require.Empty(t, archived)
+}
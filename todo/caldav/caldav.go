@@ -0,0 +1,660 @@
+/*
+Package caldav serves a todo.txt file as a CalDAV (RFC 4791) calendar collection
+of VTODO components, so that task apps of phones and desktops can sync two-way
+with a plain todo.txt file.
+
+Each task is a resource named after its iCalendar UID (see todo.ICalUID), such
+as "/tasks/42.ics". The first time the tasks are served, those without a "uid:"
+tag get one, written to the file, so that the name of their resource is kept
+when their text is edited and identical tasks do not share a resource. The
+requests are mapped onto the task list as follows:
+
+  - GET and REPORT (calendar-query and calendar-multiget) read the tasks.
+  - PUT of a new resource adds a task, PUT of an existing one updates it.
+  - DELETE removes the task.
+  - PROPFIND lists the collection and its resources.
+
+Changes are written via todo.UpdatePath, which locks the file and replaces it
+atomically. The ETag of a resource is derived from the task content, so the
+"If-Match" and "If-None-Match" preconditions detect concurrent changes made by
+other clients or by editing the file directly.
+
+Example usage:
+
+	handler := caldav.NewHandler("/home/me/todo/todo.txt", "/tasks/")
+
+	log.Fatal(http.ListenAndServe("127.0.0.1:8080", handler))
+
+Configure the client with the URL of the collection (e.g.
+"http://127.0.0.1:8080/tasks/"). The handler does no authentication; serve it
+on localhost or behind a reverse proxy which does.
+*/
+package caldav
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/KEINOS/go-todotxt/todo"
+	"github.com/pkg/errors"
+)
+
+const (
+	emptyStr = ""
+
+	// contentTypeICal is the content type of the resources.
+	contentTypeICal = "text/calendar; charset=utf-8"
+	// contentTypeXML is the content type of the WebDAV responses.
+	contentTypeXML = "application/xml; charset=utf-8"
+	// resourceExt is the extension of the resource names.
+	resourceExt = ".ics"
+	// allowedMethods is the value of the Allow header.
+	allowedMethods = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT"
+	// maxBodySize is the maximum size of a request body.
+	maxBodySize = 1 << 20
+)
+
+// Errors of the update operations, mapped to HTTP status codes.
+var (
+	errNotFound     = errors.New("task not found")
+	errPrecondition = errors.New("precondition failed")
+)
+
+// ----------------------------------------------------------------------------
+//  Type: Handler
+// ----------------------------------------------------------------------------
+
+// Handler is an http.Handler serving a todo.txt file as a CalDAV collection.
+type Handler struct {
+	// Clock is used for the DTSTAMP of the served components. Defaults to the
+	// real time.
+	Clock todo.Clock
+	// Path is the path of the todo.txt file. It is created on the first PUT
+	// if it does not exist.
+	Path string
+	// Prefix is the URL path of the collection, with a trailing slash.
+	Prefix string
+	// Name is the display name of the collection. Defaults to the base name
+	// of Path.
+	Name string
+}
+
+// NewHandler returns a Handler serving the todo.txt file at the URL path
+// prefix (e.g. "/tasks/"). An empty prefix is "/".
+func NewHandler(path, prefix string) *Handler {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	return &Handler{Clock: nil, Path: path, Prefix: prefix, Name: emptyStr}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	uid, isResource, found := h.resolve(req.URL.Path)
+	if !found {
+		http.NotFound(writer, req)
+
+		return
+	}
+
+	writer.Header().Set("DAV", "1, calendar-access")
+
+	switch {
+	case req.Method == http.MethodOptions:
+		writer.Header().Set("Allow", allowedMethods)
+		writer.WriteHeader(http.StatusNoContent)
+	case req.Method == "PROPFIND":
+		h.propfind(writer, req, uid, isResource)
+	case req.Method == "REPORT" && !isResource:
+		h.report(writer, req)
+	case (req.Method == http.MethodGet || req.Method == http.MethodHead) && isResource:
+		h.get(writer, req, uid)
+	case (req.Method == http.MethodGet || req.Method == http.MethodHead) && !isResource:
+		h.getCollection(writer, req)
+	case req.Method == http.MethodPut && isResource:
+		h.put(writer, req, uid)
+	case req.Method == http.MethodDelete && isResource:
+		h.delete(writer, req, uid)
+	default:
+		writer.Header().Set("Allow", allowedMethods)
+		http.Error(writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Methods: request handlers
+// ----------------------------------------------------------------------------
+
+// delete removes the task of the resource.
+func (h *Handler) delete(writer http.ResponseWriter, req *http.Request, uid string) {
+	err := todo.UpdatePath(h.Path, func(tasklist *todo.TaskList) error {
+		index := findTask(*tasklist, uid)
+		if index < 0 {
+			return errNotFound
+		}
+
+		if !matchPreconditions(req, ETag(&(*tasklist)[index])) {
+			return errPrecondition
+		}
+
+		*tasklist = append((*tasklist)[:index], (*tasklist)[index+1:]...)
+
+		return nil
+	})
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// get serves the resource of a task.
+func (h *Handler) get(writer http.ResponseWriter, req *http.Request, uid string) {
+	tasklist, err := h.load()
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	index := findTask(tasklist, uid)
+	if index < 0 {
+		writeError(writer, errNotFound)
+
+		return
+	}
+
+	data, err := h.encode(tasklist[index : index+1])
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", contentTypeICal)
+	writer.Header().Set("ETag", ETag(&tasklist[index]))
+
+	if req.Method == http.MethodGet {
+		_, _ = writer.Write(data)
+	}
+}
+
+// getCollection serves all the tasks as a single iCalendar, for apps which
+// subscribe to a calendar URL.
+func (h *Handler) getCollection(writer http.ResponseWriter, req *http.Request) {
+	tasklist, err := h.load()
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	data, err := h.encode(tasklist)
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", contentTypeICal)
+
+	if req.Method == http.MethodGet {
+		_, _ = writer.Write(data)
+	}
+}
+
+// propfind lists the properties of the collection and, with "Depth: 1", of its
+// resources. All the known properties are returned regardless of the request.
+func (h *Handler) propfind(writer http.ResponseWriter, req *http.Request, uid string, isResource bool) {
+	tasklist, err := h.load()
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	status := newMultistatus()
+
+	if isResource {
+		index := findTask(tasklist, uid)
+		if index < 0 {
+			writeError(writer, errNotFound)
+
+			return
+		}
+
+		status.Responses = append(status.Responses, h.taskResponse(&tasklist[index], false))
+		writeMultistatus(writer, status)
+
+		return
+	}
+
+	stamp, err := todo.StampPath(h.Path)
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	status.Responses = append(status.Responses, response{
+		Href:   h.Prefix,
+		Status: emptyStr,
+		Propstat: &propstat{
+			Prop: prop{
+				ResourceType:   &resourceType{Collection: &struct{}{}, Calendar: &struct{}{}},
+				ComponentSet:   &componentSet{Comps: []comp{{Name: "VTODO"}}},
+				CalendarData:   nil,
+				Privileges:     &privilegeSet{Privileges: []privilege{{Read: &struct{}{}, Write: &struct{}{}}}},
+				DisplayName:    h.displayName(),
+				GetContentType: emptyStr,
+				GetETag:        `"` + stamp.Hash + `"`,
+				GetCTag:        stamp.Hash,
+			},
+			Status: statusLine(http.StatusOK),
+		},
+	})
+
+	if req.Header.Get("Depth") == "1" {
+		for i := range tasklist {
+			status.Responses = append(status.Responses, h.taskResponse(&tasklist[i], false))
+		}
+	}
+
+	writeMultistatus(writer, status)
+}
+
+// put adds or updates the task of the resource. The body must hold a single
+// VTODO component. The UID of the task is the name of the resource, a different
+// UID in the body is rejected.
+func (h *Handler) put(writer http.ResponseWriter, req *http.Request, uid string) {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, req.Body, maxBodySize))
+	if err != nil {
+		http.Error(writer, "failed to read body: "+err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	imported, _, err := todo.LoadFromICal(bytes.NewReader(body))
+	if err != nil || len(imported) != 1 {
+		http.Error(writer, "body must be an iCalendar with a single VTODO", http.StatusBadRequest)
+
+		return
+	}
+
+	if bodyUID := icalUID(body); bodyUID != emptyStr && bodyUID != uid {
+		http.Error(writer, "UID of the body does not match the resource: "+bodyUID, http.StatusBadRequest)
+
+		return
+	}
+
+	task := imported[0]
+	if task.AdditionalTags == nil {
+		task.AdditionalTags = map[string]string{}
+	}
+
+	task.AdditionalTags[todo.UIDTag] = uid
+
+	created := false
+
+	err = todo.UpdatePath(h.Path, func(tasklist *todo.TaskList) error {
+		index := findTask(*tasklist, uid)
+
+		current := emptyStr
+		if index >= 0 {
+			current = ETag(&(*tasklist)[index])
+		}
+
+		if !matchPreconditions(req, current) {
+			return errPrecondition
+		}
+
+		if index < 0 {
+			created = true
+
+			tasklist.AddTask(&task)
+
+			return nil
+		}
+
+		task.ID = (*tasklist)[index].ID
+		(*tasklist)[index] = task
+
+		return nil
+	})
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	writer.Header().Set("ETag", ETag(&task))
+
+	if created {
+		writer.WriteHeader(http.StatusCreated)
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// report serves the calendar-query and calendar-multiget reports. The filters
+// of calendar-query are not evaluated, all the tasks are returned.
+func (h *Handler) report(writer http.ResponseWriter, req *http.Request) {
+	request, err := parseReport(http.MaxBytesReader(nil, req.Body, maxBodySize))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	tasklist, err := h.load()
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	status := newMultistatus()
+
+	switch {
+	case request.XMLName.Space == nsCalDAV && request.XMLName.Local == "calendar-query":
+		for i := range tasklist {
+			status.Responses = append(status.Responses, h.taskResponse(&tasklist[i], true))
+		}
+	case request.XMLName.Space == nsCalDAV && request.XMLName.Local == "calendar-multiget":
+		for _, href := range request.Hrefs {
+			uid, isResource, _ := h.resolve(href)
+
+			index := findTask(tasklist, uid)
+			if !isResource || index < 0 {
+				status.Responses = append(status.Responses, response{
+					Href: href, Status: statusLine(http.StatusNotFound), Propstat: nil,
+				})
+
+				continue
+			}
+
+			status.Responses = append(status.Responses, h.taskResponse(&tasklist[index], true))
+		}
+	default:
+		http.Error(writer, "unsupported report: "+request.XMLName.Local, http.StatusForbidden)
+
+		return
+	}
+
+	writeMultistatus(writer, status)
+}
+
+// ----------------------------------------------------------------------------
+//  Methods: helpers
+// ----------------------------------------------------------------------------
+
+// displayName returns the name of the collection.
+func (h *Handler) displayName() string {
+	if h.Name != emptyStr {
+		return h.Name
+	}
+
+	return strings.TrimSuffix(filepath.Base(h.Path), filepath.Ext(h.Path))
+}
+
+// encode returns the tasks as iCalendar.
+func (h *Handler) encode(tasklist todo.TaskList) ([]byte, error) {
+	var buf bytes.Buffer
+
+	_, err := tasklist.WriteICal(&buf, todo.ICalOptions{Clock: h.Clock, Name: h.displayName()})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode tasks")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// href returns the URL path of the resource of the task.
+func (h *Handler) href(task *todo.Task) string {
+	return h.Prefix + url.PathEscape(todo.ICalUID(task)) + resourceExt
+}
+
+// load loads the task list. A non-existing file is an empty list. If some tasks
+// have no UIDTag, one is written to the file first, as assignUIDs does.
+func (h *Handler) load() (todo.TaskList, error) {
+	tasklist, err := todo.LoadFromPath(h.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return todo.NewTaskList(), nil
+	}
+
+	if err != nil || !missingUID(tasklist) {
+		return tasklist, err
+	}
+
+	err = todo.UpdateLinesPath(h.Path, assignUIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return todo.LoadFromPath(h.Path)
+}
+
+// resolve returns the UID of the resource of the URL path and whether the path
+// is a resource or the collection. It returns false if the path is neither or
+// if the UID can not be held by a todo.txt tag.
+func (h *Handler) resolve(path string) (string, bool, bool) {
+	if path == h.Prefix || path+"/" == h.Prefix {
+		return emptyStr, false, true
+	}
+
+	name, found := strings.CutPrefix(path, h.Prefix)
+	if !found || strings.Contains(name, "/") || !strings.HasSuffix(name, resourceExt) {
+		return emptyStr, false, false
+	}
+
+	uid, err := url.PathUnescape(strings.TrimSuffix(name, resourceExt))
+	if err != nil || uid == emptyStr || strings.ContainsAny(uid, " \t\r\n") {
+		return emptyStr, false, false
+	}
+
+	return uid, true, true
+}
+
+// taskResponse returns the multistatus response of the task, with the calendar
+// data if withData is true.
+func (h *Handler) taskResponse(task *todo.Task, withData bool) response {
+	resp := response{
+		Href:   h.href(task),
+		Status: emptyStr,
+		Propstat: &propstat{
+			Prop: prop{
+				ResourceType:   &resourceType{Collection: nil, Calendar: nil},
+				ComponentSet:   nil,
+				CalendarData:   nil,
+				Privileges:     nil,
+				DisplayName:    emptyStr,
+				GetContentType: contentTypeICal,
+				GetETag:        ETag(task),
+				GetCTag:        emptyStr,
+			},
+			Status: statusLine(http.StatusOK),
+		},
+	}
+
+	if withData {
+		data, err := h.encode(todo.TaskList{*task})
+		if err == nil {
+			resp.Propstat.Prop.CalendarData = &calendarData{Data: string(data)}
+		}
+	}
+
+	return resp
+}
+
+// ----------------------------------------------------------------------------
+//  Public functions
+// ----------------------------------------------------------------------------
+
+// ETag returns the entity tag of the task, derived from its todo.txt line. Any
+// change of the task changes the tag.
+func ETag(task *todo.Task) string {
+	sum := sha256.Sum256([]byte(task.String()))
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// assignUIDs appends a UIDTag to the lines of the tasks without one. The UID is
+// the one derived by todo.ICalUID, so that the name of the resource does not
+// change, followed by a counter if it is already used by another task. The
+// other lines are kept as they are.
+func assignUIDs(lines []string) ([]string, error) {
+	tasks := make([]*todo.Task, len(lines))
+	used := map[string]bool{}
+
+	for i, line := range lines {
+		text := strings.TrimSpace(line)
+		if text == emptyStr || (todo.IgnoreComments && strings.HasPrefix(text, "#")) {
+			continue
+		}
+
+		task, err := todo.ParseTask(text)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", i+1)
+		}
+
+		tasks[i] = task
+
+		if uid := task.AdditionalTags[todo.UIDTag]; uid != emptyStr {
+			used[uid] = true
+		}
+	}
+
+	for i, task := range tasks {
+		if task == nil || task.AdditionalTags[todo.UIDTag] != emptyStr {
+			continue
+		}
+
+		derived := todo.ICalUID(task)
+
+		uid := derived
+		for count := 2; used[uid]; count++ {
+			uid = derived + "-" + strconv.Itoa(count)
+		}
+
+		used[uid] = true
+		lines[i] = strings.TrimRight(lines[i], " \t") + " " + todo.UIDTag + ":" + uid
+	}
+
+	return lines, nil
+}
+
+// findTask returns the index of the task with the UID, or -1 if not found.
+func findTask(tasklist todo.TaskList, uid string) int {
+	for i := range tasklist {
+		if todo.ICalUID(&tasklist[i]) == uid {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// icalUID returns the value of the first UID property of the iCalendar data, or
+// an empty string if there is none.
+func icalUID(data []byte) string {
+	unfolded := strings.NewReplacer("\r\n ", "", "\r\n\t", "", "\n ", "", "\n\t", "").Replace(string(data))
+
+	for _, line := range strings.Split(unfolded, "\n") {
+		name, value, found := strings.Cut(strings.TrimRight(line, "\r"), ":")
+		if !found {
+			continue
+		}
+
+		if name, _, _ = strings.Cut(name, ";"); strings.EqualFold(name, "UID") {
+			return strings.NewReplacer(`\\`, `\`, `\,`, ",", `\;`, ";").Replace(strings.TrimSpace(value))
+		}
+	}
+
+	return emptyStr
+}
+
+// matchPreconditions returns true if the "If-Match" and "If-None-Match" headers
+// match the current ETag of the resource. An empty ETag is a missing resource.
+func matchPreconditions(req *http.Request, current string) bool {
+	if ifMatch := req.Header.Get("If-Match"); ifMatch != emptyStr {
+		if current == emptyStr || (ifMatch != "*" && !containsETag(ifMatch, current)) {
+			return false
+		}
+	}
+
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != emptyStr {
+		if current != emptyStr && (ifNoneMatch == "*" || containsETag(ifNoneMatch, current)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// missingUID returns true if any of the tasks has no UIDTag.
+func missingUID(tasklist todo.TaskList) bool {
+	for i := range tasklist {
+		if tasklist[i].AdditionalTags[todo.UIDTag] == emptyStr {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsETag returns true if the comma separated list of the header holds the
+// ETag. Weak tags are compared as strong ones.
+func containsETag(header, etag string) bool {
+	for _, item := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(item), "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeError writes the HTTP error of the error.
+func writeError(writer http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errNotFound):
+		http.Error(writer, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errPrecondition):
+		http.Error(writer, err.Error(), http.StatusPreconditionFailed)
+	default:
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeMultistatus writes the 207 Multi-Status response.
+func writeMultistatus(writer http.ResponseWriter, status *multistatus) {
+	data, err := xml.Marshal(status)
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", contentTypeXML)
+	writer.WriteHeader(http.StatusMultiStatus)
+	_, _ = writer.Write([]byte(xml.Header))
+	_, _ = writer.Write(data)
+}
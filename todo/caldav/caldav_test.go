@@ -0,0 +1,336 @@
+package caldav
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KEINOS/go-todotxt/todo"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a test helper that implements the todo.Clock interface.
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+const testTodoTxt = `(A) 2024-01-01 Call Mom @Phone +Family uid:42
+Learn Go +Study
+`
+
+// testHandler returns a Handler of a temporary todo.txt file with the content.
+func testHandler(t *testing.T, content string) *Handler {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "todo.txt")
+	require.NoError(t, os.WriteFile(path, []byte(content), todo.PermReadWrite))
+
+	handler := NewHandler(path, "/tasks")
+	handler.Clock = &fakeClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	return handler
+}
+
+// testDo serves the request and returns the recorded response.
+func testDo(t *testing.T, handler http.Handler, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+// testVTodo returns an iCalendar with a single VTODO of the UID and summary.
+func testVTodo(uid, summary string) string {
+	return "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:" + uid + "\r\nSUMMARY:" + summary +
+		"\r\nPRIORITY:2\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+}
+
+// testReadFile returns the content of the file of the handler, with "\n" as
+// the line separator.
+func testReadFile(t *testing.T, handler *Handler) string {
+	t.Helper()
+
+	raw, err := os.ReadFile(handler.Path)
+	require.NoError(t, err)
+
+	return strings.ReplaceAll(string(raw), todo.NewLine, "\n")
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "/tasks/", NewHandler("todo.txt", "/tasks").Prefix)
+	require.Equal(t, "/", NewHandler("todo.txt", "").Prefix)
+}
+
+func TestHandler_OPTIONS(t *testing.T) {
+	t.Parallel()
+
+	rec := testDo(t, testHandler(t, testTodoTxt), http.MethodOptions, "/tasks/", "")
+
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Contains(t, rec.Header().Get("DAV"), "calendar-access")
+	require.Contains(t, rec.Header().Get("Allow"), "REPORT")
+}
+
+func TestHandler_PROPFIND(t *testing.T) {
+	t.Parallel()
+
+	handler := testHandler(t, testTodoTxt)
+
+	rec := testDo(t, handler, "PROPFIND", "/tasks/", "", "Depth", "0")
+	require.Equal(t, http.StatusMultiStatus, rec.Code)
+	require.Contains(t, rec.Body.String(), "<D:collection></D:collection><C:calendar></C:calendar>")
+	require.Contains(t, rec.Body.String(), `<C:comp name="VTODO"></C:comp>`)
+	require.Contains(t, rec.Body.String(), "<D:displayname>todo</D:displayname>")
+	require.NotContains(t, rec.Body.String(), "/tasks/42.ics", "depth 0 should not list the resources")
+
+	rec = testDo(t, handler, "PROPFIND", "/tasks/", "", "Depth", "1")
+	require.Equal(t, http.StatusMultiStatus, rec.Code)
+	require.Contains(t, rec.Body.String(), "<D:href>/tasks/42.ics</D:href>")
+	require.Equal(t, 3, strings.Count(rec.Body.String(), "<D:response>"))
+
+	rec = testDo(t, handler, "PROPFIND", "/tasks/42.ics", "")
+	require.Equal(t, http.StatusMultiStatus, rec.Code)
+	require.Equal(t, 1, strings.Count(rec.Body.String(), "<D:response>"))
+
+	rec = testDo(t, handler, "PROPFIND", "/tasks/unknown.ics", "")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandler_GET(t *testing.T) {
+	t.Parallel()
+
+	handler := testHandler(t, testTodoTxt)
+
+	rec := testDo(t, handler, http.MethodGet, "/tasks/42.ics", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, contentTypeICal, rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), "SUMMARY:Call Mom\r\n")
+	require.NotContains(t, rec.Body.String(), "Learn Go")
+
+	tasklist, err := todo.LoadFromPath(handler.Path)
+	require.NoError(t, err)
+	require.Equal(t, ETag(&tasklist[0]), rec.Header().Get("ETag"))
+
+	rec = testDo(t, handler, http.MethodHead, "/tasks/42.ics", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Body.String())
+
+	// Whole collection
+	rec = testDo(t, handler, http.MethodGet, "/tasks/", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, 2, strings.Count(rec.Body.String(), "BEGIN:VTODO"))
+
+	// Unknown resources and paths
+	require.Equal(t, http.StatusNotFound, testDo(t, handler, http.MethodGet, "/tasks/unknown.ics", "").Code)
+	require.Equal(t, http.StatusNotFound, testDo(t, handler, http.MethodGet, "/other/42.ics", "").Code)
+	require.Equal(t, http.StatusNotFound, testDo(t, handler, http.MethodGet, "/tasks/42.txt", "").Code)
+	require.Equal(t, http.StatusNotFound, testDo(t, handler, http.MethodGet, "/tasks/a%20b.ics", "").Code)
+}
+
+func TestHandler_PUT(t *testing.T) {
+	t.Parallel()
+
+	handler := testHandler(t, testTodoTxt)
+
+	// Create
+	rec := testDo(t, handler, http.MethodPut, "/tasks/new-1.ics", testVTodo("new-1", "Water plants"),
+		"If-None-Match", "*")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, testTodoTxt+"(B) Water plants uid:new-1\n", testReadFile(t, handler))
+
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	// Create again
+	rec = testDo(t, handler, http.MethodPut, "/tasks/new-1.ics", testVTodo("new-1", "Water plants"),
+		"If-None-Match", "*")
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code, "existing resource should not be overwritten")
+
+	// Update with outdated ETag
+	rec = testDo(t, handler, http.MethodPut, "/tasks/new-1.ics", testVTodo("new-1", "Water flowers"),
+		"If-Match", `"outdated"`)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	// Update
+	rec = testDo(t, handler, http.MethodPut, "/tasks/new-1.ics", testVTodo("new-1", "Water flowers"),
+		"If-Match", "W/"+etag)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.NotEqual(t, etag, rec.Header().Get("ETag"), "ETag should change with the content")
+	require.Equal(t, testTodoTxt+"(B) Water flowers uid:new-1\n", testReadFile(t, handler))
+
+	// Update of a task without uid tag keeps its position
+	tasklist, err := todo.LoadFromPath(handler.Path)
+	require.NoError(t, err)
+
+	rec = testDo(t, handler, http.MethodPut, "/tasks/"+todo.ICalUID(&tasklist[1])+".ics",
+		testVTodo(todo.ICalUID(&tasklist[1]), "Learn Rust"))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	tasklist, err = todo.LoadFromPath(handler.Path)
+	require.NoError(t, err)
+	require.Len(t, tasklist, 3)
+	require.Equal(t, "Learn Rust", tasklist[1].Todo)
+	require.Equal(t, 2, tasklist[1].ID)
+
+	// Invalid bodies
+	for _, body := range []string{"", "invalid", testVTodo("new-2", "foo") + testVTodo("new-2", "bar")} {
+		rec = testDo(t, handler, http.MethodPut, "/tasks/new-2.ics", body)
+		require.Equal(t, http.StatusBadRequest, rec.Code, "body %q should be rejected", body)
+	}
+
+	// UID of the body other than the resource
+	rec = testDo(t, handler, http.MethodPut, "/tasks/new-1.ics", testVTodo("new-2", "Water flowers"))
+	require.Equal(t, http.StatusBadRequest, rec.Code, "UID of the body should match the resource")
+	require.Contains(t, rec.Body.String(), "new-2")
+
+	// Too large body
+	rec = testDo(t, handler, http.MethodPut, "/tasks/new-2.ics", testVTodo("new-2", strings.Repeat("a", maxBodySize)))
+	require.Equal(t, http.StatusBadRequest, rec.Code, "too large body should be rejected")
+
+	// PUT of the collection
+	rec = testDo(t, handler, http.MethodPut, "/tasks/", testVTodo("new-2", "foo"))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestHandler_stable_uid(t *testing.T) {
+	t.Parallel()
+
+	handler := testHandler(t, "# Chores\nWater plants\n\nWater plants\nCall Mom uid:42\n")
+
+	rec := testDo(t, handler, "PROPFIND", "/tasks/", "", "Depth", "1")
+	require.Equal(t, http.StatusMultiStatus, rec.Code)
+
+	content := testReadFile(t, handler)
+	tasklist, err := todo.LoadFromPath(handler.Path)
+	require.NoError(t, err)
+	require.Len(t, tasklist, 3)
+
+	uid := tasklist[0].AdditionalTags[todo.UIDTag]
+	require.NotEmpty(t, uid, "served task should get a uid tag")
+	require.Equal(t, uid+"-2", tasklist[1].AdditionalTags[todo.UIDTag], "identical tasks should get distinct UIDs")
+	require.Equal(t, "# Chores\nWater plants uid:"+uid+"\n\nWater plants uid:"+uid+"-2\nCall Mom uid:42\n", content,
+		"comments and blank lines should be kept")
+	require.Contains(t, rec.Body.String(), "<D:href>/tasks/"+url.PathEscape(uid)+".ics</D:href>")
+	require.Contains(t, rec.Body.String(), "<D:href>/tasks/"+url.PathEscape(uid)+"-2.ics</D:href>")
+
+	// Served again without change
+	testDo(t, handler, http.MethodGet, "/tasks/", "")
+	require.Equal(t, content, testReadFile(t, handler), "tasks with UID should not be written again")
+
+	// Editing the text keeps the resource
+	require.NoError(t, os.WriteFile(handler.Path,
+		[]byte(strings.Replace(content, "Water plants", "Water flowers", 1)), todo.PermReadWrite))
+
+	rec = testDo(t, handler, http.MethodGet, "/tasks/"+url.PathEscape(uid)+".ics", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "SUMMARY:Water flowers")
+}
+
+func Test_icalUID(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "new-1", icalUID([]byte(testVTodo("new-1", "foo"))))
+	require.Equal(t, "long,uid", icalUID([]byte("BEGIN:VTODO\r\nUID;X-PARAM=1:long\r\n \\,uid\r\nEND:VTODO\r\n")))
+	require.Empty(t, icalUID([]byte("BEGIN:VTODO\r\nSUMMARY:foo\r\nEND:VTODO\r\n")))
+}
+
+func TestHandler_PUT_new_file(t *testing.T) {
+	t.Parallel()
+
+	handler := NewHandler(filepath.Join(t.TempDir(), "todo.txt"), "/")
+
+	rec := testDo(t, handler, "PROPFIND", "/", "", "Depth", "1")
+	require.Equal(t, http.StatusMultiStatus, rec.Code, "missing file should be an empty collection")
+
+	rec = testDo(t, handler, http.MethodPut, "/1.ics", testVTodo("1", "Water plants"))
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "(B) Water plants uid:1\n", testReadFile(t, handler))
+}
+
+func TestHandler_DELETE(t *testing.T) {
+	t.Parallel()
+
+	handler := testHandler(t, testTodoTxt)
+
+	rec := testDo(t, handler, http.MethodDelete, "/tasks/42.ics", "", "If-Match", `"outdated"`)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	require.Equal(t, testTodoTxt, testReadFile(t, handler), "file should be unchanged")
+
+	rec = testDo(t, handler, http.MethodDelete, "/tasks/42.ics", "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, "Learn Go +Study\n", testReadFile(t, handler))
+
+	rec = testDo(t, handler, http.MethodDelete, "/tasks/42.ics", "")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandler_REPORT(t *testing.T) {
+	t.Parallel()
+
+	handler := testHandler(t, testTodoTxt)
+
+	query := `<?xml version="1.0"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/><C:calendar-data/></D:prop>
+  <C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="VTODO"/></C:comp-filter></C:filter>
+</C:calendar-query>`
+
+	rec := testDo(t, handler, "REPORT", "/tasks/", query)
+	require.Equal(t, http.StatusMultiStatus, rec.Code)
+	require.Equal(t, 2, strings.Count(rec.Body.String(), "<C:calendar-data>"))
+	require.Contains(t, rec.Body.String(), "SUMMARY:Learn Go")
+
+	multiget := `<?xml version="1.0"?>
+<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/><C:calendar-data/></D:prop>
+  <D:href>/tasks/42.ics</D:href>
+  <D:href>/tasks/unknown.ics</D:href>
+</C:calendar-multiget>`
+
+	rec = testDo(t, handler, "REPORT", "/tasks/", multiget)
+	require.Equal(t, http.StatusMultiStatus, rec.Code)
+	require.Equal(t, 1, strings.Count(rec.Body.String(), "<C:calendar-data>"))
+	require.Contains(t, rec.Body.String(), "SUMMARY:Call Mom")
+	require.Contains(t, rec.Body.String(),
+		"<D:response><D:href>/tasks/unknown.ics</D:href><D:status>HTTP/1.1 404 Not Found</D:status></D:response>")
+
+	rec = testDo(t, handler, "REPORT", "/tasks/", `<D:sync-collection xmlns:D="DAV:"/>`)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = testDo(t, handler, "REPORT", "/tasks/", "<invalid")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = testDo(t, handler, "REPORT", "/tasks/42.ics", query)
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestETag(t *testing.T) {
+	t.Parallel()
+
+	task1, err := todo.ParseTask("Call Mom")
+	require.NoError(t, err)
+
+	task2, err := todo.ParseTask("Call Mom @Phone")
+	require.NoError(t, err)
+
+	require.Regexp(t, `^"[0-9a-f]{32}"$`, ETag(task1))
+	require.NotEqual(t, ETag(task1), ETag(task2))
+}
@@ -0,0 +1,125 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// XML namespaces of WebDAV and CalDAV.
+const (
+	nsDAV      = "DAV:"
+	nsCalDAV   = "urn:ietf:params:xml:ns:caldav"
+	nsCalendar = "http://calendarserver.org/ns/"
+)
+
+// ----------------------------------------------------------------------------
+//  Responses
+// ----------------------------------------------------------------------------
+
+// multistatus is the body of a 207 Multi-Status response.
+type multistatus struct {
+	XMLName   xml.Name   `xml:"D:multistatus"`
+	XmlnsD    string     `xml:"xmlns:D,attr"`
+	XmlnsC    string     `xml:"xmlns:C,attr"`
+	XmlnsCS   string     `xml:"xmlns:CS,attr"`
+	Responses []response `xml:"D:response"`
+}
+
+// response is a resource of a multistatus.
+type response struct {
+	Href     string    `xml:"D:href"`
+	Status   string    `xml:"D:status,omitempty"`
+	Propstat *propstat `xml:"D:propstat,omitempty"`
+}
+
+// propstat holds the properties of a resource.
+type propstat struct {
+	Prop   prop   `xml:"D:prop"`
+	Status string `xml:"D:status"`
+}
+
+// prop is the set of properties served. Empty properties are omitted.
+type prop struct {
+	ResourceType   *resourceType `xml:"D:resourcetype,omitempty"`
+	ComponentSet   *componentSet `xml:"C:supported-calendar-component-set,omitempty"`
+	CalendarData   *calendarData `xml:"C:calendar-data,omitempty"`
+	Privileges     *privilegeSet `xml:"D:current-user-privilege-set,omitempty"`
+	DisplayName    string        `xml:"D:displayname,omitempty"`
+	GetContentType string        `xml:"D:getcontenttype,omitempty"`
+	GetETag        string        `xml:"D:getetag,omitempty"`
+	GetCTag        string        `xml:"CS:getctag,omitempty"`
+}
+
+// resourceType is the resource type of the collection.
+type resourceType struct {
+	Collection *struct{} `xml:"D:collection"`
+	Calendar   *struct{} `xml:"C:calendar"`
+}
+
+// componentSet is the set of the supported calendar components.
+type componentSet struct {
+	Comps []comp `xml:"C:comp"`
+}
+
+// comp is a calendar component.
+type comp struct {
+	Name string `xml:"name,attr"`
+}
+
+// calendarData is the iCalendar data of a resource.
+type calendarData struct {
+	Data string `xml:",chardata"`
+}
+
+// privilegeSet is the set of privileges of the current user.
+type privilegeSet struct {
+	Privileges []privilege `xml:"D:privilege"`
+}
+
+// privilege is a privilege of the current user.
+type privilege struct {
+	Read  *struct{} `xml:"D:read,omitempty"`
+	Write *struct{} `xml:"D:write,omitempty"`
+}
+
+// newMultistatus returns an empty multistatus with the namespaces.
+func newMultistatus() *multistatus {
+	return &multistatus{
+		XMLName:   xml.Name{Space: emptyStr, Local: emptyStr},
+		XmlnsD:    nsDAV,
+		XmlnsC:    nsCalDAV,
+		XmlnsCS:   nsCalendar,
+		Responses: []response{},
+	}
+}
+
+// statusLine returns the HTTP status line for the multistatus.
+func statusLine(code int) string {
+	return "HTTP/1.1 " + strconv.Itoa(code) + " " + http.StatusText(code)
+}
+
+// ----------------------------------------------------------------------------
+//  Requests
+// ----------------------------------------------------------------------------
+
+// reportRequest is the body of a REPORT request. Only calendar-query and
+// calendar-multiget are supported.
+type reportRequest struct {
+	XMLName xml.Name
+	Hrefs   []string `xml:"DAV: href"`
+}
+
+// parseReport parses the body of a REPORT request.
+func parseReport(body io.Reader) (reportRequest, error) {
+	var report reportRequest
+
+	if err := xml.NewDecoder(body).Decode(&report); err != nil {
+		return report, errors.Wrap(err, "invalid REPORT body")
+	}
+
+	return report, nil
+}
@@ -29,6 +29,14 @@ const (
 	PermReadWriteExec = 0o755
 	// DateLayout is used for formatting time.Time into todo.txt date format and vice-versa.
 	DateLayout = "2006-01-02"
+	// UIDTag is the key of the additional tag holding a stable identifier of a
+	// task (e.g. "uid:4f1c..."). It is used to identify the same task across
+	// different lists, such as on merge.
+	UIDTag = "uid"
+	// ThresholdTag is the key of the additional tag of the threshold date, the
+	// date before which many todo.txt clients hide the task (e.g.
+	// "t:2024-01-10").
+	ThresholdTag = "t"
 
 	// contextPrefix is the prefix for contexts.
 	contextPrefix = "@"
@@ -77,6 +85,9 @@ var (
 	contextRx = regexp.MustCompile(`(^|\s+)@(\S+)`)
 	// Match projects: '+Project...' or '... +Project ...'.
 	projectRx = regexp.MustCompile(`(^|\s+)\+(\S+)`)
+	// Match a leading word read as completion, priority or date: 'x ...' or
+	// '(A) ...' or '2012-12-12 ...'.
+	leadingMarkerRx = regexp.MustCompile(`^(x|\([A-Z]\)|\d{4}-\d{2}-\d{2})\s`)
 )
 
 // ----------------------------------------------------------------------------
@@ -102,6 +113,17 @@ func WriteToPath(tasklist *TaskList, filename string) error {
 //  Private functions
 // ----------------------------------------------------------------------------
 
+// escapeLeadingMarker escapes the text of a task with a backslash if it starts
+// with a word which would be read as the completion mark, the priority or the
+// created date of the task.
+func escapeLeadingMarker(text string) string {
+	if leadingMarkerRx.MatchString(text) {
+		return `\` + text
+	}
+
+	return text
+}
+
 // It will collect projects/contexts from txtOrig and returns them as a slice.
 func getSlice(txtOrig string, rx *regexp.Regexp) []string {
 	matches := rx.FindAllStringSubmatch(txtOrig, -1)
@@ -252,6 +274,25 @@ func parseTime(s string) (time.Time, error) {
 	return parsed, nil
 }
 
+// reparseTask returns the task parsed from its todo.txt line, to make sure that
+// a task built from the fields of another format is valid. If the text of the
+// task would be read as its completion mark, priority or dates, such as a Todo
+// "x marks the spot" of a task not completed, the text is escaped with
+// escapeLeadingMarker. It is not escaped otherwise, so that the Todo of a task
+// parsed from "(A) (B) foo" is kept as "(B) foo".
+func reparseTask(task *Task) (*Task, error) {
+	parsed, err := ParseTask(task.String())
+	if err == nil && parsed.Completed == task.Completed && parsed.Priority == task.Priority &&
+		parsed.CompletedDate.Equal(task.CompletedDate) && parsed.CreatedDate.Equal(task.CreatedDate) {
+		return parsed, nil
+	}
+
+	escaped := cloneTask(*task)
+	escaped.Todo = escapeLeadingMarker(task.Todo)
+
+	return ParseTask(escaped.String())
+}
+
 func sortByDate(asc bool, hasDate1, hasDate2 bool, date1, date2 time.Time) bool {
 	// ASC
 	if asc {
@@ -0,0 +1,437 @@
+package todo
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: CSVColumn
+// ----------------------------------------------------------------------------
+
+// CSVColumn represents a column of the CSV format. It is also the name of the
+// column in the header row.
+type CSVColumn string
+
+// Columns of the CSV format. For a column of a single additional tag, use
+// CSVTagColumn.
+const (
+	CSVColumnID        CSVColumn = "id"        // ID of the task.
+	CSVColumnDone      CSVColumn = "done"      // "x" if completed, empty otherwise.
+	CSVColumnPriority  CSVColumn = "priority"  // Priority letter, such as "A".
+	CSVColumnCreated   CSVColumn = "created"   // Created date in "YYYY-MM-DD" format.
+	CSVColumnCompleted CSVColumn = "completed" // Completed date in "YYYY-MM-DD" format.
+	CSVColumnDue       CSVColumn = "due"       // Due date in "YYYY-MM-DD" format.
+	CSVColumnText      CSVColumn = "text"      // Text of the task (Task.Todo).
+	CSVColumnContexts  CSVColumn = "contexts"  // Contexts without "@", delimited by ListSeparator.
+	CSVColumnProjects  CSVColumn = "projects"  // Projects without "+", delimited by ListSeparator.
+	CSVColumnTags      CSVColumn = "tags"      // Tags without own column as "key:value", delimited by ListSeparator.
+
+	// csvTagPrefix is the prefix of the columns of a single tag.
+	csvTagPrefix = "tag:"
+)
+
+// DefaultCSVColumns are the columns used if CSVOptions.Columns is empty. They
+// hold all the fields of a task, so that the CSV round-trips.
+//
+//nolint:gochecknoglobals // it is intentionally global as a default
+var DefaultCSVColumns = []CSVColumn{
+	CSVColumnID,
+	CSVColumnDone,
+	CSVColumnPriority,
+	CSVColumnCreated,
+	CSVColumnCompleted,
+	CSVColumnDue,
+	CSVColumnText,
+	CSVColumnContexts,
+	CSVColumnProjects,
+	CSVColumnTags,
+}
+
+// CSVTagColumn returns the column of the value of the given additional tag. The
+// name of the column is "tag:" followed by the key (e.g. "tag:uid").
+func CSVTagColumn(key string) CSVColumn {
+	return CSVColumn(csvTagPrefix + key)
+}
+
+// tagKey returns the key of a tag column and true, or false if not a tag column.
+func (column CSVColumn) tagKey() (string, bool) {
+	key, found := strings.CutPrefix(string(column), csvTagPrefix)
+
+	return key, found && isNotEmpty(key)
+}
+
+// validate returns an error if the column is unknown.
+func (column CSVColumn) validate() error {
+	if _, isTag := column.tagKey(); isTag {
+		return nil
+	}
+
+	for _, known := range DefaultCSVColumns {
+		if column == known {
+			return nil
+		}
+	}
+
+	return errors.New("unknown CSV column: " + string(column))
+}
+
+// ----------------------------------------------------------------------------
+//  Type: CSVOptions
+// ----------------------------------------------------------------------------
+
+// CSVOptions are the options of the CSV format. The zero value is a comma
+// separated CSV with a header row and DefaultCSVColumns.
+type CSVOptions struct {
+	// Columns to write, in order. Defaults to DefaultCSVColumns. On reading,
+	// it is used only with NoHeader, otherwise the header row is used.
+	Columns []CSVColumn
+	// ListSeparator delimits the values of multi-valued fields. Defaults to a
+	// space, which never appears in contexts, projects nor tags.
+	ListSeparator string
+	// Comma is the field delimiter. Defaults to ','.
+	Comma rune
+	// NoHeader omits the header row on writing, and does not expect it on
+	// reading.
+	NoHeader bool
+}
+
+// columns returns the columns or the default ones.
+func (opts CSVOptions) columns() []CSVColumn {
+	if len(opts.Columns) == 0 {
+		return DefaultCSVColumns
+	}
+
+	return opts.Columns
+}
+
+// separator returns the list separator or the default one.
+func (opts CSVOptions) separator() string {
+	if opts.ListSeparator == emptyStr {
+		return " "
+	}
+
+	return opts.ListSeparator
+}
+
+// ----------------------------------------------------------------------------
+//  Public functions
+// ----------------------------------------------------------------------------
+
+// LoadFromCSV loads a TaskList from CSV. See CSVOptions for the format.
+//
+// Unknown columns in the header row are ignored, so extra columns added in a
+// spreadsheet do no harm. Each row is converted to a todo.txt line and parsed
+// as ParseTask does, so the result is always valid todo.txt. Tasks without an ID
+// get their position in the list, starting from 1, and empty rows are skipped.
+//
+// Line breaks in the cells are replaced by spaces, since a task is a single
+// line. A text starting with a word which would be read as the completion mark,
+// the priority or a date of the task, such as "x" in a row which is not done, is
+// escaped with a leading backslash. An invalid priority is an error.
+func LoadFromCSV(reader io.Reader, opts CSVOptions) (TaskList, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	if opts.Comma != 0 {
+		csvReader.Comma = opts.Comma
+	}
+
+	columns := opts.columns()
+
+	if opts.NoHeader {
+		if err := validateColumns(columns); err != nil {
+			return nil, err
+		}
+	} else {
+		header, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			return NewTaskList(), nil
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read CSV header")
+		}
+
+		columns = make([]CSVColumn, len(header))
+		for i, name := range header {
+			columns[i] = CSVColumn(strings.TrimSpace(name))
+			if _, isTag := columns[i].tagKey(); !isTag {
+				columns[i] = CSVColumn(strings.ToLower(string(columns[i])))
+			}
+		}
+	}
+
+	tasklist := NewTaskList()
+
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read CSV")
+		}
+
+		task, err := csvToTask(columns, record, opts.separator())
+		if err != nil {
+			line, _ := csvReader.FieldPos(0)
+
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+
+		if task == nil {
+			continue
+		}
+
+		if task.ID == 0 {
+			task.ID = len(tasklist) + 1
+		}
+
+		tasklist = append(tasklist, *task)
+	}
+
+	return tasklist, nil
+}
+
+// ----------------------------------------------------------------------------
+//  TaskList.WriteCSV()
+// ----------------------------------------------------------------------------
+
+// WriteCSV writes the TaskList as CSV. See CSVOptions for the format.
+func (tasklist *TaskList) WriteCSV(writer io.Writer, opts CSVOptions) error {
+	columns := opts.columns()
+
+	if err := validateColumns(columns); err != nil {
+		return err
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.UseCRLF = NewLine == "\r\n"
+
+	if opts.Comma != 0 {
+		csvWriter.Comma = opts.Comma
+	}
+
+	if !opts.NoHeader {
+		header := make([]string, len(columns))
+		for i, column := range columns {
+			header[i] = string(column)
+		}
+
+		if err := csvWriter.Write(header); err != nil {
+			return errors.Wrap(err, "failed to write CSV header")
+		}
+	}
+
+	for i := range *tasklist {
+		record := taskToCSV(&(*tasklist)[i], columns, opts.separator())
+
+		if err := csvWriter.Write(record); err != nil {
+			return errors.Wrap(err, "failed to write CSV")
+		}
+	}
+
+	csvWriter.Flush()
+
+	return errors.Wrap(csvWriter.Error(), "failed to write CSV")
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// csvToTask converts the CSV record to a Task. It returns nil if the record is
+// empty.
+//
+//nolint:cyclop // a simple switch over the columns
+func csvToTask(columns []CSVColumn, record []string, separator string) (*Task, error) {
+	task := new(Task)
+	taskID := 0
+	empty := true
+
+	for i, value := range record {
+		value = strings.Join(strings.Fields(value), " ") // a task is a single line
+		if i >= len(columns) || isEmpty(value) {
+			continue
+		}
+
+		empty = false
+
+		var err error
+
+		switch columns[i] {
+		case CSVColumnID:
+			taskID, err = strconv.Atoi(value)
+		case CSVColumnDone:
+			task.Completed = isTruthy(value)
+		case CSVColumnPriority:
+			task.Priority, err = csvPriority(value)
+		case CSVColumnCreated:
+			task.CreatedDate, err = parseTime(value)
+		case CSVColumnCompleted:
+			task.CompletedDate, err = parseTime(value)
+		case CSVColumnDue:
+			task.DueDate, err = parseTime(value)
+		case CSVColumnText:
+			task.Todo = value
+		case CSVColumnContexts:
+			task.Contexts = splitList(value, separator, contextPrefix)
+		case CSVColumnProjects:
+			task.Projects = splitList(value, separator, projectPrefix)
+		case CSVColumnTags:
+			for _, item := range splitList(value, separator, emptyStr) {
+				key, val, _ := strings.Cut(item, ":")
+				setTag(task, key, val)
+			}
+		default:
+			if key, isTag := columns[i].tagKey(); isTag {
+				setTag(task, key, value)
+			}
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid value of "+string(columns[i]))
+		}
+	}
+
+	if empty {
+		return nil, nil //nolint:nilnil // nil task means an empty record
+	}
+
+	// Parse the todo.txt line to make sure it is valid
+	parsed, err := reparseTask(task)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed.ID = taskID
+
+	return parsed, nil
+}
+
+// csvPriority returns the priority letter of the value, in upper case.
+func csvPriority(value string) (string, error) {
+	priority := strings.ToUpper(value)
+	if len(priority) != 1 || priority[0] < 'A' || priority[0] > 'Z' {
+		return emptyStr, errors.New("priority must be a letter from A to Z: " + value)
+	}
+
+	return priority, nil
+}
+
+// isTruthy returns true if the value means "yes", such as "x" or "true".
+func isTruthy(value string) bool {
+	switch strings.ToLower(value) {
+	case "x", "1", "y", "yes", "true", "done":
+		return true
+	}
+
+	return false
+}
+
+// setTag sets the additional tag to the task. The "due" tag sets the due date
+// unless already set.
+func setTag(task *Task, key, value string) {
+	if isEmpty(key) || isEmpty(value) {
+		return
+	}
+
+	if key == "due" {
+		if date, err := parseTime(value); err == nil && task.DueDate.IsZero() {
+			task.DueDate = date
+		}
+
+		return
+	}
+
+	if task.AdditionalTags == nil {
+		task.AdditionalTags = map[string]string{}
+	}
+
+	task.AdditionalTags[key] = value
+}
+
+// splitList splits the value by the separator and removes the prefix and the
+// empty items.
+func splitList(value, separator, prefix string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, separator) {
+		item = strings.TrimPrefix(strings.TrimSpace(item), prefix)
+		if isNotEmpty(item) {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// validateColumns returns an error if any of the columns is unknown.
+func validateColumns(columns []CSVColumn) error {
+	for _, column := range columns {
+		if err := column.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// taskToCSV converts the task to a CSV record of the columns.
+func taskToCSV(task *Task, columns []CSVColumn, separator string) []string {
+	record := make([]string, len(columns))
+	ownColumn := map[string]bool{}
+
+	for _, column := range columns {
+		if key, isTag := column.tagKey(); isTag {
+			ownColumn[key] = true
+		}
+	}
+
+	for i, column := range columns {
+		switch column {
+		case CSVColumnID:
+			record[i] = strconv.Itoa(task.ID)
+		case CSVColumnDone:
+			if task.Completed {
+				record[i] = "x"
+			}
+		case CSVColumnPriority:
+			record[i] = task.Priority
+		case CSVColumnCreated:
+			record[i] = formatDate(task.CreatedDate)
+		case CSVColumnCompleted:
+			record[i] = formatDate(task.CompletedDate)
+		case CSVColumnDue:
+			record[i] = formatDate(task.DueDate)
+		case CSVColumnText:
+			record[i] = task.Todo
+		case CSVColumnContexts:
+			record[i] = strings.Join(task.Contexts, separator)
+		case CSVColumnProjects:
+			record[i] = strings.Join(task.Projects, separator)
+		case CSVColumnTags:
+			items := []string{}
+
+			for _, key := range sortedKeys(tagKeys(task.AdditionalTags)) {
+				if !ownColumn[key] {
+					items = append(items, key+":"+task.AdditionalTags[key])
+				}
+			}
+
+			record[i] = strings.Join(items, separator)
+		default:
+			if key, isTag := column.tagKey(); isTag {
+				record[i] = task.AdditionalTags[key]
+			}
+		}
+	}
+
+	return record
+}
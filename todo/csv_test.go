@@ -0,0 +1,174 @@
+package todo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskList_WriteCSV(t *testing.T) {
+	t.Parallel()
+
+	tasklist := testMustLoad(t, `(A) 2024-01-01 Call Mom, then Dad @Phone @Home +Family due:2024-01-05 uid:42
+x 2024-01-03 2024-01-02 Learn Go +Study level:basic
+`)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, tasklist.WriteCSV(&buf, CSVOptions{}))
+
+	expect := `id,done,priority,created,completed,due,text,contexts,projects,tags
+1,,A,2024-01-01,,2024-01-05,"Call Mom, then Dad",Home Phone,Family,uid:42
+2,x,,2024-01-02,2024-01-03,,Learn Go,,Study,level:basic
+`
+	require.Equal(t, strings.ReplaceAll(expect, "\n", NewLine), buf.String())
+
+	// Custom columns, separator and delimiter
+	buf.Reset()
+
+	opts := CSVOptions{
+		Columns:       []CSVColumn{CSVColumnText, CSVColumnContexts, CSVTagColumn("uid"), CSVColumnTags},
+		ListSeparator: "|",
+		Comma:         ';',
+		NoHeader:      true,
+	}
+
+	require.NoError(t, tasklist.WriteCSV(&buf, opts))
+
+	expect = `Call Mom, then Dad;Home|Phone;42;
+Learn Go;;;level:basic
+`
+	require.Equal(t, strings.ReplaceAll(expect, "\n", NewLine), buf.String(), "tags with own column should be excluded from tags")
+
+	err := tasklist.WriteCSV(&buf, CSVOptions{Columns: []CSVColumn{"unknown"}})
+	require.ErrorContains(t, err, "unknown CSV column: unknown")
+}
+
+func TestLoadFromCSV_round_trip(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{testInputTask, testInputSort, testInputFilter, testInputTasklist} {
+		expect, err := LoadFromPath(path)
+		require.NoError(t, err)
+
+		for _, opts := range []CSVOptions{
+			{Columns: nil, ListSeparator: "", Comma: 0, NoHeader: false},
+			{Columns: nil, ListSeparator: ";", Comma: '\t', NoHeader: true},
+		} {
+			var buf bytes.Buffer
+
+			require.NoError(t, expect.WriteCSV(&buf, opts))
+
+			actual, err := LoadFromCSV(&buf, opts)
+			require.NoError(t, err)
+			require.Equal(t, expect.String(), actual.String(), "%s should round-trip", path)
+
+			for i := range expect {
+				require.Equal(t, expect[i].ID, actual[i].ID)
+			}
+		}
+	}
+}
+
+func TestLoadFromCSV(t *testing.T) {
+	t.Parallel()
+
+	input := `Text , Done,priority,due,contexts,projects,tag:uid,tags,notes
+Call Mom,,b,2024-01-05,@Phone Home,+Family,42,due:2024-01-06 level:1,ignored
+,,,,,,,,
+Learn Go,yes,,,,,,,
+`
+
+	tasklist, err := LoadFromCSV(strings.NewReader(input), CSVOptions{})
+	require.NoError(t, err)
+	require.Len(t, tasklist, 2, "empty rows should be skipped")
+	require.Equal(t, strings.ReplaceAll(
+		"(B) Call Mom @Home @Phone +Family level:1 uid:42 due:2024-01-05\nx Learn Go\n", "\n", NewLine),
+		tasklist.String())
+	require.Equal(t, 1, tasklist[0].ID)
+	require.Equal(t, 2, tasklist[1].ID, "missing ID should be the position")
+
+	// Empty
+	tasklist, err = LoadFromCSV(strings.NewReader(""), CSVOptions{})
+	require.NoError(t, err)
+	require.Empty(t, tasklist)
+
+	// Errors
+	for _, input := range []string{
+		"id,text\nfoo,bar\n",
+		"due,text\n2024-02-30,bar\n",
+		"text\n\"unterminated\n",
+	} {
+		_, err := LoadFromCSV(strings.NewReader(input), CSVOptions{})
+		require.Error(t, err, "invalid input should fail: %q", input)
+	}
+
+	_, err = LoadFromCSV(strings.NewReader("foo\n"), CSVOptions{Columns: []CSVColumn{"unknown"}, NoHeader: true})
+	require.Error(t, err, "unknown column without header should fail")
+}
+
+func TestWriteCSV_LoadFromCSV_leading_marker(t *testing.T) {
+	t.Parallel()
+
+	tasklist := testTaskList(t, "(A) (B) foo\n2024-01-02 2024-01-03 bar\n\\x marks the spot\n")
+	want := []string{"(A) (B) foo", "2024-01-02 2024-01-03 bar", `\x marks the spot`}
+
+	for range 2 {
+		var buf bytes.Buffer
+
+		require.NoError(t, tasklist.WriteCSV(&buf, CSVOptions{}))
+
+		var err error
+
+		tasklist, err = LoadFromCSV(&buf, CSVOptions{})
+		require.NoError(t, err)
+
+		for i, line := range want {
+			require.Equal(t, line, tasklist[i].String(), "round trip should not add a backslash")
+		}
+	}
+
+	require.Equal(t, "(B) foo", tasklist[0].Todo)
+}
+
+func TestLoadFromCSV_sanitize(t *testing.T) {
+	t.Parallel()
+
+	input := "text,priority\n" +
+		"\"Call Mom\nabout the\r\ntrip\",a\n" +
+		"x marks the spot,\n" +
+		"(A) is the best grade,\n" +
+		"2024-01-05 is the deadline,\n"
+
+	tasklist, err := LoadFromCSV(strings.NewReader(input), CSVOptions{})
+	require.NoError(t, err)
+	require.Len(t, tasklist, 4, "line breaks in a cell should not split the task")
+	require.Equal(t, "(A) Call Mom about the trip", tasklist[0].String())
+
+	for i, text := range []string{"x marks the spot", "(A) is the best grade", "2024-01-05 is the deadline"} {
+		task := tasklist[i+1]
+		require.False(t, task.Completed, "leading text should not be read as the completion mark")
+		require.False(t, task.HasPriority(), "leading text should not be read as the priority")
+		require.False(t, task.HasCreatedDate(), "leading text should not be read as the created date")
+		require.Equal(t, `\`+text, task.String(), "leading marker should be escaped")
+
+		reloaded, err := ParseTask(task.String())
+		require.NoError(t, err)
+		require.Equal(t, task.String(), reloaded.String(), "escaped task should survive a reload")
+	}
+
+	// Escaped only if needed
+	tasklist, err = LoadFromCSV(strings.NewReader("text,priority,created\n(B) foo,A,\nx foo,,2024-01-02\n"), CSVOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "(A) (B) foo", tasklist[0].String(), "text after the priority should not be escaped")
+	require.Equal(t, "2024-01-02 x foo", tasklist[1].String(), "text after the created date should not be escaped")
+
+	// Invalid priority
+	for _, priority := range []string{"AB", "1", "-"} {
+		_, err := LoadFromCSV(strings.NewReader("text,priority\nCall Mom,B\nBuy milk,"+priority+"\n"), CSVOptions{})
+		require.ErrorContains(t, err, "line 3", "error should name the row")
+		require.ErrorContains(t, err, "priority must be a letter from A to Z: "+priority)
+	}
+}
@@ -0,0 +1,268 @@
+package todo
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: ChangeKind
+// ----------------------------------------------------------------------------
+
+// ChangeKind represents the kind of change of a task between two TaskLists.
+type ChangeKind string
+
+// Kinds of changes reported by Diff.
+const (
+	ChangeAdded     ChangeKind = "added"     // The task was added.
+	ChangeRemoved   ChangeKind = "removed"   // The task was removed.
+	ChangeCompleted ChangeKind = "completed" // The task was completed, other fields may have changed too.
+	ChangeReopened  ChangeKind = "reopened"  // The task was reopened, other fields may have changed too.
+	ChangeModified  ChangeKind = "modified"  // Any other field of the task was changed.
+)
+
+// ----------------------------------------------------------------------------
+//  Type: FieldChange
+// ----------------------------------------------------------------------------
+
+// FieldChange represents the change of a single field of a task.
+//
+// For single valued fields Old and New are set in todo.txt format (empty if not
+// set). For contexts, projects and tags, the added and removed items are set
+// instead (tags in "key:value" format).
+type FieldChange struct {
+	Field   TaskField `json:"field"`
+	Old     string    `json:"old,omitempty"`
+	New     string    `json:"new,omitempty"`
+	Added   []string  `json:"added,omitempty"`
+	Removed []string  `json:"removed,omitempty"`
+}
+
+// String returns the change in a human-readable format. For example:
+//
+//	"priority: B -> A"
+//	"contexts: +@Home -@Office"
+func (change FieldChange) String() string {
+	if change.Added == nil && change.Removed == nil {
+		return string(change.Field) + ": " + orNone(change.Old) + " -> " + orNone(change.New)
+	}
+
+	prefix := map[TaskField]string{FieldContexts: contextPrefix, FieldProjects: projectPrefix}[change.Field]
+	items := make([]string, 0, len(change.Added)+len(change.Removed))
+
+	for _, item := range change.Added {
+		items = append(items, "+"+prefix+item)
+	}
+
+	for _, item := range change.Removed {
+		items = append(items, "-"+prefix+item)
+	}
+
+	return string(change.Field) + ": " + strings.Join(items, " ")
+}
+
+// ----------------------------------------------------------------------------
+//  Type: TaskChange
+// ----------------------------------------------------------------------------
+
+// TaskChange represents the change of a task between two TaskLists.
+type TaskChange struct {
+	Old    *Task         // Old version of the task. nil if added.
+	New    *Task         // New version of the task. nil if removed.
+	Kind   ChangeKind    // Kind of the change.
+	Fields []FieldChange // Fields changed. Empty if added or removed.
+}
+
+// MarshalJSON implements the json.Marshaler interface. The tasks are encoded
+// as todo.txt lines.
+//
+//	{"kind":"modified","old":"(B) Call Mom","new":"(A) Call Mom","fields":[...]}
+func (change TaskChange) MarshalJSON() ([]byte, error) {
+	type jsonTaskChange struct {
+		Old    *string       `json:"old"`
+		New    *string       `json:"new"`
+		Kind   ChangeKind    `json:"kind"`
+		Fields []FieldChange `json:"fields"`
+	}
+
+	//nolint:exhaustruct // tasks are set below
+	encoded := jsonTaskChange{Kind: change.Kind, Fields: change.Fields}
+
+	if change.Old != nil {
+		line := change.Old.String()
+		encoded.Old = &line
+	}
+
+	if change.New != nil {
+		line := change.New.String()
+		encoded.New = &line
+	}
+
+	if encoded.Fields == nil {
+		encoded.Fields = []FieldChange{}
+	}
+
+	return json.Marshal(encoded) //nolint:wrapcheck // no need to wrap
+}
+
+// String returns the change in a human-readable format. The task is followed
+// by the changed fields, one per line and indented. For example:
+//
+//	"modified: (A) Call Mom\n    priority: B -> A"
+func (change TaskChange) String() string {
+	task := change.New
+	if task == nil {
+		task = change.Old
+	}
+
+	lines := []string{string(change.Kind) + ": " + task.String()}
+
+	for _, field := range change.Fields {
+		lines = append(lines, "    "+field.String())
+	}
+
+	return strings.Join(lines, NewLine)
+}
+
+// ----------------------------------------------------------------------------
+//  Type: ChangeSet
+// ----------------------------------------------------------------------------
+
+// ChangeSet is the list of changes between two TaskLists returned by Diff.
+type ChangeSet []TaskChange
+
+// String returns the changes in a human-readable format, one change per line
+// followed by the changed fields. For example:
+//
+//	added: Pick up milk @GroceryStore
+//	completed: x 2024-01-02 Call Mom
+//	modified: (A) Plan backyard herb garden @Home due:2024-01-05
+//	    priority: B -> A
+//	    due_date: 2024-01-01 -> 2024-01-05
+func (changes ChangeSet) String() string {
+	var strBldr strings.Builder
+
+	for _, change := range changes {
+		strBldr.WriteString(change.String() + NewLine)
+	}
+
+	return strBldr.String()
+}
+
+// ----------------------------------------------------------------------------
+//  Public functions
+// ----------------------------------------------------------------------------
+
+// Diff returns the semantic changes from TaskList a to TaskList b.
+//
+// Tasks are matched the same way as Merge does (UIDTag, text or a fuzzy
+// similarity of the text). The changes of the tasks in a come first in the
+// order of a, followed by the added tasks in the order of b. Unchanged tasks
+// are not reported.
+func Diff(a, b TaskList) ChangeSet {
+	matches := matchTasks(a, b)
+	matchedB := make([]bool, len(b))
+	changes := ChangeSet{}
+
+	for i, j := range matches {
+		if j < 0 {
+			changes = append(changes, TaskChange{Old: &a[i], New: nil, Kind: ChangeRemoved, Fields: nil})
+
+			continue
+		}
+
+		matchedB[j] = true
+
+		fields := diffFields(&a[i], &b[j])
+		if len(fields) == 0 {
+			continue
+		}
+
+		kind := ChangeModified
+
+		switch {
+		case !a[i].Completed && b[j].Completed:
+			kind = ChangeCompleted
+		case a[i].Completed && !b[j].Completed:
+			kind = ChangeReopened
+		}
+
+		changes = append(changes, TaskChange{Old: &a[i], New: &b[j], Kind: kind, Fields: fields})
+	}
+
+	for j := range b {
+		if !matchedB[j] {
+			changes = append(changes, TaskChange{Old: nil, New: &b[j], Kind: ChangeAdded, Fields: nil})
+		}
+	}
+
+	return changes
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// diffFields returns the changed fields from oldTask to newTask in the order
+// of the task segments.
+func diffFields(oldTask, newTask *Task) []FieldChange {
+	var changes []FieldChange
+
+	for _, scalar := range scalarFields {
+		valOld, valNew := scalar.get(oldTask), scalar.get(newTask)
+		if valOld != valNew {
+			changes = append(changes, FieldChange{
+				Field: scalar.field, Old: valOld, New: valNew, Added: nil, Removed: nil,
+			})
+		}
+	}
+
+	sets := []struct {
+		oldItems, newItems map[string]bool
+		field              TaskField
+	}{
+		{toSet(oldTask.Contexts), toSet(newTask.Contexts), FieldContexts},
+		{toSet(oldTask.Projects), toSet(newTask.Projects), FieldProjects},
+		{tagItems(oldTask.AdditionalTags), tagItems(newTask.AdditionalTags), FieldTags},
+	}
+
+	for _, set := range sets {
+		var added, removed []string
+
+		for _, item := range sortedKeys(set.oldItems, set.newItems) {
+			switch {
+			case set.newItems[item] && !set.oldItems[item]:
+				added = append(added, item)
+			case set.oldItems[item] && !set.newItems[item]:
+				removed = append(removed, item)
+			}
+		}
+
+		if added != nil || removed != nil {
+			changes = append(changes, FieldChange{
+				Field: set.field, Old: emptyStr, New: emptyStr, Added: added, Removed: removed,
+			})
+		}
+	}
+
+	return changes
+}
+
+// orNone returns "(none)" if the value is empty.
+func orNone(value string) string {
+	if isEmpty(value) {
+		return "(none)"
+	}
+
+	return value
+}
+
+// tagItems returns the tags as a set of "key:value" strings.
+func tagItems(tags map[string]string) map[string]bool {
+	set := make(map[string]bool, len(tags))
+	for key, value := range tags {
+		set[key+":"+value] = true
+	}
+
+	return set
+}
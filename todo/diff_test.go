@@ -0,0 +1,94 @@
+package todo
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	listA := testMustLoad(t, `
+		(B) Call Mom @Phone +Family
+		Pick up milk @GroceryStore
+		(C) Plan backyard herb garden @Home due:2024-01-01 level:1
+		x 2024-01-01 Download Todo.txt mobile app
+		Unchanged task
+	`)
+	listB := testMustLoad(t, `
+		Unchanged task
+		x 2024-01-02 (B) Call Mom @Phone +Family
+		(A) Plan backyard herb garden @Garden due:2024-01-05 level:2
+		Download Todo.txt mobile app
+		Water plants
+	`)
+
+	changes := Diff(listA, listB)
+
+	require.Len(t, changes, 5)
+
+	kinds := make([]ChangeKind, len(changes))
+	for i, change := range changes {
+		kinds[i] = change.Kind
+	}
+
+	require.Equal(t, []ChangeKind{
+		ChangeCompleted, ChangeRemoved, ChangeModified, ChangeReopened, ChangeAdded,
+	}, kinds)
+
+	require.Equal(t, []FieldChange{
+		{Field: FieldCompleted, Old: "", New: "x 2024-01-02", Added: nil, Removed: nil},
+	}, changes[0].Fields)
+	require.Nil(t, changes[1].New)
+	require.Nil(t, changes[4].Old)
+
+	expect := strings.Join([]string{
+		"completed: x 2024-01-02 (B) Call Mom @Phone +Family",
+		"    completed: (none) -> x 2024-01-02",
+		"removed: Pick up milk @GroceryStore",
+		"modified: (A) Plan backyard herb garden @Garden level:2 due:2024-01-05",
+		"    priority: C -> A",
+		"    due_date: 2024-01-01 -> 2024-01-05",
+		"    contexts: +@Garden -@Home",
+		"    tags: +level:2 -level:1",
+		"reopened: Download Todo.txt mobile app",
+		"    completed: x 2024-01-01 -> (none)",
+		"added: Water plants",
+		"",
+	}, NewLine)
+
+	require.Equal(t, expect, changes.String())
+}
+
+func TestDiff_no_changes(t *testing.T) {
+	t.Parallel()
+
+	tasklist := testMustLoad(t, "(A) Call Mom\nPick up milk")
+
+	changes := Diff(tasklist, tasklist)
+
+	require.Empty(t, changes)
+	require.Empty(t, changes.String())
+}
+
+func TestChangeSet_json(t *testing.T) {
+	t.Parallel()
+
+	listA := testMustLoad(t, "(B) Call Mom +Family\nPick up milk")
+	listB := testMustLoad(t, "(A) Call Mom\nWater plants")
+
+	raw, err := json.Marshal(Diff(listA, listB))
+	require.NoError(t, err)
+
+	expect := `[` +
+		`{"old":"(B) Call Mom +Family","new":"(A) Call Mom","kind":"modified","fields":[` +
+		`{"field":"priority","old":"B","new":"A"},` +
+		`{"field":"projects","removed":["Family"]}]},` +
+		`{"old":"Pick up milk","new":null,"kind":"removed","fields":[]},` +
+		`{"old":null,"new":"Water plants","kind":"added","fields":[]}` +
+		`]`
+	require.JSONEq(t, expect, string(raw))
+}
@@ -12,6 +12,27 @@ Key features:
 - Create and modify tasks programmatically
 - Filter and sort tasks based on various criteria
 - Load and save task lists from/to files
+- Update files safely with advisory locking and atomic writes (UpdatePath, UpdateLinesPath)
+- Watch a todo.txt file for added, removed, completed or modified tasks (WatchPath)
+- Hooks before and after the changes of a task list, able to veto them (HookedTaskList)
+- Undo and redo history of the changes, persisted beside the todo.txt file (History)
+- Three-way merge of task lists, usable as a git merge driver (Merge, MergeFiles)
+- Semantic diff of task lists as text or JSON (Diff)
+- Archive completed tasks to done.txt like todo.sh (ArchivePath, ArchiveLinesPath)
+- Workspace of todo.txt, done.txt, report.txt and other lists located via TODO_DIR
+- Read and write report.txt of todo.sh and its history as time series (Report)
+- Statistics and burndown/burnup series in the "stats" sub-package
+- JSON encoding of tasks and task lists with a JSON Schema (JSONSchema)
+- encoding.TextMarshaler for Task, TaskList, TaskSortByType and TaskSegmentType
+- CSV import and export with configurable columns (LoadFromCSV, WriteCSV)
+- iCalendar VTODO export and import with lossy-field reporting (WriteICal, LoadFromICal)
+- CalDAV server of a todo.txt file in the "caldav" sub-package
+- Markdown checklist import and export grouped by project or context (LoadFromMarkdown, WriteMarkdown)
+- Taskwarrior JSON import and export with configurable mapping (LoadFromTaskwarrior, WriteTaskwarrior)
+- Org-mode headlines import and export with planning and properties (LoadFromOrg, WriteOrg)
+- text/template rendering of tasks with helpers and built-in layouts (Formatter)
+- ANSI colour and HTML rendering of tasks with todo.sh-compatible themes (Renderer)
+- Self-contained static HTML report of a task list (WriteHTMLReport)
 - Support for all standard todo.txt elements: priority, completion, dates, contexts, projects, tags
 
 Example usage:
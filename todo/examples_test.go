@@ -245,3 +245,47 @@ func ExampleTaskList_Sort() {
 	// After  #2: [Apple]
 	// After  #3: [Apple]
 }
+
+// ----------------------------------------------------------------------------
+//  Diff
+// ----------------------------------------------------------------------------
+
+func ExampleDiff() {
+	yesterday, err := todo.LoadFromString(`
+		(B) Call Mom @Phone
+		Pick up milk @GroceryStore
+		Plan backyard herb garden @Home
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	today, err := todo.LoadFromString(`
+		(A) Call Mom @Phone
+		x 2024-01-02 Pick up milk @GroceryStore
+		Research self-publishing services +Novel
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, change := range todo.Diff(yesterday, today) {
+		task := change.New
+		if task == nil {
+			task = change.Old
+		}
+
+		fmt.Printf("%s: %s\n", change.Kind, task.Todo)
+
+		for _, field := range change.Fields {
+			fmt.Println("  ", field)
+		}
+	}
+	// Output:
+	// modified: Call Mom
+	//    priority: B -> A
+	// completed: Pick up milk
+	//    completed: (none) -> x 2024-01-02
+	// removed: Plan backyard herb garden
+	// added: Research self-publishing services
+}
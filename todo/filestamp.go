@@ -0,0 +1,170 @@
+package todo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: FileStamp
+// ----------------------------------------------------------------------------
+
+// FileStamp identifies the state of a todo.txt file at the time it was read.
+//
+// It is captured by LoadFromPathWithStamp and used by WriteToPathIfUnchanged
+// to detect modifications made by other programs in the meantime. The zero
+// value represents a file that does not exist.
+type FileStamp struct {
+	ModTime time.Time // ModTime is the modification time of the file.
+	Hash    string    // Hash is the hex encoded SHA-256 hash of the file contents.
+	Size    int64     // Size is the size of the file in bytes.
+}
+
+// ----------------------------------------------------------------------------
+//  Constructors
+// ----------------------------------------------------------------------------
+
+// StampPath returns the current FileStamp of the given file. A non-existing
+// file returns the zero FileStamp without an error.
+func StampPath(filename string) (FileStamp, error) {
+	_, stamp, err := readWithStamp(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return FileStamp{}, nil
+	}
+
+	return stamp, err
+}
+
+// LoadFromPathWithStamp loads and returns a TaskList from a file along with
+// the FileStamp of the loaded contents.
+//
+// Pass the stamp to WriteToPathIfUnchanged to save the TaskList only if the
+// file has not been changed by someone else since.
+func LoadFromPathWithStamp(filename string) (TaskList, FileStamp, error) {
+	data, stamp, err := readWithStamp(filename)
+	if err != nil {
+		return nil, FileStamp{}, err
+	}
+
+	tasklist, err := LoadFromFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, FileStamp{}, err
+	}
+
+	return tasklist, stamp, nil
+}
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// Equal returns true if both stamps refer to the same file contents. The
+// modification time is not compared since it may change without the contents
+// being changed (e.g. "touch").
+func (stamp FileStamp) Equal(other FileStamp) bool {
+	return stamp.Size == other.Size && stamp.Hash == other.Hash
+}
+
+// IsZero returns true if the stamp represents a non-existing file.
+func (stamp FileStamp) IsZero() bool {
+	return isEmpty(stamp.Hash)
+}
+
+// ----------------------------------------------------------------------------
+//  Type: ConflictError
+// ----------------------------------------------------------------------------
+
+// ConflictError is returned by WriteToPathIfUnchanged if the file on disk has
+// been modified since it was loaded.
+//
+// Callers can detect it with errors.As, reload the file and retry, for example
+// after merging their changes.
+type ConflictError struct {
+	Path     string    // Path of the conflicting file.
+	Expected FileStamp // Expected is the stamp of the file when it was loaded.
+	Actual   FileStamp // Actual is the stamp of the file on disk.
+}
+
+// Error implements the error interface.
+func (e *ConflictError) Error() string {
+	return "file has been modified since it was loaded: " + e.Path
+}
+
+// ----------------------------------------------------------------------------
+//  TaskList.WriteToPathIfUnchanged()
+// ----------------------------------------------------------------------------
+
+// WriteToPathIfUnchanged writes the TaskList to the specified file only if the
+// file on disk still matches the given stamp. It returns the stamp of the newly
+// written file, which can be used for the next save.
+//
+// If the file has been changed in the meantime, nothing is written and a
+// *ConflictError is returned. The check and the write are done under the same
+// advisory lock as UpdatePath, and the file is replaced atomically.
+func (tasklist *TaskList) WriteToPathIfUnchanged(filename string, stamp FileStamp) (FileStamp, error) {
+	unlock, err := lockPath(filename)
+	if err != nil {
+		return FileStamp{}, err
+	}
+
+	defer func() { _ = unlock() }()
+
+	actual, err := StampPath(filename)
+	if err != nil {
+		return FileStamp{}, err
+	}
+
+	if !actual.Equal(stamp) {
+		return FileStamp{}, &ConflictError{
+			Path:     filename,
+			Expected: stamp,
+			Actual:   actual,
+		}
+	}
+
+	err = writeFileAtomic(filename, []byte(tasklist.String()))
+	if err != nil {
+		return FileStamp{}, err
+	}
+
+	return StampPath(filename)
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// readWithStamp reads the whole file and returns its contents and stamp.
+func readWithStamp(filename string) ([]byte, FileStamp, error) {
+	//nolint:gosec // filename is provided by user, same as LoadFromPath
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, FileStamp{}, errors.Wrap(err, "failed to open file: "+filename)
+	}
+
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, FileStamp{}, errors.Wrap(err, "failed to stat file: "+filename)
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, FileStamp{}, errors.Wrap(err, "failed to read file: "+filename)
+	}
+
+	sum := sha256.Sum256(data)
+
+	return data, FileStamp{
+		ModTime: info.ModTime(),
+		Hash:    hex.EncodeToString(sum[:]),
+		Size:    int64(len(data)),
+	}, nil
+}
@@ -0,0 +1,181 @@
+package todo
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  StampPath()
+// ----------------------------------------------------------------------------
+
+func TestStampPath(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+
+	// Non-existing file
+	stamp, err := StampPath(pathFile)
+	require.NoError(t, err, "non-existing file should not be an error")
+	require.True(t, stamp.IsZero(), "non-existing file should return the zero stamp")
+
+	require.NoError(t, os.WriteFile(pathFile, []byte("(A) Call Mom\n"), PermReadWrite))
+
+	stampFirst, err := StampPath(pathFile)
+	require.NoError(t, err)
+	require.False(t, stampFirst.IsZero())
+	require.Equal(t, int64(13), stampFirst.Size)
+
+	// Same contents
+	stampSecond, err := StampPath(pathFile)
+	require.NoError(t, err)
+	require.True(t, stampFirst.Equal(stampSecond), "same contents should have equal stamps")
+
+	// Same size but different contents
+	require.NoError(t, os.WriteFile(pathFile, []byte("(B) Call Mom\n"), PermReadWrite))
+
+	stampThird, err := StampPath(pathFile)
+	require.NoError(t, err)
+	require.False(t, stampFirst.Equal(stampThird), "different contents should not have equal stamps")
+}
+
+func TestStampPath_error(t *testing.T) {
+	t.Parallel()
+
+	// Directories can be opened but not read
+	_, err := StampPath(t.TempDir())
+
+	require.Error(t, err, "reading a directory should be an error")
+}
+
+// ----------------------------------------------------------------------------
+//  LoadFromPathWithStamp()
+// ----------------------------------------------------------------------------
+
+func TestLoadFromPathWithStamp(t *testing.T) {
+	t.Parallel()
+
+	tasklist, stamp, err := LoadFromPathWithStamp(testInputTasklist)
+	require.NoError(t, err)
+
+	expectTasklist, err := LoadFromPath(testInputTasklist)
+	require.NoError(t, err)
+	require.Equal(t, expectTasklist.String(), tasklist.String(), "loaded tasklist should match LoadFromPath")
+
+	expectStamp, err := StampPath(testInputTasklist)
+	require.NoError(t, err)
+	require.True(t, expectStamp.Equal(stamp), "stamp should match the file on disk")
+}
+
+func TestLoadFromPathWithStamp_errors(t *testing.T) {
+	t.Parallel()
+
+	tasklist, _, err := LoadFromPathWithStamp("some_file_that_does_not_exists.txt")
+	require.Error(t, err, "non-existing file should be an error")
+	require.Nil(t, tasklist)
+
+	tasklist, _, err = LoadFromPathWithStamp(testInputTasklistDueDateError)
+	require.Error(t, err, "invalid task file should be an error")
+	require.Nil(t, tasklist)
+}
+
+// ----------------------------------------------------------------------------
+//  TaskList.WriteToPathIfUnchanged()
+// ----------------------------------------------------------------------------
+
+func TestTaskList_WriteToPathIfUnchanged(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+
+	require.NoError(t, os.WriteFile(pathFile, []byte("(A) Call Mom\n"), PermReadWrite))
+
+	tasklist, stamp, err := LoadFromPathWithStamp(pathFile)
+	require.NoError(t, err)
+
+	tasklist[0].Priority = "B"
+
+	newStamp, err := tasklist.WriteToPathIfUnchanged(pathFile, stamp)
+	require.NoError(t, err, "unchanged file should be written")
+	require.False(t, newStamp.Equal(stamp), "the returned stamp should match the new contents")
+
+	// Save again with the returned stamp
+	tasklist[0].Priority = "C"
+
+	_, err = tasklist.WriteToPathIfUnchanged(pathFile, newStamp)
+	require.NoError(t, err, "the returned stamp should be usable for the next save")
+
+	raw, err := os.ReadFile(pathFile)
+	require.NoError(t, err)
+	require.Equal(t, "(C) Call Mom"+NewLine, string(raw))
+}
+
+func TestTaskList_WriteToPathIfUnchanged_conflict(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+
+	require.NoError(t, os.WriteFile(pathFile, []byte("(A) Call Mom\n"), PermReadWrite))
+
+	tasklist, stamp, err := LoadFromPathWithStamp(pathFile)
+	require.NoError(t, err)
+
+	// Modified by another writer
+	other := "(A) Call Mom\nPick up milk\n"
+	require.NoError(t, os.WriteFile(pathFile, []byte(other), PermReadWrite))
+
+	_, err = tasklist.WriteToPathIfUnchanged(pathFile, stamp)
+	require.Error(t, err, "modified file should not be overwritten")
+
+	var conflict *ConflictError
+
+	require.True(t, errors.As(err, &conflict), "the error should be a *ConflictError")
+	require.Equal(t, pathFile, conflict.Path)
+	require.True(t, conflict.Expected.Equal(stamp))
+	require.Equal(t, int64(len(other)), conflict.Actual.Size)
+	require.Contains(t, err.Error(), "file has been modified since it was loaded")
+
+	raw, err := os.ReadFile(pathFile)
+	require.NoError(t, err)
+	require.Equal(t, other, string(raw), "the changes of the other writer should be kept")
+}
+
+func TestTaskList_WriteToPathIfUnchanged_new_file(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+	tasklist := NewTaskList()
+
+	task, err := ParseTask("New task")
+	require.NoError(t, err)
+
+	tasklist.AddTask(task)
+
+	// The zero stamp expects the file to not exist
+	stamp, err := tasklist.WriteToPathIfUnchanged(pathFile, FileStamp{})
+	require.NoError(t, err)
+	require.False(t, stamp.IsZero())
+
+	// Writing again with the zero stamp is a conflict
+	_, err = tasklist.WriteToPathIfUnchanged(pathFile, FileStamp{})
+	require.Error(t, err, "existing file should conflict with the zero stamp")
+}
+
+func TestTaskList_WriteToPathIfUnchanged_errors(t *testing.T) {
+	t.Parallel()
+
+	tasklist := NewTaskList()
+
+	// Lock error
+	_, err := tasklist.WriteToPathIfUnchanged("/path/to/unknown/dir/todo.txt", FileStamp{})
+	require.Error(t, err, "missing directory should fail to lock")
+
+	// Stat error
+	pathDir := t.TempDir()
+
+	_, err = tasklist.WriteToPathIfUnchanged(pathDir, FileStamp{})
+	require.Error(t, err, "directory should fail to be read")
+}
@@ -113,3 +113,18 @@ func testGetPathFileTemp(t *testing.T, pathSub string) string {
 
 	return pathFileFull
 }
+
+// testCopyToTemp copies the given file to a temporary directory and returns the
+// path of the copy. Use it for functions that lock the file, so that no lock
+// file is left in testdata.
+func testCopyToTemp(t *testing.T, path string) string {
+	t.Helper()
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	pathCopy := filepath.Join(t.TempDir(), filepath.Base(path))
+	require.NoError(t, os.WriteFile(pathCopy, raw, PermReadWrite))
+
+	return pathCopy
+}
@@ -0,0 +1,699 @@
+package todo
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Constants
+// ----------------------------------------------------------------------------
+
+const (
+	// DefaultHistoryLimit is the default number of actions kept by History.
+	DefaultHistoryLimit = 100
+	// historySuffix is appended to the todo.txt path to name its history file.
+	historySuffix = ".history"
+)
+
+// Errors returned by History.
+var (
+	// ErrNothingToUndo is returned by Undo if there is no action to undo.
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrNothingToRedo is returned by Redo if there is no action to redo.
+	ErrNothingToRedo = errors.New("nothing to redo")
+	// ErrHistoryConflict is returned by Undo and Redo if a task of the action
+	// has been changed since, so that the action can not be applied.
+	ErrHistoryConflict = errors.New("task has been changed since")
+)
+
+// ----------------------------------------------------------------------------
+//  Type: OperationKind
+// ----------------------------------------------------------------------------
+
+// OperationKind represents the kind of an Operation.
+type OperationKind string
+
+// Kinds of operations recorded by History.
+const (
+	OperationAdd      OperationKind = "add"      // The task was added.
+	OperationRemove   OperationKind = "remove"   // The task was removed.
+	OperationComplete OperationKind = "complete" // The task was completed, other fields may have changed too.
+	OperationReopen   OperationKind = "reopen"   // The task was reopened, other fields may have changed too.
+	OperationPriority OperationKind = "priority" // Only the priority of the task was changed.
+	OperationEdit     OperationKind = "edit"     // The text of the task was changed.
+)
+
+// ----------------------------------------------------------------------------
+//  Type: Operation
+// ----------------------------------------------------------------------------
+
+// Operation is the change of a single task line, recorded by History.
+type Operation struct {
+	Kind  OperationKind `json:"kind"`
+	Old   string        `json:"old,omitempty"` // Old line of the task, empty if added.
+	New   string        `json:"new,omitempty"` // New line of the task, empty if removed.
+	Index int           `json:"index"`         // Index of the line, from 0.
+}
+
+// String returns the operation in a human-readable format, such as
+// "complete: x 2024-01-02 Call Mom".
+func (op Operation) String() string {
+	line := op.New
+	if op.Kind == OperationRemove {
+		line = op.Old
+	}
+
+	return string(op.Kind) + ": " + line
+}
+
+// ----------------------------------------------------------------------------
+//  Type: Action
+// ----------------------------------------------------------------------------
+
+// Action is a group of operations undone or redone as a single step, such as
+// the changes of a command.
+type Action struct {
+	Time       time.Time   `json:"time"`
+	Operations []Operation `json:"operations"`
+	// Archived are the lines moved to the done file by the action (see
+	// RecordArchive). History does not change the done file: the caller
+	// removes them from it on undo and appends them again on redo.
+	Archived []string `json:"archived,omitempty"`
+}
+
+// String returns the operations of the action, one per line.
+func (action Action) String() string {
+	lines := make([]string, 0, len(action.Operations))
+	for _, op := range action.Operations {
+		lines = append(lines, op.String())
+	}
+
+	return strings.Join(lines, NewLine)
+}
+
+// ----------------------------------------------------------------------------
+//  Type: History
+// ----------------------------------------------------------------------------
+
+// History is the log of the changes of a todo.txt file to undo and redo them.
+//
+// Changes are recorded as actions by comparing the lines before and after
+// them (Record or Track), so that any change of the tasks can be recorded.
+// Undo and Redo then find the changed tasks by their text, using the recorded
+// index as a hint, so that they still work after other tasks were added or
+// removed, or with the blank lines left by todo.sh. The lines are compared as
+// tasks, so that the actions recorded from the lines of the file can be undone
+// on a TaskList, of which the lines are given by Task.String, and vice versa.
+//
+// Save the history beside the todo.txt file (see HistoryPath) to undo the
+// changes across separate runs. Actions are kept up to Limit, the oldest ones
+// are dropped first. Limit is saved with the actions, so that it applies to the
+// later runs too.
+type History struct {
+	// Clock is used for the time of the actions. Defaults to the real time.
+	Clock Clock `json:"-"`
+	// Done are the actions to undo, the last one is the most recent.
+	Done []Action `json:"done"`
+	// Undone are the actions to redo, the last one is the most recently undone.
+	Undone []Action `json:"undone"`
+	// Limit is the maximum number of actions of Done. Zero or less keeps all.
+	Limit int `json:"limit"`
+}
+
+// ----------------------------------------------------------------------------
+//  Constructors
+// ----------------------------------------------------------------------------
+
+// NewHistory returns an empty History with DefaultHistoryLimit.
+func NewHistory() *History {
+	return &History{Clock: nil, Done: []Action{}, Undone: []Action{}, Limit: DefaultHistoryLimit}
+}
+
+// LoadHistory loads the History saved to the file by History.Save, with its
+// Limit, or DefaultHistoryLimit if the file has none. A non-existing file is an
+// empty History.
+func LoadHistory(filename string) (*History, error) {
+	history := NewHistory()
+
+	//nolint:gosec // filename is provided by user, same as LoadFromPath
+	raw, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return history, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read history: "+filename)
+	}
+
+	if err := json.Unmarshal(raw, history); err != nil {
+		return nil, errors.Wrap(err, "failed to parse history: "+filename)
+	}
+
+	return history, nil
+}
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// Record records the changes from the lines before to the lines after as an
+// action, and clears the actions to redo. It returns false if the lines have
+// no changes, in which case nothing is recorded.
+//
+// The lines are todo.txt lines; blank lines are ignored, so that the blank
+// line left by a todo.sh deletion is recorded as the removal of the task. Give
+// the lines of the file as they are (see UpdateLinesPath) if the history is
+// saved beside it, so that all the actions of the file have the same lines.
+func (history *History) Record(before, after []string) bool {
+	return history.RecordArchive(before, after, nil)
+}
+
+// RecordArchive is like Record for a change which also moved the archived lines
+// to the done file, as ArchivePath does. The lines are kept in the Archived of
+// the action, so that the caller can move them back on undo.
+func (history *History) RecordArchive(before, after, archived []string) bool {
+	ops := diffLines(before, after)
+	if len(ops) == 0 && len(archived) == 0 {
+		return false
+	}
+
+	history.Done = append(history.Done, Action{Time: history.now(), Operations: ops, Archived: archived})
+	history.Undone = []Action{}
+
+	if history.Limit > 0 && len(history.Done) > history.Limit {
+		history.Done = slices.Clone(history.Done[len(history.Done)-history.Limit:])
+	}
+
+	return true
+}
+
+// Redo applies again the last undone action to the TaskList and returns it.
+// See Undo.
+func (history *History) Redo(tasklist *TaskList) (Action, error) {
+	return history.applyTasks(tasklist, false)
+}
+
+// RedoLines is like Redo for the lines of a todo.txt file. See UndoLines.
+func (history *History) RedoLines(lines []string) ([]string, Action, error) {
+	return history.applyLines(lines, false)
+}
+
+// Save writes the History to the file atomically, such as the file given by
+// HistoryPath.
+func (history *History) Save(filename string) error {
+	data, err := json.Marshal(history)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode history")
+	}
+
+	return writeFileAtomic(filename, append(data, '\n'))
+}
+
+// Track calls change and records the changes it made to the TaskList as an
+// action. Nothing is recorded if change returns an error.
+func (history *History) Track(tasklist *TaskList, change func() error) error {
+	before := taskLines(*tasklist)
+
+	if err := change(); err != nil {
+		return err
+	}
+
+	history.Record(before, taskLines(*tasklist))
+
+	return nil
+}
+
+// Undo reverts the last action on the TaskList, moves it to the actions to redo
+// and returns it.
+//
+// It returns ErrNothingToUndo if there is no action. If a task of the action
+// has been changed since, it returns an error wrapping ErrHistoryConflict: the
+// TaskList is left untouched and the action is dropped, so that the previous
+// actions can still be undone. The re-added tasks get the next ID like with
+// AddTask; the other tasks keep theirs.
+func (history *History) Undo(tasklist *TaskList) (Action, error) {
+	return history.applyTasks(tasklist, true)
+}
+
+// UndoLines is like Undo for the lines of a todo.txt file, as given by
+// UpdateLinesPath, and returns the new lines. A removed task leaves a blank
+// line unless it is the last line, and a re-added task takes the place of the
+// blank line at its index if any, like todo.sh.
+func (history *History) UndoLines(lines []string) ([]string, Action, error) {
+	return history.applyLines(lines, true)
+}
+
+// applyLines undoes or redoes the next action on a copy of the lines.
+func (history *History) applyLines(lines []string, undo bool) ([]string, Action, error) {
+	target := &lineTarget{lines: slices.Clone(lines)}
+
+	action, err := history.apply(target, undo)
+	if err != nil {
+		return lines, action, err
+	}
+
+	return target.lines, action, nil
+}
+
+// applyTasks undoes or redoes the next action on a copy of the TaskList.
+func (history *History) applyTasks(tasklist *TaskList, undo bool) (Action, error) {
+	target := &taskTarget{tasks: slices.Clone(*tasklist)}
+
+	action, err := history.apply(target, undo)
+	if err != nil {
+		return action, err
+	}
+
+	*tasklist = target.tasks
+
+	return action, nil
+}
+
+// apply undoes or redoes the next action on the target and moves it to the
+// other stack. A conflicting action is dropped.
+func (history *History) apply(target historyTarget, undo bool) (Action, error) {
+	from, to, errEmpty := &history.Undone, &history.Done, ErrNothingToRedo
+	if undo {
+		from, to, errEmpty = &history.Done, &history.Undone, ErrNothingToUndo
+	}
+
+	if len(*from) == 0 {
+		return Action{}, errEmpty
+	}
+
+	action := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+
+	ops := slices.Clone(action.Operations)
+	if undo {
+		slices.Reverse(ops)
+	}
+
+	for _, op := range ops {
+		if err := applyOperation(target, op, undo); err != nil {
+			return action, err
+		}
+	}
+
+	*to = append(*to, action)
+
+	return action, nil
+}
+
+// now returns the current time of the clock.
+func (history *History) now() time.Time {
+	if history.Clock == nil {
+		return realClock{}.Now()
+	}
+
+	return history.Clock.Now()
+}
+
+// ----------------------------------------------------------------------------
+//  Type: historyTarget
+// ----------------------------------------------------------------------------
+
+// historyTarget is a list of todo.txt lines changed by the operations.
+type historyTarget interface {
+	// current returns the current lines.
+	current() []string
+	// insert inserts the line at the index, or at the end if out of range.
+	insert(index int, line string) error
+	// remove removes the line at the index.
+	remove(index int)
+	// replace replaces the line at the index.
+	replace(index int, line string) error
+}
+
+// lineTarget is a historyTarget of the lines of a todo.txt file, which keeps
+// the line numbers like todo.sh.
+type lineTarget struct {
+	lines []string
+}
+
+// current implements the historyTarget interface.
+func (target *lineTarget) current() []string { return target.lines }
+
+// insert implements the historyTarget interface. A blank line at the index is
+// replaced instead.
+func (target *lineTarget) insert(index int, line string) error {
+	switch {
+	case index >= len(target.lines):
+		target.lines = append(target.lines, line)
+	case isBlank(target.lines[index]):
+		target.lines[index] = line
+	default:
+		target.lines = slices.Insert(target.lines, index, line)
+	}
+
+	return nil
+}
+
+// remove implements the historyTarget interface. The line is left blank unless
+// it is the last one.
+func (target *lineTarget) remove(index int) {
+	if index == len(target.lines)-1 {
+		target.lines = target.lines[:index]
+
+		return
+	}
+
+	target.lines[index] = emptyStr
+}
+
+// replace implements the historyTarget interface.
+func (target *lineTarget) replace(index int, line string) error {
+	target.lines[index] = line
+
+	return nil
+}
+
+// patchTarget is a lineTarget of which the index of an insertion counts the
+// tasks only, as in the lines of a TaskList, so that the blank lines and the
+// comments of the file are skipped. See PatchLines.
+type patchTarget struct {
+	lineTarget
+}
+
+// insert implements the historyTarget interface. The line is inserted before
+// the task at the index, or at the end.
+func (target *patchTarget) insert(index int, line string) error {
+	count := 0
+
+	for i, current := range target.lines {
+		text := strings.TrimSpace(current)
+		if isEmpty(text) || isComment(text) {
+			continue
+		}
+
+		if count == index {
+			return target.lineTarget.insert(i, line)
+		}
+
+		count++
+	}
+
+	return target.lineTarget.insert(len(target.lines), line)
+}
+
+// taskTarget is a historyTarget of a TaskList.
+type taskTarget struct {
+	tasks TaskList
+}
+
+// current implements the historyTarget interface.
+func (target *taskTarget) current() []string { return taskLines(target.tasks) }
+
+// insert implements the historyTarget interface. The task gets a new ID.
+func (target *taskTarget) insert(index int, line string) error {
+	task, err := ParseTask(line)
+	if err != nil {
+		return err
+	}
+
+	task.ID = target.tasks.nextID()
+	target.tasks = slices.Insert(target.tasks, min(index, len(target.tasks)), *task)
+
+	return nil
+}
+
+// remove implements the historyTarget interface.
+func (target *taskTarget) remove(index int) {
+	target.tasks = slices.Delete(target.tasks, index, index+1)
+}
+
+// replace implements the historyTarget interface. The task keeps its ID.
+func (target *taskTarget) replace(index int, line string) error {
+	task, err := ParseTask(line)
+	if err != nil {
+		return err
+	}
+
+	task.ID = target.tasks[index].ID
+	target.tasks[index] = *task
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+//  Public functions
+// ----------------------------------------------------------------------------
+
+// HistoryPath returns the path of the history file of the todo.txt file, such
+// as "todo.txt.history" beside "todo.txt".
+func HistoryPath(filename string) string {
+	return filename + historySuffix
+}
+
+// PatchLines applies the changes from the task lines before to the task lines
+// after, such as the lines of a TaskList before and after an edit, onto the
+// lines of a todo.txt file, as given by UpdateLinesPath, and returns the new
+// lines. The changed tasks are found by their text like Undo and Redo do, so
+// that the comments, the blank lines and the tasks changed by others since are
+// kept. The lines of the file are compared as tasks, so that they do not need
+// to be in the order of Task.String.
+//
+// It returns an error wrapping ErrHistoryConflict if a changed task is not in
+// the lines anymore, in which case the lines are returned as they are.
+func PatchLines(lines, before, after []string) ([]string, error) {
+	target := &patchTarget{lineTarget: lineTarget{lines: slices.Clone(lines)}}
+
+	for _, op := range diffLines(before, after) {
+		if err := applyOperation(target, op, false); err != nil {
+			return lines, err
+		}
+	}
+
+	return target.lines, nil
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// applyOperation undoes or redoes the operation on the target.
+func applyOperation(target historyTarget, op Operation, undo bool) error {
+	from, to := op.Old, op.New
+	if undo {
+		from, to = op.New, op.Old
+	}
+
+	if isBlank(from) {
+		return target.insert(max(op.Index, 0), to)
+	}
+
+	index := findLine(target.current(), from, op.Index)
+	if index < 0 {
+		verb := map[bool]string{true: "undo", false: "redo"}[undo]
+
+		return errors.Wrapf(ErrHistoryConflict, "can not %s %q", verb, op.String())
+	}
+
+	if isBlank(to) {
+		target.remove(index)
+
+		return nil
+	}
+
+	return target.replace(index, to)
+}
+
+// diffLines returns the operations changing the lines before into the lines
+// after. The lines are matched by their longest common subsequence, so that the
+// lines shifted by an addition or a removal are not seen as changed. The lines
+// removed and added between two matched lines are paired in order as the
+// changes of the tasks, the rest are removals or additions.
+func diffLines(before, after []string) []Operation {
+	oldLines, newLines := trimLines(before), trimLines(after)
+
+	// Skip the common lines at the start and at the end to keep the table small
+	start := 0
+	for start < len(oldLines) && start < len(newLines) && oldLines[start] == newLines[start] {
+		start++
+	}
+
+	endOld, endNew := len(oldLines), len(newLines)
+	for endOld > start && endNew > start && oldLines[endOld-1] == newLines[endNew-1] {
+		endOld--
+		endNew--
+	}
+
+	oldLines, newLines = oldLines[start:endOld], newLines[start:endNew]
+	lengths := lcsLengths(oldLines, newLines)
+	ops := []Operation{}
+	removed, added := []string{}, []string{}
+
+	for i, j := 0, 0; i < len(oldLines) || j < len(newLines); {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			ops = append(ops, pairLines(removed, added, start+j-len(added))...)
+			removed, added = removed[:0], added[:0]
+			i++
+			j++
+		case j == len(newLines) || (i < len(oldLines) && lengths[i+1][j] >= lengths[i][j+1]):
+			removed = append(removed, oldLines[i])
+			i++
+		default:
+			added = append(added, newLines[j])
+			j++
+		}
+	}
+
+	return append(ops, pairLines(removed, added, start+len(newLines)-len(added))...)
+}
+
+// findLine returns the index of the line, preferring the index hint and then
+// the nearest one, or -1 if not found. The lines are compared as tasks (see
+// taskKey), so that the line of a Task given by String matches the line of the
+// file with the same task in another order, such as "Buy +Shop milk".
+func findLine(lines []string, line string, hint int) int {
+	key := taskKey(line)
+	found := -1
+
+	for i := range lines {
+		text := strings.TrimSpace(lines[i])
+		if text != line && (isEmpty(text) || taskKey(text) != key) {
+			continue
+		}
+
+		if found < 0 || abs(i-hint) < abs(found-hint) {
+			found = i
+		}
+	}
+
+	return found
+}
+
+// abs returns the absolute value of the integer.
+func abs(value int) int {
+	return max(value, -value)
+}
+
+// lcsLengths returns the table of the lengths of the longest common subsequence
+// of the lines from the indexes i and j, at [i][j].
+func lcsLengths(oldLines, newLines []string) [][]int {
+	lengths := make([][]int, len(oldLines)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(newLines)+1)
+	}
+
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	return lengths
+}
+
+// isBlank returns true if the line has only whitespaces.
+func isBlank(line string) bool {
+	return isEmpty(strings.TrimSpace(line))
+}
+
+// operationKind returns the kind of the change from the old line to the new
+// one.
+func operationKind(oldLine, newLine string) OperationKind {
+	switch {
+	case isBlank(oldLine):
+		return OperationAdd
+	case isBlank(newLine):
+		return OperationRemove
+	}
+
+	oldTask, errOld := ParseTask(oldLine)
+	newTask, errNew := ParseTask(newLine)
+
+	if errOld != nil || errNew != nil {
+		return OperationEdit
+	}
+
+	switch {
+	case !oldTask.Completed && newTask.Completed:
+		return OperationComplete
+	case oldTask.Completed && !newTask.Completed:
+		return OperationReopen
+	case oldTask.Priority != newTask.Priority:
+		newTask.Priority = oldTask.Priority
+		if newTask.String() == oldTask.String() {
+			return OperationPriority
+		}
+	}
+
+	return OperationEdit
+}
+
+// pairLines returns the operations of the lines removed and added between two
+// matched lines, where index is the index of the first added line. A removed
+// line is restored after the added ones on undo, since the operations are
+// undone in the reverse order.
+func pairLines(removed, added []string, index int) []Operation {
+	ops := []Operation{}
+
+	for k := range max(len(removed), len(added)) {
+		var oldLine, newLine string
+
+		if k < len(removed) {
+			oldLine = removed[k]
+		}
+
+		if k < len(added) {
+			newLine = added[k]
+		}
+
+		if oldLine == newLine {
+			continue // blank lines
+		}
+
+		ops = append(ops, Operation{
+			Kind: operationKind(oldLine, newLine), Old: oldLine, New: newLine, Index: index + min(k, len(added)),
+		})
+	}
+
+	return ops
+}
+
+// taskKey returns the line as written by Task.String, to compare the lines as
+// tasks, or the line as is if it is a comment or not a task.
+func taskKey(line string) string {
+	if isComment(line) {
+		return line
+	}
+
+	task, err := ParseTask(line)
+	if err != nil {
+		return line
+	}
+
+	return task.String()
+}
+
+// taskLines returns the tasks as todo.txt lines.
+func taskLines(tasklist TaskList) []string {
+	lines := make([]string, 0, len(tasklist))
+	for i := range tasklist {
+		lines = append(lines, tasklist[i].String())
+	}
+
+	return lines
+}
+
+// trimLines returns the lines without the leading and trailing whitespaces.
+func trimLines(lines []string) []string {
+	trimmed := make([]string, 0, len(lines))
+	for _, line := range lines {
+		trimmed = append(trimmed, strings.TrimSpace(line))
+	}
+
+	return trimmed
+}
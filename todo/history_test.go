@@ -0,0 +1,370 @@
+package todo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testHistory returns an empty History with a fake clock.
+func testHistory() *History {
+	history := NewHistory()
+	history.Clock = &fakeClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	return history
+}
+
+// testTaskList loads the task list from the text.
+func testTaskList(t *testing.T, text string) TaskList {
+	t.Helper()
+
+	tasklist, err := LoadFromString(text)
+	require.NoError(t, err)
+
+	return tasklist
+}
+
+func Test_operationKind(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		oldLine, newLine string
+		expect           OperationKind
+	}{
+		{"", "Call Mom", OperationAdd},
+		{"Call Mom", " ", OperationRemove},
+		{"(A) Call Mom", "x 2024-01-02 Call Mom", OperationComplete},
+		{"x 2024-01-02 Call Mom", "Call Mom @Phone", OperationReopen},
+		{"(A) Call Mom", "(B) Call Mom", OperationPriority},
+		{"Call Mom", "(B) Call Mom", OperationPriority},
+		{"(A) Call Mom", "(B) Call Dad", OperationEdit},
+		{"Call Mom", "Call Mom due:2024-02-31", OperationEdit},
+	} {
+		require.Equal(t, test.expect, operationKind(test.oldLine, test.newLine), "%q -> %q", test.oldLine, test.newLine)
+	}
+}
+
+func TestHistory_Track_Undo_Redo(t *testing.T) {
+	t.Parallel()
+
+	history := testHistory()
+	tasklist := testTaskList(t, "Call Mom\nBuy milk\nWalk dog\n")
+
+	require.NoError(t, history.Track(&tasklist, func() error {
+		tasklist[1].Complete()
+
+		return nil
+	}))
+	require.NoError(t, history.Track(&tasklist, func() error {
+		tasklist[0].Priority = "A"
+
+		return tasklist.RemoveTaskByID(3)
+	}))
+
+	task, err := ParseTask("Pay bills")
+	require.NoError(t, err)
+	require.NoError(t, history.Track(&tasklist, func() error {
+		tasklist.AddTask(task)
+
+		return nil
+	}))
+	require.NoError(t, history.Track(&tasklist, func() error { return nil }))
+	require.Len(t, history.Done, 3, "no change should not be recorded")
+
+	done := time.Now().Format(DateLayout)
+	require.Equal(t, "(A) Call Mom\nx "+done+" Buy milk\nPay bills\n", tasklist.String())
+
+	require.Equal(t, []Operation{
+		{Kind: OperationPriority, Old: "Call Mom", New: "(A) Call Mom", Index: 0},
+		{Kind: OperationRemove, Old: "Walk dog", New: "", Index: 2},
+	}, history.Done[1].Operations)
+	require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), history.Done[1].Time)
+	require.Equal(t, "priority: (A) Call Mom"+NewLine+"remove: Walk dog", history.Done[1].String())
+
+	// Undo all
+	action, err := history.Undo(&tasklist)
+	require.NoError(t, err)
+	require.Equal(t, "add: Pay bills", action.String())
+
+	_, err = history.Undo(&tasklist)
+	require.NoError(t, err)
+	require.Equal(t, "Call Mom\nx "+done+" Buy milk\nWalk dog\n", tasklist.String())
+	require.Equal(t, []int{1, 2, 3}, []int{tasklist[0].ID, tasklist[1].ID, tasklist[2].ID},
+		"re-added task should get the next ID")
+
+	_, err = history.Undo(&tasklist)
+	require.NoError(t, err)
+	require.Equal(t, "Call Mom\nBuy milk\nWalk dog\n", tasklist.String())
+
+	_, err = history.Undo(&tasklist)
+	require.ErrorIs(t, err, ErrNothingToUndo)
+
+	// Redo two, then a new change clears the rest
+	_, err = history.Redo(&tasklist)
+	require.NoError(t, err)
+	_, err = history.Redo(&tasklist)
+	require.NoError(t, err)
+	require.Equal(t, "(A) Call Mom\nx "+done+" Buy milk\n", tasklist.String())
+	require.Len(t, history.Undone, 1)
+
+	require.NoError(t, history.Track(&tasklist, func() error {
+		tasklist[1].Reopen()
+
+		return nil
+	}))
+	require.Empty(t, history.Undone)
+
+	_, err = history.Redo(&tasklist)
+	require.ErrorIs(t, err, ErrNothingToRedo)
+}
+
+func TestHistory_Track_error(t *testing.T) {
+	t.Parallel()
+
+	history := testHistory()
+	tasklist := testTaskList(t, "Call Mom\n")
+
+	require.Error(t, history.Track(&tasklist, func() error { return tasklist.RemoveTaskByID(42) }))
+	require.Empty(t, history.Done)
+}
+
+func TestHistory_Undo_conflict(t *testing.T) {
+	t.Parallel()
+
+	history := testHistory()
+	tasklist := testTaskList(t, "Call Mom\nBuy milk\n")
+
+	require.NoError(t, history.Track(&tasklist, func() error {
+		tasklist[0].Complete()
+
+		return nil
+	}))
+	require.NoError(t, history.Track(&tasklist, func() error {
+		tasklist[1].Todo = "Buy soy milk"
+
+		return nil
+	}))
+
+	// Changed by someone else
+	tasklist[1].Todo = "Buy oat milk"
+
+	_, err := history.Undo(&tasklist)
+	require.ErrorIs(t, err, ErrHistoryConflict)
+	require.Contains(t, err.Error(), `can not undo "edit: Buy soy milk"`)
+	require.Equal(t, "Buy oat milk", tasklist[1].Todo, "task list should be untouched")
+
+	// The conflicting action is dropped
+	_, err = history.Undo(&tasklist)
+	require.NoError(t, err)
+	require.False(t, tasklist[0].Completed)
+	require.Empty(t, history.Done)
+	require.Len(t, history.Undone, 1)
+}
+
+func TestHistory_UndoLines(t *testing.T) {
+	t.Parallel()
+
+	history := testHistory()
+
+	// todo.sh deletion leaving a blank line
+	require.True(t, history.Record([]string{"Call Mom", "Buy milk", "Walk dog"}, []string{"Call Mom", "", "Walk dog"}))
+	require.Equal(t, OperationRemove, history.Done[0].Operations[0].Kind)
+
+	// Addition at the end
+	require.True(t, history.Record([]string{"Call Mom", "", "Walk dog"}, []string{"Call Mom", "", "Walk dog", "Pay bills"}))
+	require.False(t, history.Record([]string{"Call Mom", ""}, []string{"Call Mom", "  "}), "blank lines should be ignored")
+
+	lines, action, err := history.UndoLines([]string{"Call Mom", "", "Walk dog", "Pay bills"})
+	require.NoError(t, err)
+	require.Equal(t, "add: Pay bills", action.String())
+	require.Equal(t, []string{"Call Mom", "", "Walk dog"}, lines, "last line should be removed")
+
+	lines, _, err = history.UndoLines(lines)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Call Mom", "Buy milk", "Walk dog"}, lines, "blank line should be reused")
+
+	lines, _, err = history.RedoLines(lines)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Call Mom", "", "Walk dog"}, lines, "removed line should be left blank")
+
+	// Moved by an archive, found by text
+	lines, _, err = history.RedoLines([]string{"Walk dog"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Walk dog", "Pay bills"}, lines)
+
+	lines, _, err = history.UndoLines([]string{"Pay bills", "Walk dog", "Pay bills"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Pay bills", "Walk dog"}, lines, "nearest line to the index should be removed")
+}
+
+func TestHistory_lines_and_tasks(t *testing.T) {
+	t.Parallel()
+
+	history := testHistory()
+
+	// Recorded from the lines of the file, undone on the TaskList
+	require.True(t, history.Record([]string{"Buy +Shop milk", "Call @Phone Mom"}, []string{"x Buy +Shop milk", "Call @Phone Mom"}))
+
+	tasklist := testTaskList(t, "x Buy +Shop milk\nCall @Phone Mom\n")
+	_, err := history.Undo(&tasklist)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Buy milk +Shop", "Call Mom @Phone"}, taskLines(tasklist))
+
+	// Recorded from the TaskList, undone on the lines of the file
+	require.NoError(t, history.Track(&tasklist, func() error {
+		tasklist[1].Priority = "A"
+
+		return nil
+	}))
+
+	lines, _, err := history.UndoLines([]string{"Buy +Shop milk", "(A) Call @Phone Mom"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Buy +Shop milk", "Call Mom @Phone"}, lines)
+}
+
+func TestHistory_Record_shifted_lines(t *testing.T) {
+	t.Parallel()
+
+	history := testHistory()
+
+	// Addition shifting the following lines, and an edit
+	before := []string{"Call Mom", "Buy milk", "Walk dog", "Pay bills"}
+	after := []string{"Plan trip", "Call Mom", "Buy milk", "Walk the dog", "Pay bills"}
+
+	require.True(t, history.Record(before, after))
+	require.Equal(t, []Operation{
+		{Kind: OperationAdd, Old: "", New: "Plan trip", Index: 0},
+		{Kind: OperationEdit, Old: "Walk dog", New: "Walk the dog", Index: 3},
+	}, history.Done[0].Operations, "shifted lines should not be changes")
+
+	// Removals of several lines
+	require.True(t, history.Record(after, []string{"Walk the dog"}))
+	require.Len(t, history.Done[1].Operations, 4)
+
+	for _, op := range history.Done[1].Operations {
+		require.Equal(t, OperationRemove, op.Kind)
+	}
+
+	tasklist := testMustLoad(t, "Walk the dog")
+
+	_, err := history.Undo(&tasklist)
+	require.NoError(t, err)
+	require.Equal(t, after, taskLines(tasklist), "removed tasks should be restored in order")
+
+	_, err = history.Undo(&tasklist)
+	require.NoError(t, err)
+	require.Equal(t, before, taskLines(tasklist))
+
+	_, err = history.Redo(&tasklist)
+	require.NoError(t, err)
+	require.Equal(t, after, taskLines(tasklist))
+}
+
+func TestHistory_RecordArchive(t *testing.T) {
+	t.Parallel()
+
+	history := testHistory()
+
+	require.False(t, history.RecordArchive([]string{"Call Mom"}, []string{"Call Mom"}, nil))
+	require.True(t, history.RecordArchive([]string{"(A) Call Mom", "Buy milk"}, []string{"Buy milk"},
+		[]string{"x 2024-01-02 Call Mom"}))
+	require.Equal(t, []string{"x 2024-01-02 Call Mom"}, history.Done[0].Archived)
+
+	lines, action, err := history.UndoLines([]string{"Buy milk"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"(A) Call Mom", "Buy milk"}, lines)
+	require.Equal(t, []string{"x 2024-01-02 Call Mom"}, action.Archived, "archived lines should be returned to move them back")
+}
+
+func TestPatchLines(t *testing.T) {
+	t.Parallel()
+
+	lines := []string{"# Home", "Call Mom", "", "Buy milk", "Walk dog", "# End"}
+	before := []string{"Call Mom", "Buy milk", "Walk dog"}
+
+	patched, err := PatchLines(lines, before, []string{"x Call Mom", "Plan trip", "Buy milk", "Walk dog", "Pay bills"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"# Home", "x Call Mom", "", "Plan trip", "Buy milk", "Walk dog", "# End", "Pay bills"}, patched,
+		"comments and blank lines should be kept")
+
+	patched, err = PatchLines(lines, before, []string{"Call Mom", "Walk dog"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"# Home", "Call Mom", "", "", "Walk dog", "# End"}, patched,
+		"removed task should leave a blank line")
+
+	// Tokens of the file line in another order than Task.String
+	patched, err = PatchLines([]string{"Buy +Shop milk @Store key:val"}, []string{"Buy milk @Store +Shop key:val"},
+		[]string{"(A) Buy milk @Store +Shop key:val"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"(A) Buy milk @Store +Shop key:val"}, patched)
+
+	// Changed by others since
+	patched, err = PatchLines(lines, []string{"Pay bills"}, []string{"x Pay bills"})
+	require.ErrorIs(t, err, ErrHistoryConflict)
+	require.Equal(t, lines, patched)
+}
+
+func TestHistory_Limit(t *testing.T) {
+	t.Parallel()
+
+	history := testHistory()
+	history.Limit = 2
+
+	for _, line := range []string{"a", "b", "c"} {
+		require.True(t, history.Record(nil, []string{line}))
+	}
+
+	require.Len(t, history.Done, 2)
+	require.Equal(t, "b", history.Done[0].Operations[0].New)
+}
+
+func TestHistory_Save_LoadHistory(t *testing.T) {
+	t.Parallel()
+
+	pathFile := HistoryPath(filepath.Join(t.TempDir(), "todo.txt"))
+	require.Equal(t, "todo.txt.history", filepath.Base(pathFile))
+
+	history, err := LoadHistory(pathFile)
+	require.NoError(t, err, "missing file should be an empty history")
+	require.Empty(t, history.Done)
+	require.Equal(t, DefaultHistoryLimit, history.Limit)
+
+	history.Clock = &fakeClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	history.Record([]string{"Call Mom"}, []string{"x Call Mom"})
+	require.NoError(t, history.Save(pathFile))
+
+	raw, err := os.ReadFile(pathFile)
+	require.NoError(t, err)
+	require.Equal(t, `{"done":[{"time":"2024-01-02T03:04:05Z","operations":[`+
+		`{"kind":"complete","old":"Call Mom","new":"x Call Mom","index":0}]}],"undone":[],"limit":100}`+"\n", string(raw))
+
+	loaded, err := LoadHistory(pathFile)
+	require.NoError(t, err)
+	require.Equal(t, history.Done, loaded.Done)
+	require.Equal(t, DefaultHistoryLimit, loaded.Limit)
+
+	// Limit is kept across runs
+	loaded.Limit = 2
+	require.NoError(t, loaded.Save(pathFile))
+
+	loaded, err = LoadHistory(pathFile)
+	require.NoError(t, err)
+	require.Equal(t, 2, loaded.Limit, "saved limit should be loaded")
+
+	require.NoError(t, os.WriteFile(pathFile, []byte(`{"done":[],"undone":[]}`), 0o600))
+
+	loaded, err = LoadHistory(pathFile)
+	require.NoError(t, err)
+	require.Equal(t, DefaultHistoryLimit, loaded.Limit, "missing limit should be the default")
+
+	require.NoError(t, os.WriteFile(pathFile, []byte("{"), 0o600))
+
+	_, err = LoadHistory(pathFile)
+	require.ErrorContains(t, err, "failed to parse history")
+
+	_, err = LoadHistory(t.TempDir())
+	require.ErrorContains(t, err, "failed to read history")
+}
@@ -0,0 +1,218 @@
+package todo
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: Mutation
+// ----------------------------------------------------------------------------
+
+// Mutation describes a change of a task passed to the hooks.
+type Mutation struct {
+	// List is the changed list. Hooks may change it further via its methods,
+	// such as to add the next occurrence of a completed recurring task.
+	List *HookedTaskList
+	// Task is the added, removed, completed or reopened task. It is only valid
+	// during the hook: keep a copy to use it afterwards.
+	Task *Task
+	// Kind is ChangeAdded, ChangeRemoved, ChangeCompleted or ChangeReopened.
+	Kind ChangeKind
+}
+
+// ----------------------------------------------------------------------------
+//  Type: Hook
+// ----------------------------------------------------------------------------
+
+// Hook observes the changes of a task list, such as to write an audit log, to
+// create the next occurrence of a recurring task, to send notifications or to
+// enforce validation rules. Hooks are added to a HookedTaskList.
+type Hook struct {
+	// Before is called before the mutation, if not nil. Returning an error
+	// vetoes the mutation: nothing is changed and the error is returned by the
+	// mutating method wrapped in a *VetoError.
+	Before func(mutation Mutation) error
+	// After is called after the mutation, if not nil.
+	After func(mutation Mutation)
+}
+
+// ----------------------------------------------------------------------------
+//  Type: VetoError
+// ----------------------------------------------------------------------------
+
+// VetoError is returned by the methods of HookedTaskList if a Hook vetoed the
+// change. Callers can detect it with errors.As.
+type VetoError struct {
+	Err  error      // Err is the error returned by Hook.Before.
+	Kind ChangeKind // Kind of the vetoed change.
+}
+
+// Error implements the error interface.
+func (e *VetoError) Error() string {
+	return "change of task vetoed (" + string(e.Kind) + "): " + e.Err.Error()
+}
+
+// Unwrap returns the error of the hook.
+func (e *VetoError) Unwrap() error {
+	return e.Err
+}
+
+// ----------------------------------------------------------------------------
+//  Type: HookedTaskList
+// ----------------------------------------------------------------------------
+
+// HookedTaskList attaches hooks to a TaskList. Its methods change the TaskList
+// like the methods of the same name of TaskList, running the hooks before and
+// after each change.
+//
+// The hooks only run for the changes made via the HookedTaskList. Calls which
+// do not change anything, such as completing a completed task, do not run them.
+// Neither do the changes made to the TaskList or its tasks directly.
+type HookedTaskList struct {
+	// TaskList is the list changed by the methods.
+	TaskList *TaskList
+
+	mutex sync.RWMutex
+	hooks []*Hook
+}
+
+// NewHookedTaskList returns a HookedTaskList of the given TaskList, without
+// hooks.
+func NewHookedTaskList(tasklist *TaskList) *HookedTaskList {
+	return &HookedTaskList{TaskList: tasklist, mutex: sync.RWMutex{}, hooks: nil}
+}
+
+// AddHook adds the hook to the list and returns the function to remove it. The
+// hooks run in the order they were added.
+//
+// Hooks run synchronously in the goroutine of the mutation. They may change the
+// list from After, which runs the hooks again, but not from Before.
+func (list *HookedTaskList) AddHook(hook Hook) func() {
+	entry := &hook
+
+	list.mutex.Lock()
+	list.hooks = append(list.hooks, entry)
+	list.mutex.Unlock()
+
+	return func() {
+		list.mutex.Lock()
+		defer list.mutex.Unlock()
+
+		for i, added := range list.hooks {
+			if added == entry {
+				list.hooks = append(list.hooks[:i:i], list.hooks[i+1:]...)
+
+				return
+			}
+		}
+	}
+}
+
+// AddTask appends the Task like TaskList.AddTask. The Task, including its ID,
+// is left unchanged if a Hook vetoed the change.
+func (list *HookedTaskList) AddTask(task *Task) error {
+	added := *task
+	added.ID = list.TaskList.nextID()
+
+	return list.mutate(func() {
+		task.ID = added.ID
+		*list.TaskList = append(*list.TaskList, *task)
+	}, Mutation{List: list, Task: &added, Kind: ChangeAdded})
+}
+
+// CompleteTask completes the Task with the given 'id' like Task.Complete.
+// Returns an error if the Task could not be found or if a Hook vetoed the change.
+func (list *HookedTaskList) CompleteTask(id int) error {
+	task, err := list.TaskList.GetTask(id)
+	if err != nil || task.Completed {
+		return err
+	}
+
+	return list.mutate(task.Complete, Mutation{List: list, Task: task, Kind: ChangeCompleted})
+}
+
+// RemoveTask removes any Task with the same String representation as the given
+// Task like TaskList.RemoveTask, running the hooks once per removed task.
+// Returns an error if no Task was removed or if a Hook vetoed the removal of any
+// of them, in which case nothing is removed.
+func (list *HookedTaskList) RemoveTask(task Task) error {
+	return list.removeTasks(func(t *Task) bool { return t.String() == task.String() })
+}
+
+// RemoveTaskByID removes any Task with given Task 'id' like
+// TaskList.RemoveTaskByID, running the hooks once per removed task.
+// Returns an error if no Task was removed or if a Hook vetoed the removal of any
+// of them, in which case nothing is removed.
+func (list *HookedTaskList) RemoveTaskByID(taskID int) error {
+	return list.removeTasks(func(t *Task) bool { return t.ID == taskID })
+}
+
+// ReopenTask reopens the Task with the given 'id' like Task.Reopen.
+// Returns an error if the Task could not be found or if a Hook vetoed the change.
+func (list *HookedTaskList) ReopenTask(id int) error {
+	task, err := list.TaskList.GetTask(id)
+	if err != nil || !task.Completed {
+		return err
+	}
+
+	return list.mutate(task.Reopen, Mutation{List: list, Task: task, Kind: ChangeReopened})
+}
+
+// mutate runs the Before hooks of the mutations, applies the change if none
+// vetoed it and then runs the After hooks. The hooks are a snapshot, so that
+// they run without holding the lock.
+func (list *HookedTaskList) mutate(apply func(), mutations ...Mutation) error {
+	list.mutex.RLock()
+	hooks := list.hooks
+	list.mutex.RUnlock()
+
+	for _, mutation := range mutations {
+		for _, hook := range hooks {
+			if hook.Before == nil {
+				continue
+			}
+
+			if err := hook.Before(mutation); err != nil {
+				return &VetoError{Err: err, Kind: mutation.Kind}
+			}
+		}
+	}
+
+	apply()
+
+	for _, mutation := range mutations {
+		for _, hook := range hooks {
+			if hook.After != nil {
+				hook.After(mutation)
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeTasks removes the tasks matching the predicate.
+func (list *HookedTaskList) removeTasks(match func(t *Task) bool) error {
+	var kept, removed TaskList
+
+	for _, t := range *list.TaskList {
+		if match(&t) {
+			removed = append(removed, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+
+	if len(removed) == 0 {
+		return errors.New("task not found")
+	}
+
+	mutations := make([]Mutation, 0, len(removed))
+	for i := range removed {
+		mutations = append(mutations, Mutation{List: list, Task: &removed[i], Kind: ChangeRemoved})
+	}
+
+	return list.mutate(func() { *list.TaskList = kept }, mutations...)
+}
@@ -0,0 +1,173 @@
+package todo
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// testHookedList returns the HookedTaskList of the task list loaded from the
+// text.
+func testHookedList(t *testing.T, text string) *HookedTaskList {
+	t.Helper()
+
+	tasklist, err := LoadFromString(text)
+	require.NoError(t, err)
+
+	return NewHookedTaskList(&tasklist)
+}
+
+// testRecordHook adds a hook to the list recording the mutations as
+// "before/after kind todo" and returns the records.
+func testRecordHook(list *HookedTaskList) *[]string {
+	records := []string{}
+
+	list.AddHook(Hook{
+		Before: func(mutation Mutation) error {
+			records = append(records, "before "+string(mutation.Kind)+" "+mutation.Task.Todo)
+
+			return nil
+		},
+		After: func(mutation Mutation) {
+			records = append(records, "after "+string(mutation.Kind)+" "+mutation.Task.Todo)
+		},
+	})
+
+	return &records
+}
+
+func TestHookedTaskList(t *testing.T) {
+	t.Parallel()
+
+	list := testHookedList(t, "Call Mom\nBuy milk\n")
+	records := testRecordHook(list)
+
+	var lists []*HookedTaskList
+
+	list.AddHook(Hook{Before: nil, After: func(mutation Mutation) { lists = append(lists, mutation.List) }})
+
+	require.NoError(t, list.CompleteTask(1))
+	require.NoError(t, list.CompleteTask(1), "completing again should be a no-op")
+	require.NoError(t, list.ReopenTask(1))
+	require.NoError(t, list.ReopenTask(1), "reopening again should be a no-op")
+
+	task, err := ParseTask("Walk dog")
+	require.NoError(t, err)
+	require.NoError(t, list.AddTask(task))
+	require.Equal(t, 3, task.ID)
+	require.NoError(t, list.RemoveTaskByID(2))
+
+	require.Equal(t, []string{
+		"before completed Call Mom", "after completed Call Mom",
+		"before reopened Call Mom", "after reopened Call Mom",
+		"before added Walk dog", "after added Walk dog",
+		"before removed Buy milk", "after removed Buy milk",
+	}, *records)
+	require.Equal(t, []*HookedTaskList{list, list, list, list}, lists)
+	require.Equal(t, "Call Mom\nWalk dog\n", list.TaskList.String())
+
+	// Not found
+	require.Error(t, list.CompleteTask(42))
+	require.Error(t, list.ReopenTask(42))
+	require.Error(t, list.RemoveTaskByID(42))
+	require.Len(t, *records, 8, "hooks should not run if the task is not found")
+
+	// Changes outside the HookedTaskList or of other lists
+	(*list.TaskList)[0].Complete()
+	list.TaskList.AddTask(task)
+
+	other := NewHookedTaskList(list.TaskList)
+	require.NoError(t, other.RemoveTask(*task))
+
+	require.Len(t, *records, 8, "hooks should only run for the changes via their list")
+}
+
+func TestHookedTaskList_veto(t *testing.T) {
+	t.Parallel()
+
+	errReadOnly := errors.New("read-only task")
+
+	list := testHookedList(t, "Call Mom +ReadOnly\nBuy milk\nCall Mom +ReadOnly\n")
+	list.AddHook(Hook{
+		Before: func(mutation Mutation) error {
+			if slices.Contains(mutation.Task.Projects, "ReadOnly") {
+				return errReadOnly
+			}
+
+			return nil
+		},
+		After: nil,
+	})
+
+	records := testRecordHook(list)
+
+	err := list.CompleteTask(1)
+	require.ErrorIs(t, err, errReadOnly)
+
+	var veto *VetoError
+
+	require.ErrorAs(t, err, &veto)
+	require.Equal(t, ChangeCompleted, veto.Kind)
+	require.Equal(t, "change of task vetoed (completed): read-only task", err.Error())
+	require.False(t, (*list.TaskList)[0].Completed)
+
+	// Vetoed task is left unchanged
+	task, err := ParseTask("Walk dog +ReadOnly")
+	require.NoError(t, err)
+
+	task.ID = 42
+
+	require.ErrorIs(t, list.AddTask(task), errReadOnly)
+	require.Len(t, *list.TaskList, 3)
+	require.Equal(t, 42, task.ID)
+
+	// All the removed tasks are checked first
+	require.ErrorIs(t, list.RemoveTask((*list.TaskList)[0]), errReadOnly)
+	require.Len(t, *list.TaskList, 3)
+
+	require.Empty(t, *records, "vetoed changes should not run the next hooks")
+
+	require.NoError(t, list.CompleteTask(2))
+	require.Equal(t, []string{"before completed Buy milk", "after completed Buy milk"}, *records)
+}
+
+func TestHookedTaskList_recurrence(t *testing.T) {
+	t.Parallel()
+
+	list := testHookedList(t, "Water plants rec:1w\n")
+	remove := list.AddHook(Hook{
+		Before: nil,
+		After: func(mutation Mutation) {
+			if mutation.Kind != ChangeCompleted || mutation.Task.AdditionalTags["rec"] == "" {
+				return
+			}
+
+			next, err := ParseTask(mutation.Task.Todo + " rec:" + mutation.Task.AdditionalTags["rec"])
+			require.NoError(t, err)
+			require.NoError(t, mutation.List.AddTask(next))
+		},
+	})
+
+	records := testRecordHook(list)
+
+	require.NoError(t, list.CompleteTask(1))
+	require.Len(t, *list.TaskList, 2)
+	require.True(t, (*list.TaskList)[0].Completed)
+	require.Equal(t, "Water plants rec:1w", (*list.TaskList)[1].String())
+	require.Equal(t, []string{
+		"before completed Water plants", "before added Water plants",
+		"after added Water plants", "after completed Water plants",
+	}, *records)
+
+	// Removed
+	remove()
+	remove()
+
+	*records = []string{}
+
+	require.NoError(t, list.CompleteTask(2))
+	require.Len(t, *list.TaskList, 2)
+	require.Len(t, *records, 2)
+}
@@ -0,0 +1,198 @@
+package todo
+
+import (
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// htmlReportTemplate is the template of WriteHTMLReport. The styles are inline,
+// so the page needs no network access.
+const htmlReportTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem auto; max-width: 60rem; padding: 0 1rem; color: #222; }
+h1 { margin-bottom: 0.25rem; }
+.generated { color: #777; margin-top: 0; }
+.summary { display: flex; flex-wrap: wrap; gap: 1rem; padding: 0; list-style: none; }
+.summary li { border: 1px solid #ddd; border-radius: 0.5rem; padding: 0.5rem 1rem; text-align: center; }
+.summary .count { display: block; font-size: 1.5rem; font-weight: bold; }
+.summary .overdue .count { color: #c62828; }
+h2 small { color: #777; font-weight: normal; font-size: 0.8em; }
+ul.tasks { list-style: none; padding-left: 0; }
+ul.tasks li { padding: 0.25rem 0; border-bottom: 1px solid #eee; }
+.todo-priority-a .todo-priority { color: #c62828; font-weight: bold; }
+.todo-priority-b .todo-priority { color: #ef6c00; font-weight: bold; }
+.todo-priority-c .todo-priority { color: #1565c0; font-weight: bold; }
+.todo-priority { color: #555; }
+.todo-context { color: #00838f; }
+.todo-project { color: #6a1b9a; }
+.todo-tag, .todo-created-date, .todo-completed-date { color: #777; }
+.todo-done { color: #999; text-decoration: line-through; }
+.todo-overdue .todo-due-date { color: #fff; background: #c62828; border-radius: 0.25rem; padding: 0 0.25rem; }
+.todo-due-today .todo-due-date { color: #fff; background: #ef6c00; border-radius: 0.25rem; padding: 0 0.25rem; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="generated">Generated on {{.Generated}}</p>
+<ul class="summary">
+<li><span class="count">{{.Summary.Total}}</span> total</li>
+<li><span class="count">{{.Summary.Open}}</span> open</li>
+<li><span class="count">{{.Summary.Done}}</span> done</li>
+<li class="overdue"><span class="count">{{.Summary.Overdue}}</span> overdue</li>
+<li><span class="count">{{.Summary.DueToday}}</span> due today</li>
+</ul>
+{{range .Groups}}<section>
+{{if $.Grouped}}<h2>{{.Heading}} <small>{{.Summary.Open}} open, {{.Summary.Done}} done</small></h2>
+{{end}}<ul class="tasks">
+{{range .Tasks}}<li>{{.}}</li>
+{{end}}</ul>
+</section>
+{{end}}</body>
+</html>
+`
+
+// ----------------------------------------------------------------------------
+//  Type: HTMLReportOptions
+// ----------------------------------------------------------------------------
+
+// HTMLReportOptions are the options of WriteHTMLReport.
+type HTMLReportOptions struct {
+	// Clock is used for the generated date and the overdue tasks. Defaults to
+	// the real time.
+	Clock Clock
+	// Title is the title of the page. Defaults to "Tasks".
+	Title string
+	// GroupBy groups the tasks by "project", "context" or "due", as the
+	// "groupBy" function of Formatter does. A task with several projects or
+	// contexts is listed in each of the groups. Empty lists the tasks in a
+	// single group.
+	GroupBy string
+}
+
+// HTMLReportSummary holds the counts of the tasks of an HTML report.
+type HTMLReportSummary struct {
+	Total    int
+	Open     int
+	Done     int
+	Overdue  int
+	DueToday int
+}
+
+// htmlReportGroup is a group of rendered tasks of the HTML report.
+type htmlReportGroup struct {
+	Heading string
+	Tasks   []template.HTML
+	Summary HTMLReportSummary
+}
+
+// ----------------------------------------------------------------------------
+//  TaskList.WriteHTMLReport()
+// ----------------------------------------------------------------------------
+
+// WriteHTMLReport writes the TaskList as a self-contained HTML page, with the
+// summary counts of the tasks and the tasks grouped as HTMLReportOptions sets.
+// The tasks are rendered by Renderer.RenderHTML, so the priorities are
+// coloured and the overdue tasks are marked. The page has no external assets,
+// so it can be published as is, such as a CI artifact.
+func (tasklist *TaskList) WriteHTMLReport(writer io.Writer, opts HTMLReportOptions) error {
+	clock := opts.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	renderer := &Renderer{Clock: clock, Theme: Theme{}, NoColor: true}
+	tasks := NewTemplateListData(*tasklist, len(*tasklist)).Tasks
+	groups := []TemplateGroup{{Date: time.Time{}, Name: emptyStr, Tasks: tasks}}
+
+	if isNotEmpty(opts.GroupBy) {
+		var err error
+
+		groups, err = groupTasks(opts.GroupBy, tasks)
+		if err != nil {
+			return err
+		}
+	}
+
+	data := struct {
+		Title     string
+		Generated string
+		Groups    []htmlReportGroup
+		Summary   HTMLReportSummary
+		Grouped   bool
+	}{
+		Title:     opts.Title,
+		Generated: clock.Now().Format(DateLayout),
+		Groups:    make([]htmlReportGroup, len(groups)),
+		Summary:   renderer.summarize(tasks),
+		Grouped:   isNotEmpty(opts.GroupBy),
+	}
+
+	if isEmpty(data.Title) {
+		data.Title = "Tasks"
+	}
+
+	for i, group := range groups {
+		data.Groups[i] = htmlReportGroup{
+			Heading: htmlReportHeading(opts.GroupBy, group.Name),
+			Tasks:   make([]template.HTML, len(group.Tasks)),
+			Summary: renderer.summarize(group.Tasks),
+		}
+
+		for j, task := range group.Tasks {
+			//nolint:gosec // the segments are escaped by RenderHTML
+			data.Groups[i].Tasks[j] = template.HTML(renderer.RenderHTML(task))
+		}
+	}
+
+	tmpl := template.Must(template.New("report").Parse(htmlReportTemplate))
+
+	return errors.Wrap(tmpl.Execute(writer, data), "failed to write HTML report")
+}
+
+// htmlReportHeading returns the heading of the group, such as "+Family".
+func htmlReportHeading(groupBy, name string) string {
+	switch {
+	case isEmpty(name) && groupBy == "due":
+		return "No due date"
+	case isEmpty(name):
+		return "No " + groupBy
+	case groupBy == "project":
+		return projectPrefix + name
+	case groupBy == "context":
+		return contextPrefix + name
+	default:
+		return name
+	}
+}
+
+// summarize returns the counts of the tasks.
+func (r *Renderer) summarize(tasks []*Task) HTMLReportSummary {
+	summary := HTMLReportSummary{Total: len(tasks), Open: 0, Done: 0, Overdue: 0, DueToday: 0}
+
+	for _, task := range tasks {
+		if task.Completed {
+			summary.Done++
+
+			continue
+		}
+
+		summary.Open++
+
+		switch r.dueState(task) {
+		case "overdue":
+			summary.Overdue++
+		case "due-today":
+			summary.DueToday++
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,82 @@
+package todo
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testHTMLReport writes the report of the template test list with a fake clock
+// on 2024-01-16.
+func testHTMLReport(t *testing.T, opts HTMLReportOptions) string {
+	t.Helper()
+
+	opts.Clock = &fakeClock{now: time.Date(2024, 1, 16, 10, 0, 0, 0, time.Local)}
+	tasklist := testMustLoad(t, testTemplateList)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, tasklist.WriteHTMLReport(&buf, opts))
+
+	return buf.String()
+}
+
+func TestTaskList_WriteHTMLReport(t *testing.T) {
+	t.Parallel()
+
+	actual := testHTMLReport(t, HTMLReportOptions{Clock: nil, Title: "", GroupBy: ""})
+
+	require.Contains(t, actual, "<!DOCTYPE html>")
+	require.Contains(t, actual, "<title>Tasks</title>", "title should default to Tasks")
+	require.Contains(t, actual, "Generated on 2024-01-16")
+	require.NotContains(t, actual, "<link", "page should have no external assets")
+	require.NotContains(t, actual, "<script", "page should have no external assets")
+	require.NotContains(t, actual, "<h2>", "ungrouped report should have no headings")
+
+	require.Contains(t, actual, `<span class="count">10</span> total`)
+	require.Contains(t, actual, `<span class="count">9</span> open`)
+	require.Contains(t, actual, `<span class="count">1</span> done`)
+	require.Contains(t, actual, `<span class="count">1</span> overdue`)
+	require.Contains(t, actual, `<span class="count">1</span> due today`)
+
+	require.Contains(t, actual, `<li><span class="todo-task todo-priority-a">`)
+	require.Contains(t, actual, `<li><span class="todo-task todo-overdue">`+
+		`<span class="todo-segment todo-todo-text">Plan garden</span>`)
+	require.Contains(t, actual, `<li><span class="todo-task todo-done">`)
+}
+
+func TestTaskList_WriteHTMLReport_groupBy(t *testing.T) {
+	t.Parallel()
+
+	actual := testHTMLReport(t, HTMLReportOptions{Clock: nil, Title: "Home & Garden", GroupBy: "project"})
+
+	require.Contains(t, actual, "<title>Home &amp; Garden</title>", "title should be escaped")
+	require.Contains(t, actual, "<h2>&#43;Family <small>2 open, 0 done</small></h2>")
+	require.Contains(t, actual, "<h2>&#43;Garden <small>1 open, 1 done</small></h2>")
+	require.Contains(t, actual, "<h2>No project <small>7 open, 0 done</small></h2>")
+	require.Contains(t, actual, `<span class="count">10</span> total`, "summary should count each task once")
+
+	actual = testHTMLReport(t, HTMLReportOptions{Clock: nil, Title: "", GroupBy: "context"})
+
+	require.Contains(t, actual, "<h2>@Home <small>1 open, 0 done</small></h2>")
+	require.Contains(t, actual, "<h2>@Phone <small>2 open, 0 done</small></h2>")
+	require.Contains(t, actual, "<h2>No context <small>6 open, 1 done</small></h2>")
+
+	actual = testHTMLReport(t, HTMLReportOptions{Clock: nil, Title: "", GroupBy: "due"})
+
+	require.Contains(t, actual, "<h2>2024-01-10 <small>1 open, 0 done</small></h2>")
+	require.Contains(t, actual, "<h2>No due date <small>5 open, 1 done</small></h2>")
+}
+
+func TestTaskList_WriteHTMLReport_error(t *testing.T) {
+	t.Parallel()
+
+	tasklist := testMustLoad(t, testTemplateList)
+
+	var buf bytes.Buffer
+
+	err := tasklist.WriteHTMLReport(&buf, HTMLReportOptions{Clock: nil, Title: "", GroupBy: "unknown"})
+	require.ErrorContains(t, err, "unknown group key: unknown")
+}
@@ -0,0 +1,686 @@
+package todo
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Constants
+// ----------------------------------------------------------------------------
+
+const (
+	// RecurrenceTag is the key of the additional tag of a recurring task, as
+	// used by many todo.txt clients (e.g. "rec:1w" or "rec:+2d"). See
+	// WriteICal for the supported values.
+	RecurrenceTag = "rec"
+
+	// ICalProdID is the PRODID of the exported iCalendar.
+	ICalProdID = "-//KEINOS//go-todotxt//EN"
+
+	// icalUIDSuffix is the suffix of the UIDs derived from the task.
+	icalUIDSuffix = "@go-todotxt"
+	// icalDateLayout is the layout of DATE values.
+	icalDateLayout = "20060102"
+	// icalDateTimeLayout is the layout of UTC DATE-TIME values.
+	icalDateTimeLayout = "20060102T150405Z"
+	// icalLineMax is the maximum length of a content line in octets.
+	icalLineMax = 75
+	// icalTodoTxtProp is the property holding the todo.txt line of the task.
+	icalTodoTxtProp = "X-TODOTXT"
+	// icalPriorityMax is the lowest iCalendar priority.
+	icalPriorityMax = 9
+)
+
+// recurrenceRx matches the value of RecurrenceTag: an optional "+" (strict
+// recurrence from the due date), an optional interval and the unit.
+var recurrenceRx = regexp.MustCompile(`^(\+?)(\d*)([dbwmy])$`)
+
+// ----------------------------------------------------------------------------
+//  Type: LossyField
+// ----------------------------------------------------------------------------
+
+// LossyField reports a value which could not be converted exactly between
+// todo.txt and another format, such as iCalendar or Taskwarrior.
+type LossyField struct {
+	Field  string `json:"field"`  // Field is the property of the other format or the todo.txt tag.
+	Value  string `json:"value"`  // Value is the value which was not converted exactly.
+	Reason string `json:"reason"` // Reason is why the value was not converted exactly.
+	TaskID int    `json:"task_id"`
+}
+
+// String returns the lossy field in a human-readable format.
+func (lossy LossyField) String() string {
+	return fmt.Sprintf("task %d: %s: %s (%s)", lossy.TaskID, lossy.Field, lossy.Reason, lossy.Value)
+}
+
+// ----------------------------------------------------------------------------
+//  Type: ICalOptions
+// ----------------------------------------------------------------------------
+
+// ICalOptions are the options of WriteICal.
+type ICalOptions struct {
+	// Clock is used for the DTSTAMP of the components. Defaults to the real
+	// time.
+	Clock Clock
+	// Name is the name of the calendar shown by calendar apps (X-WR-CALNAME).
+	// Omitted if empty.
+	Name string
+}
+
+// ----------------------------------------------------------------------------
+//  Public functions
+// ----------------------------------------------------------------------------
+
+// ICalUID returns the iCalendar UID of the task. It is the value of UIDTag if
+// set, otherwise it is derived from the created date and the text of the task,
+// so that it is stable across exports as long as those do not change.
+func ICalUID(task *Task) string {
+	if uid := task.AdditionalTags[UIDTag]; isNotEmpty(uid) {
+		return uid
+	}
+
+	sum := sha256.Sum256([]byte(formatDate(task.CreatedDate) + " " + task.Todo))
+
+	return hex.EncodeToString(sum[:8]) + icalUIDSuffix
+}
+
+// LoadFromICal loads a TaskList from an iCalendar stream. Only VTODO components
+// are read, any other component is ignored.
+//
+// The task is built from the standard properties (see WriteICal), so changes
+// made in calendar apps take effect. The todo.txt line in the X-TODOTXT
+// property, if any, restores the values which iCalendar can not hold, such as
+// the additional tags and the priorities below "I".
+//
+// Properties which can not be represented in todo.txt are reported as
+// LossyField. The ID of the tasks is their position, starting from 1.
+func LoadFromICal(reader io.Reader) (TaskList, []LossyField, error) {
+	lines, err := unfoldICal(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tasklist := NewTaskList()
+	lossy := []LossyField{}
+
+	var (
+		props   []icalProp
+		inTodo  bool
+		nesting []string
+	)
+
+	for _, line := range lines {
+		prop, err := parseICalProp(line)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case prop.name == "BEGIN" && !inTodo && strings.EqualFold(prop.value, "VTODO"):
+			inTodo, props = true, nil
+		case prop.name == "BEGIN" && inTodo:
+			nesting = append(nesting, prop.value)
+		case prop.name == "END" && inTodo && len(nesting) > 0:
+			lossy = append(lossy, LossyField{
+				Field: strings.ToUpper(nesting[len(nesting)-1]), Value: emptyStr,
+				Reason: "component is not supported", TaskID: len(tasklist) + 1,
+			})
+			nesting = nesting[:len(nesting)-1]
+		case prop.name == "END" && inTodo:
+			task, taskLossy, err := vtodoToTask(props, len(tasklist)+1)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			tasklist = append(tasklist, *task)
+			lossy = append(lossy, taskLossy...)
+			inTodo = false
+		case inTodo && len(nesting) == 0:
+			props = append(props, prop)
+		}
+	}
+
+	if inTodo {
+		return nil, nil, errors.New("failed to load iCalendar: VTODO is not closed")
+	}
+
+	return tasklist, lossy, nil
+}
+
+// ----------------------------------------------------------------------------
+//  TaskList.WriteICal()
+// ----------------------------------------------------------------------------
+
+// WriteICal writes the TaskList as an iCalendar (RFC 5545) stream with a VTODO
+// component per task, and returns the values which could not be converted
+// exactly.
+//
+// The fields are mapped as follows:
+//
+//   - Todo to SUMMARY and ICalUID to UID.
+//   - Priority "A" to "H" to PRIORITY 1 to 8, and "I" to "Z" to 9.
+//   - CreatedDate to CREATED, DueDate to DUE and CompletedDate to COMPLETED.
+//   - Completed to STATUS "COMPLETED", otherwise "NEEDS-ACTION".
+//   - Contexts and projects to CATEGORIES with their "@" and "+" prefixes.
+//   - RecurrenceTag to RRULE. The units "d", "w", "m" and "y" are supported,
+//     as well as "b" (business days) without interval. The strict "+" form is
+//     exported as a normal recurrence.
+//
+// The whole todo.txt line is kept in the X-TODOTXT property, so that the tasks
+// round-trip through LoadFromICal.
+func (tasklist *TaskList) WriteICal(writer io.Writer, opts ICalOptions) ([]LossyField, error) {
+	clock := opts.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	stamp := clock.Now().UTC().Format(icalDateTimeLayout)
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:" + ICalProdID, "CALSCALE:GREGORIAN"}
+	lossy := []LossyField{}
+
+	if isNotEmpty(opts.Name) {
+		lines = append(lines, "X-WR-CALNAME:"+escapeICalText(opts.Name))
+	}
+
+	for i := range *tasklist {
+		taskLines, taskLossy := taskToVTODO(&(*tasklist)[i], stamp)
+
+		lines = append(lines, taskLines...)
+		lossy = append(lossy, taskLossy...)
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+
+	bufWriter := bufio.NewWriter(writer)
+
+	for _, line := range lines {
+		if _, err := bufWriter.WriteString(foldICalLine(line)); err != nil {
+			return nil, errors.Wrap(err, "failed to write iCalendar")
+		}
+	}
+
+	return lossy, errors.Wrap(bufWriter.Flush(), "failed to write iCalendar")
+}
+
+// ----------------------------------------------------------------------------
+//  Type: icalProp
+// ----------------------------------------------------------------------------
+
+// icalProp is a content line of iCalendar.
+type icalProp struct {
+	params map[string]string
+	name   string
+	value  string
+}
+
+// parseICalProp parses an unfolded content line ("NAME;PARAM=x:value").
+func parseICalProp(line string) (icalProp, error) {
+	prop := icalProp{params: map[string]string{}, name: emptyStr, value: emptyStr}
+	quoted := false
+	start := 0
+
+	for i, char := range line {
+		switch {
+		case char == '"':
+			quoted = !quoted
+		case quoted:
+			continue
+		case char == ';' || char == ':':
+			part := line[start:i]
+
+			if isEmpty(prop.name) {
+				prop.name = strings.ToUpper(part)
+			} else {
+				key, value, _ := strings.Cut(part, "=")
+				prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+			}
+
+			start = i + 1
+
+			if char == ':' {
+				prop.value = line[start:]
+
+				return prop, nil
+			}
+		}
+	}
+
+	return prop, errors.New("invalid iCalendar content line: " + line)
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// escapeICalText escapes a TEXT value.
+func escapeICalText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
+
+// foldICalLine folds the content line at 75 octets, without breaking UTF-8
+// characters, and appends CRLF.
+func foldICalLine(line string) string {
+	var strBldr strings.Builder
+
+	limit := icalLineMax
+
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+
+		strBldr.WriteString(line[:cut] + "\r\n ")
+
+		line = line[cut:]
+		limit = icalLineMax - 1 // the leading space counts
+	}
+
+	strBldr.WriteString(line + "\r\n")
+
+	return strBldr.String()
+}
+
+// isRuneStart returns true if the byte is the first byte of a UTF-8 character.
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// icalPriority converts the todo.txt priority to iCalendar priority. Zero is
+// undefined.
+func icalPriority(priority string) int {
+	if len(priority) != 1 || priority[0] < 'A' || priority[0] > 'Z' {
+		return 0
+	}
+
+	return min(int(priority[0]-'A')+1, icalPriorityMax)
+}
+
+// parseICalDate parses a DATE or DATE-TIME value and returns the date in the
+// local time zone. It returns true if a time of the day was dropped.
+func parseICalDate(value string) (time.Time, bool, error) {
+	if len(value) < len(icalDateLayout) {
+		return time.Time{}, false, errors.New("invalid iCalendar date: " + value)
+	}
+
+	//nolint:gosmopolitan // same as parseTime
+	date, err := time.ParseInLocation(icalDateLayout, value[:len(icalDateLayout)], time.Local)
+	if err != nil {
+		return time.Time{}, false, errors.Wrap(err, "invalid iCalendar date")
+	}
+
+	timePart := strings.TrimSuffix(value[len(icalDateLayout):], "Z")
+
+	return date, timePart != emptyStr && timePart != "T000000", nil
+}
+
+// recurrenceToRRule converts the value of RecurrenceTag to a RRULE value. It
+// returns false if it can not be converted.
+func recurrenceToRRule(rec string) (string, bool) {
+	match := recurrenceRx.FindStringSubmatch(rec)
+	if match == nil {
+		return emptyStr, false
+	}
+
+	interval := 1
+	if isNotEmpty(match[2]) {
+		interval, _ = strconv.Atoi(match[2]) // digits only
+
+		if interval < 1 {
+			return emptyStr, false
+		}
+	}
+
+	freq := map[string]string{"d": "DAILY", "w": "WEEKLY", "m": "MONTHLY", "y": "YEARLY"}[match[3]]
+
+	if match[3] == "b" {
+		if interval != 1 {
+			return emptyStr, false
+		}
+
+		return "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", true
+	}
+
+	if interval == 1 {
+		return "FREQ=" + freq, true
+	}
+
+	return "FREQ=" + freq + ";INTERVAL=" + strconv.Itoa(interval), true
+}
+
+// rruleToRecurrence converts a RRULE value to the value of RecurrenceTag. It
+// returns false if it can not be converted.
+func rruleToRecurrence(rrule string) (string, bool) {
+	parts := map[string]string{}
+
+	for _, part := range strings.Split(strings.ToUpper(rrule), ";") {
+		key, value, _ := strings.Cut(part, "=")
+		parts[key] = value
+	}
+
+	interval := parts["INTERVAL"]
+	delete(parts, "INTERVAL")
+
+	if isEmpty(interval) || interval == "1" {
+		interval = emptyStr
+	} else if num, err := strconv.Atoi(interval); err != nil || num < 1 {
+		return emptyStr, false
+	}
+
+	if parts["FREQ"] == "DAILY" && parts["BYDAY"] == "MO,TU,WE,TH,FR" && len(parts) == 2 && isEmpty(interval) {
+		return "b", true
+	}
+
+	unit := map[string]string{"DAILY": "d", "WEEKLY": "w", "MONTHLY": "m", "YEARLY": "y"}[parts["FREQ"]]
+	if isEmpty(unit) || len(parts) != 1 {
+		return emptyStr, false
+	}
+
+	if isEmpty(interval) {
+		interval = "1"
+	}
+
+	return interval + unit, true
+}
+
+// sanitizeWord replaces the whitespaces and colons, which todo.txt words can
+// not hold, with "_".
+func sanitizeWord(word string) string {
+	return strings.Map(func(char rune) rune {
+		if char == ':' || strings.ContainsRune(whitespaces, char) {
+			return '_'
+		}
+
+		return char
+	}, strings.TrimSpace(word))
+}
+
+// splitICalList splits a list value by unescaped commas and unescapes the
+// items.
+func splitICalList(value string) []string {
+	var (
+		items   []string
+		current strings.Builder
+	)
+
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value):
+			current.WriteByte(value[i])
+			current.WriteByte(value[i+1])
+			i++
+		case value[i] == ',':
+			items = append(items, unescapeICalText(current.String()))
+			current.Reset()
+		default:
+			current.WriteByte(value[i])
+		}
+	}
+
+	return append(items, unescapeICalText(current.String()))
+}
+
+// taskToVTODO returns the content lines of the VTODO component of the task.
+func taskToVTODO(task *Task, stamp string) ([]string, []LossyField) {
+	lossy := []LossyField{}
+	lines := []string{
+		"BEGIN:VTODO",
+		"UID:" + escapeICalText(ICalUID(task)),
+		"DTSTAMP:" + stamp,
+		"SUMMARY:" + escapeICalText(task.Todo),
+	}
+
+	if priority := icalPriority(task.Priority); priority > 0 {
+		lines = append(lines, "PRIORITY:"+strconv.Itoa(priority))
+
+		if priority == icalPriorityMax && task.Priority != "I" {
+			lossy = append(lossy, LossyField{
+				Field: "priority", Value: task.Priority, Reason: "exported as the lowest priority 9", TaskID: task.ID,
+			})
+		}
+	}
+
+	if task.HasCreatedDate() {
+		lines = append(lines, "CREATED:"+dateToUTC(task.CreatedDate))
+	}
+
+	if task.HasDueDate() {
+		lines = append(lines, "DUE;VALUE=DATE:"+task.DueDate.Format(icalDateLayout))
+	}
+
+	if task.Completed {
+		lines = append(lines, "STATUS:COMPLETED")
+
+		if task.HasCompletedDate() {
+			lines = append(lines, "COMPLETED:"+dateToUTC(task.CompletedDate))
+		}
+	} else {
+		lines = append(lines, "STATUS:NEEDS-ACTION")
+	}
+
+	categories := make([]string, 0, len(task.Contexts)+len(task.Projects))
+	for _, context := range task.Contexts {
+		categories = append(categories, escapeICalText(contextPrefix+context))
+	}
+
+	for _, project := range task.Projects {
+		categories = append(categories, escapeICalText(projectPrefix+project))
+	}
+
+	if len(categories) > 0 {
+		lines = append(lines, "CATEGORIES:"+strings.Join(categories, ","))
+	}
+
+	if rec, found := task.AdditionalTags[RecurrenceTag]; found {
+		rrule, ok := recurrenceToRRule(rec)
+
+		switch {
+		case !ok:
+			lossy = append(lossy, LossyField{
+				Field: RecurrenceTag, Value: rec, Reason: "recurrence is not supported by RRULE", TaskID: task.ID,
+			})
+		case strings.HasPrefix(rec, "+"):
+			lossy = append(lossy, LossyField{
+				Field: RecurrenceTag, Value: rec, Reason: "strict recurrence is exported as normal RRULE", TaskID: task.ID,
+			})
+		}
+
+		if ok {
+			lines = append(lines, "RRULE:"+rrule)
+		}
+	}
+
+	lines = append(lines, icalTodoTxtProp+":"+escapeICalText(task.String()), "END:VTODO")
+
+	return lines, lossy
+}
+
+// dateToUTC returns the date as a UTC DATE-TIME at midnight of the same date.
+func dateToUTC(date time.Time) string {
+	year, month, day := date.Date()
+
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Format(icalDateTimeLayout)
+}
+
+// unescapeICalText unescapes a TEXT value. Line breaks become spaces, since a
+// todo.txt task is a single line.
+func unescapeICalText(text string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, " ", `\N`, " ").Replace(text)
+}
+
+// unfoldICal reads the content lines and unfolds them.
+func unfoldICal(reader io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(reader)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		switch {
+		case isEmpty(line):
+			continue
+		case (line[0] == ' ' || line[0] == '\t') && len(lines) > 0:
+			lines[len(lines)-1] += line[1:]
+		default:
+			lines = append(lines, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read iCalendar")
+	}
+
+	return lines, nil
+}
+
+// vtodoToTask converts the properties of a VTODO component to a Task.
+//
+//nolint:cyclop,funlen // a simple switch over the properties
+func vtodoToTask(props []icalProp, taskID int) (*Task, []LossyField, error) {
+	lossy := []LossyField{}
+	task := new(Task)
+	rrule := emptyStr
+	uid := emptyStr
+
+	var original *Task
+
+	for _, prop := range props {
+		var (
+			err     error
+			hasTime bool
+		)
+
+		switch prop.name {
+		case "UID":
+			uid = unescapeICalText(prop.value)
+		case "SUMMARY":
+			task.Todo = unescapeICalText(prop.value)
+		case "PRIORITY":
+			var priority int
+
+			priority, err = strconv.Atoi(prop.value)
+			if err == nil && priority > 0 && priority <= icalPriorityMax {
+				task.Priority = string(rune('A' + priority - 1))
+			}
+		case "CREATED":
+			task.CreatedDate, hasTime, err = parseICalDate(prop.value)
+		case "DUE":
+			task.DueDate, hasTime, err = parseICalDate(prop.value)
+		case "COMPLETED":
+			task.CompletedDate, hasTime, err = parseICalDate(prop.value)
+		case "STATUS":
+			task.Completed = strings.EqualFold(prop.value, "COMPLETED") || strings.EqualFold(prop.value, "CANCELLED")
+			if strings.EqualFold(prop.value, "CANCELLED") {
+				lossy = append(lossy, LossyField{
+					Field: prop.name, Value: prop.value, Reason: "imported as completed", TaskID: taskID,
+				})
+			}
+		case "CATEGORIES":
+			for _, category := range splitICalList(prop.value) {
+				word := sanitizeWord(category)
+
+				switch {
+				case isEmpty(word):
+					continue
+				case strings.HasPrefix(word, projectPrefix):
+					task.Projects = append(task.Projects, strings.TrimPrefix(word, projectPrefix))
+				default:
+					task.Contexts = append(task.Contexts, strings.TrimPrefix(word, contextPrefix))
+				}
+			}
+		case "RRULE":
+			rrule = prop.value
+		case icalTodoTxtProp:
+			original, err = ParseTask(unescapeICalText(prop.value))
+		case "DTSTAMP", "LAST-MODIFIED", "SEQUENCE", "PERCENT-COMPLETE", "CLASS":
+			continue // metadata without todo.txt counterpart
+		default:
+			lossy = append(lossy, LossyField{
+				Field: prop.name, Value: prop.value, Reason: "property is not supported", TaskID: taskID,
+			})
+		}
+
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to load iCalendar: invalid %s of task %d", prop.name, taskID)
+		}
+
+		if hasTime {
+			lossy = append(lossy, LossyField{
+				Field: prop.name, Value: prop.value, Reason: "time of the day is dropped", TaskID: taskID,
+			})
+		}
+	}
+
+	lossy = append(lossy, restoreFromOriginal(task, original, rrule, taskID)...)
+
+	if isNotEmpty(uid) && !strings.HasSuffix(uid, icalUIDSuffix) && isEmpty(task.AdditionalTags[UIDTag]) {
+		setTag(task, UIDTag, sanitizeWord(uid))
+	}
+
+	// Parse the todo.txt line to make sure it is valid, and that the summary is
+	// not read as the completion mark, priority or dates
+	parsed, err := reparseTask(task)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to load iCalendar: task %d", taskID)
+	}
+
+	parsed.ID = taskID
+
+	return parsed, lossy, nil
+}
+
+// restoreFromOriginal restores the values iCalendar can not hold from the task
+// of the X-TODOTXT property, and sets the recurrence from the RRULE.
+func restoreFromOriginal(task, original *Task, rrule string, taskID int) []LossyField {
+	lossy := []LossyField{}
+	origRec := emptyStr
+
+	if original != nil {
+		if icalPriority(original.Priority) == icalPriority(task.Priority) {
+			task.Priority = original.Priority
+		}
+
+		for key, value := range original.AdditionalTags {
+			if key != RecurrenceTag {
+				setTag(task, key, value)
+			}
+		}
+
+		origRec = original.AdditionalTags[RecurrenceTag]
+	}
+
+	origRRule, convertible := recurrenceToRRule(origRec)
+
+	switch {
+	case isEmpty(rrule) && isNotEmpty(origRec) && !convertible:
+		setTag(task, RecurrenceTag, origRec) // was not exported as RRULE
+	case isEmpty(rrule):
+		break
+	case isNotEmpty(origRec) && strings.EqualFold(origRRule, rrule):
+		setTag(task, RecurrenceTag, origRec)
+	default:
+		rec, ok := rruleToRecurrence(rrule)
+		if !ok {
+			lossy = append(lossy, LossyField{
+				Field: "RRULE", Value: rrule, Reason: "recurrence is not supported by todo.txt", TaskID: taskID,
+			})
+
+			break
+		}
+
+		setTag(task, RecurrenceTag, rec)
+	}
+
+	return lossy
+}
@@ -0,0 +1,227 @@
+package todo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskList_WriteICal(t *testing.T) {
+	t.Parallel()
+
+	tasklist := testMustLoad(t, `(A) 2024-01-01 Call Mom, Dad; Aunt @Phone +Family due:2024-01-05 uid:42 rec:+1w
+x 2024-01-03 (K) 2024-01-02 Learn Go rec:2b
+`)
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	var buf bytes.Buffer
+
+	lossy, err := tasklist.WriteICal(&buf, ICalOptions{Clock: clock, Name: "My tasks"})
+	require.NoError(t, err)
+
+	expect := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//KEINOS//go-todotxt//EN",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:My tasks",
+		"BEGIN:VTODO",
+		"UID:42",
+		"DTSTAMP:20240102T030405Z",
+		"SUMMARY:Call Mom\\, Dad\\; Aunt",
+		"PRIORITY:1",
+		"CREATED:20240101T000000Z",
+		"DUE;VALUE=DATE:20240105",
+		"STATUS:NEEDS-ACTION",
+		"CATEGORIES:@Phone,+Family",
+		"RRULE:FREQ=WEEKLY",
+		"X-TODOTXT:(A) 2024-01-01 Call Mom\\, Dad\\; Aunt @Phone +Family rec:+1w uid:4",
+		" 2 due:2024-01-05",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:" + ICalUID(&tasklist[1]),
+		"DTSTAMP:20240102T030405Z",
+		"SUMMARY:Learn Go",
+		"PRIORITY:9",
+		"CREATED:20240102T000000Z",
+		"STATUS:COMPLETED",
+		"COMPLETED:20240103T000000Z",
+		"X-TODOTXT:x 2024-01-03 (K) 2024-01-02 Learn Go rec:2b",
+		"END:VTODO",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	require.Equal(t, expect, buf.String())
+
+	require.Equal(t, []LossyField{
+		{Field: "rec", Value: "+1w", Reason: "strict recurrence is exported as normal RRULE", TaskID: 1},
+		{Field: "priority", Value: "K", Reason: "exported as the lowest priority 9", TaskID: 2},
+		{Field: "rec", Value: "2b", Reason: "recurrence is not supported by RRULE", TaskID: 2},
+	}, lossy, "completed priority is kept as RemoveCompletedPriority is false in tests")
+	require.Equal(t, "task 2: rec: recurrence is not supported by RRULE (2b)", lossy[2].String())
+
+	require.True(t, strings.HasSuffix(ICalUID(&tasklist[1]), "@go-todotxt"))
+}
+
+func TestLoadFromICal_round_trip(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{testInputTask, testInputSort, testInputFilter, testInputTasklist} {
+		expect, err := LoadFromPath(path)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+
+		_, err = expect.WriteICal(&buf, ICalOptions{})
+		require.NoError(t, err)
+
+		actual, lossy, err := LoadFromICal(&buf)
+		require.NoError(t, err)
+		require.Empty(t, lossy)
+		require.Equal(t, expect.String(), actual.String(), "%s should round-trip", path)
+	}
+
+	// Priorities, recurrences and tags which iCalendar can not hold
+	expect := testMustLoad(t, "(K) Learn Go rec:2b level:basic\n(B) Call Mom rec:+3m\n")
+
+	var buf bytes.Buffer
+
+	_, err := expect.WriteICal(&buf, ICalOptions{})
+	require.NoError(t, err)
+
+	actual, _, err := LoadFromICal(&buf)
+	require.NoError(t, err)
+	require.Equal(t, expect.String(), actual.String())
+}
+
+func TestLoadFromICal(t *testing.T) {
+	t.Parallel()
+
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"SUMMARY:Ignored event",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:abc-123@example.com",
+		"SUMMARY:Call Mom\\, then D",
+		" ad\\nsoon",
+		"PRIORITY:2",
+		"DUE;TZID=Europe/Paris:20240105T120000",
+		"STATUS:CANCELLED",
+		"CATEGORIES:+Family,Phone calls,@Home",
+		"RRULE:FREQ=WEEKLY;INTERVAL=2",
+		"DESCRIPTION:Notes",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"END:VALARM",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"SUMMARY:Water plants",
+		"RRULE:FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"SUMMARY:Pay bills",
+		"RRULE:FREQ=MONTHLY;BYMONTHDAY=1",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	tasklist, lossy, err := LoadFromICal(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Equal(t, strings.ReplaceAll(`x (B) Call Mom, then Dad soon @Home @Phone_calls +Family rec:2w uid:abc-123@example.com due:2024-01-05
+Water plants rec:b
+Pay bills
+`, "\n", NewLine), tasklist.String())
+	require.Equal(t, 3, tasklist[2].ID)
+
+	fields := make([]string, len(lossy))
+	for i, field := range lossy {
+		fields[i] = field.Field
+	}
+
+	require.Equal(t, []string{"VALARM", "DUE", "STATUS", "DESCRIPTION", "RRULE"}, fields)
+}
+
+func TestLoadFromICal_leading_marker(t *testing.T) {
+	t.Parallel()
+
+	for _, summary := range []string{"x marks the spot", "2024-01-01 review", "(B) foo"} {
+		input := "BEGIN:VTODO\r\nSUMMARY:" + summary + "\r\nEND:VTODO\r\n"
+
+		tasklist, _, err := LoadFromICal(strings.NewReader(input))
+		require.NoError(t, err)
+		require.Len(t, tasklist, 1)
+
+		task := tasklist[0]
+		require.False(t, task.Completed, "summary should not be read as the completion mark")
+		require.False(t, task.HasPriority(), "summary should not be read as the priority")
+		require.False(t, task.HasCreatedDate(), "summary should not be read as the created date")
+		require.Equal(t, `\`+summary, task.String(), "leading marker should be escaped")
+	}
+
+	// Not escaped after the priority
+	tasklist, _, err := LoadFromICal(strings.NewReader("BEGIN:VTODO\r\nSUMMARY:(B) foo\r\nPRIORITY:1\r\nEND:VTODO\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, "(A) (B) foo", tasklist[0].String())
+}
+
+func TestLoadFromICal_error(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		"BEGIN:VTODO\nSUMMARY:foo\n",
+		"BEGIN:VTODO\nDUE:2024\nEND:VTODO\n",
+		"BEGIN:VTODO\nDUE:20240230\nEND:VTODO\n",
+		"BEGIN:VTODO\nPRIORITY:high\nEND:VTODO\n",
+		"BEGIN:VTODO\nX-TODOTXT:2024-02-30 foo\nEND:VTODO\n",
+		"BEGIN:VTODO\ninvalid line\nEND:VTODO\n",
+	} {
+		_, _, err := LoadFromICal(strings.NewReader(input))
+		require.Error(t, err, "invalid input should fail: %q", input)
+	}
+}
+
+func TestRecurrence_RRule(t *testing.T) {
+	t.Parallel()
+
+	for rec, rrule := range map[string]string{
+		"d":   "FREQ=DAILY",
+		"1w":  "FREQ=WEEKLY",
+		"3m":  "FREQ=MONTHLY;INTERVAL=3",
+		"+2y": "FREQ=YEARLY;INTERVAL=2",
+		"b":   "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+	} {
+		actual, ok := recurrenceToRRule(rec)
+		require.True(t, ok)
+		require.Equal(t, rrule, actual)
+	}
+
+	for _, rec := range []string{"0d", "2b", "1h", "w1"} {
+		_, ok := recurrenceToRRule(rec)
+		require.False(t, ok, "%q should not be converted", rec)
+	}
+
+	for _, rrule := range []string{"FREQ=HOURLY", "FREQ=DAILY;INTERVAL=0", "FREQ=WEEKLY;COUNT=3"} {
+		_, ok := rruleToRecurrence(rrule)
+		require.False(t, ok, "%q should not be converted", rrule)
+	}
+}
+
+func TestFoldICalLine(t *testing.T) {
+	t.Parallel()
+
+	line := "SUMMARY:" + strings.Repeat("あ", 40)
+	folded := foldICalLine(line)
+
+	for _, part := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		require.LessOrEqual(t, len(part), 75)
+	}
+
+	lines, err := unfoldICal(strings.NewReader(folded))
+	require.NoError(t, err)
+	require.Equal(t, []string{line}, lines, "folded line should be unfolded as it was")
+}
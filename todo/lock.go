@@ -0,0 +1,346 @@
+package todo
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Constants
+// ----------------------------------------------------------------------------
+
+const (
+	// lockSuffix is appended to the todo.txt path to name its lock file.
+	lockSuffix = ".lock"
+	// lockBreakSuffix is appended to the lock file path to name the lock taken
+	// to remove an abandoned lock file, so that only one process removes it.
+	lockBreakSuffix = ".break"
+	// lockRetryInterval is the wait time between attempts to get a lock file.
+	lockRetryInterval = 10 * time.Millisecond
+	// lockRefreshInterval is the interval at which the holder of a lock file
+	// updates its modification time, to show that it is still alive.
+	lockRefreshInterval = lockStaleAge / 4
+	// lockStaleAge is the age after which a lock file which was not refreshed
+	// is considered to be abandoned by a crashed process and removed.
+	lockStaleAge = 10 * time.Second
+	// lockTimeout is the maximum time to wait for a lock file.
+	lockTimeout = 15 * time.Second
+)
+
+// ----------------------------------------------------------------------------
+//  Public functions
+// ----------------------------------------------------------------------------
+
+// UpdatePath applies a locked read-modify-write cycle to the given todo.txt file.
+//
+// It takes an advisory lock on "<filename>.lock" ("flock" on Linux, the
+// exclusive creation of the file on other platforms), which is removed once
+// done. It re-reads the file inside the lock and calls update
+// with the loaded TaskList. If update returns nil, the modified TaskList is
+// written back atomically (a temporary file is renamed over the original).
+// If update returns an error, the file is left untouched and the error is
+// returned.
+//
+// A non-existing file is treated as an empty TaskList and will be created.
+//
+// Note: As with WriteToPath, comments from the original file will be omitted
+// if IgnoreComments is set to 'true'.
+func UpdatePath(filename string, update func(tasklist *TaskList) error) error {
+	unlock, err := lockPath(filename)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = unlock() }()
+
+	tasklist := NewTaskList()
+
+	err = tasklist.LoadFromPath(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	err = update(&tasklist)
+	if err != nil {
+		return errors.Wrap(err, "update of the task list canceled")
+	}
+
+	return writeFileAtomic(filename, []byte(tasklist.String()))
+}
+
+// UpdateLinesPath is like UpdatePath, but calls update with the raw lines of
+// the file instead of a TaskList. Blank lines and comments are passed as they
+// are, so that the line numbers are kept, as todo.sh refers to the tasks by
+// their line number and leaves a blank line on deletion.
+//
+// The lines have no line endings and are written back joined by NewLine.
+func UpdateLinesPath(filename string, update func(lines []string) ([]string, error)) error {
+	unlock, err := lockPath(filename)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = unlock() }()
+
+	lines, err := readLines(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	lines, err = update(lines)
+	if err != nil {
+		return errors.Wrap(err, "update of the task list canceled")
+	}
+
+	return writeFileAtomic(filename, []byte(joinLines(lines)))
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// breakStaleLock removes the lock file if it was not refreshed for lockStaleAge
+// and returns true if it did.
+//
+// The removal is done while holding "<lock file>.break", so that two waiters
+// can not both see the same stale lock file and one of them remove the lock
+// file created meanwhile by the other.
+func breakStaleLock(pathLock string) bool {
+	if !isStaleLock(pathLock) {
+		return false
+	}
+
+	pathBreak := pathLock + lockBreakSuffix
+
+	//nolint:gosec // the lock file path is derived from the user provided todo.txt path
+	file, err := os.OpenFile(pathBreak, os.O_CREATE|os.O_EXCL|os.O_WRONLY, PermReadWrite)
+	if err != nil {
+		// Only held for an instant, unless its holder crashed meanwhile
+		if isStaleLock(pathBreak) {
+			_ = os.Remove(pathBreak)
+		}
+
+		return false
+	}
+
+	_ = file.Close()
+
+	defer func() { _ = os.Remove(pathBreak) }()
+
+	return isStaleLock(pathLock) && os.Remove(pathLock) == nil
+}
+
+// createLockFile creates the lock file with the token as contents. It fails
+// with os.ErrExist if the lock file exists.
+func createLockFile(pathLock, token string) error {
+	//nolint:gosec // the lock file path is derived from the user provided todo.txt path
+	file, err := os.OpenFile(pathLock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, PermReadWrite)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteString(token)
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+
+	if err != nil {
+		_ = os.Remove(pathLock)
+	}
+
+	return err
+}
+
+// holdLockFile refreshes the modification time of the created lock file until
+// the returned function is called. The function removes the lock file, unless
+// it was removed as stale and taken by another process meanwhile.
+func holdLockFile(pathLock, token string) func() error {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(lockRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				_ = os.Chtimes(pathLock, now, now)
+			}
+		}
+	}()
+
+	return func() error {
+		close(done)
+		<-stopped
+
+		if readLockToken(pathLock) != token {
+			return errors.New("lock file was taken over by another process: " + pathLock)
+		}
+
+		return errors.Wrap(os.Remove(pathLock), "failed to remove lock file")
+	}
+}
+
+// isStaleLock returns true if the lock file exists and was not modified for
+// lockStaleAge.
+func isStaleLock(pathLock string) bool {
+	info, err := os.Stat(pathLock)
+
+	return err == nil && time.Since(info.ModTime()) > lockStaleAge
+}
+
+// lockPaths takes the locks of the files like lockPath, in the order of their
+// absolute paths, so that two callers locking the same files never wait for
+// each other. A file given twice is locked once. It returns a function to
+// release the locks.
+func lockPaths(filenames ...string) (func() error, error) {
+	paths := make([]string, 0, len(filenames))
+
+	for _, filename := range filenames {
+		path, err := filepath.Abs(filename)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to lock file: "+filename)
+		}
+
+		paths = append(paths, path)
+	}
+
+	slices.Sort(paths)
+
+	unlocks := []func() error{}
+	unlockAll := func() error {
+		var err error
+
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			if errUnlock := unlocks[i](); err == nil {
+				err = errUnlock
+			}
+		}
+
+		return err
+	}
+
+	for _, path := range slices.Compact(paths) {
+		unlock, err := lockPath(path)
+		if err != nil {
+			_ = unlockAll()
+
+			return nil, err
+		}
+
+		unlocks = append(unlocks, unlock)
+	}
+
+	return unlockAll, nil
+}
+
+// lockToken returns the contents of a lock file taken by this process: its PID
+// followed by a random string, unique to the lock.
+func lockToken() string {
+	random := make([]byte, 8) //nolint:mnd // 64 bits are enough to be unique
+	_, _ = rand.Read(random)
+
+	return strconv.Itoa(os.Getpid()) + " " + hex.EncodeToString(random)
+}
+
+// lockWithFile takes an exclusive lock by creating "<filename>.lock" with the
+// PID of the process. It is the fallback for platforms without flock(2).
+//
+// The lock file is refreshed while the lock is held, so that a lock file not
+// refreshed for lockStaleAge is left by a crashed process and is removed. A
+// lock held longer than lockTimeout by a live process makes the other writers
+// fail instead.
+//
+// It returns a function to release the lock.
+func lockWithFile(filename string) (func() error, error) {
+	pathLock := filename + lockSuffix
+	token := lockToken()
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		err := createLockFile(pathLock, token)
+		if err == nil {
+			return holdLockFile(pathLock, token), nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, errors.Wrap(err, "failed to create lock file: "+pathLock)
+		}
+
+		if breakStaleLock(pathLock) {
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			holder, _, _ := strings.Cut(readLockToken(pathLock), " ")
+
+			return nil, errors.New("timeout waiting for lock file held by process " + holder + ": " + pathLock)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// readLockToken returns the contents of the lock file, or an empty string if it
+// can not be read.
+func readLockToken(pathLock string) string {
+	raw, err := os.ReadFile(pathLock)
+	if err != nil {
+		return emptyStr
+	}
+
+	return string(raw)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory as
+// filename and renames it over filename. The permission of an existing file
+// is kept, otherwise PermReadWrite is used.
+func writeFileAtomic(filename string, data []byte) error {
+	perm := os.FileMode(PermReadWrite)
+	if info, err := os.Stat(filename); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+
+	pathTemp := file.Name()
+
+	defer func() { _ = os.Remove(pathTemp) }() // no-op after a successful rename
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "failed to write temporary file")
+	}
+
+	err = os.Chmod(pathTemp, perm)
+	if err != nil {
+		return errors.Wrap(err, "failed to set permission of temporary file")
+	}
+
+	return errors.Wrap(os.Rename(pathTemp, filename),
+		"failed to save task list to the path: "+filename)
+}
@@ -0,0 +1,73 @@
+//go:build linux
+// +build linux
+
+package todo
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// lockPath takes an exclusive advisory lock (flock) on "<filename>.lock".
+// It blocks until the lock is acquired and returns a function to release it.
+//
+// The lock file is removed before it is unlocked. A waiter which gets the lock
+// of a removed file tries again with the file now at the path, so that all the
+// writers lock the same file.
+func lockPath(filename string) (func() error, error) {
+	pathLock := filename + lockSuffix
+
+	for {
+		//nolint:gosec // the lock file path is derived from the user provided todo.txt path
+		file, err := os.OpenFile(pathLock, os.O_CREATE|os.O_RDWR, PermReadWrite)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open lock file: "+pathLock)
+		}
+
+		//nolint:gosec // file descriptors fit into int on all supported platforms
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+		if err != nil {
+			_ = file.Close()
+
+			return nil, errors.Wrap(err, "failed to lock file: "+pathLock)
+		}
+
+		if isLockedPath(file, pathLock) {
+			return func() error { return unlockPath(file, pathLock) }, nil
+		}
+
+		_ = file.Close() // releases the lock of the removed file
+	}
+}
+
+// isLockedPath returns true if the locked file is still the file at the path.
+func isLockedPath(file *os.File, pathLock string) bool {
+	infoLocked, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	infoPath, err := os.Stat(pathLock)
+
+	return err == nil && os.SameFile(infoLocked, infoPath)
+}
+
+// unlockPath removes the lock file and releases its lock.
+func unlockPath(file *os.File, pathLock string) error {
+	errRemove := os.Remove(pathLock)
+
+	//nolint:gosec // file descriptors fit into int on all supported platforms
+	errUnlock := syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	errClose := file.Close()
+
+	switch {
+	case errRemove != nil:
+		return errors.Wrap(errRemove, "failed to remove lock file: "+pathLock)
+	case errUnlock != nil:
+		return errors.Wrap(errUnlock, "failed to unlock file: "+pathLock)
+	}
+
+	return errors.Wrap(errClose, "failed to close lock file: "+pathLock)
+}
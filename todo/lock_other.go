@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package todo
+
+// lockPath takes an exclusive lock by creating "<filename>.lock". It returns
+// a function to release the lock.
+func lockPath(filename string) (func() error, error) {
+	return lockWithFile(filename)
+}
@@ -0,0 +1,402 @@
+package todo
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// ----------------------------------------------------------------------------
+//  UpdatePath()
+// ----------------------------------------------------------------------------
+
+func TestUpdatePath(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+
+	require.NoError(t, os.WriteFile(pathFile, []byte("(A) Call Mom @Phone\n"), 0o600))
+
+	err := UpdatePath(pathFile, func(tasklist *TaskList) error {
+		task, err := ParseTask("Pick up milk @GroceryStore")
+		require.NoError(t, err)
+
+		tasklist.AddTask(task)
+
+		return nil
+	})
+	require.NoError(t, err, "UpdatePath should not fail")
+
+	actualTasklist, err := LoadFromPath(pathFile)
+	require.NoError(t, err)
+
+	expect := "(A) Call Mom @Phone\nPick up milk @GroceryStore\n"
+	actual := actualTasklist.String()
+	require.Equal(t, expect, actual, "the task should be appended to the file")
+
+	info, err := os.Stat(pathFile)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "the permission of the file should be kept")
+}
+
+func TestUpdatePath_new_file(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+
+	err := UpdatePath(pathFile, func(tasklist *TaskList) error {
+		require.Empty(t, *tasklist, "non-existing file should be loaded as empty task list")
+
+		task, err := ParseTask("(B) New task")
+		require.NoError(t, err)
+
+		tasklist.AddTask(task)
+
+		return nil
+	})
+	require.NoError(t, err, "UpdatePath should create a non-existing file")
+
+	raw, err := os.ReadFile(pathFile)
+	require.NoError(t, err)
+	require.Equal(t, "(B) New task"+NewLine, string(raw))
+}
+
+func TestUpdatePath_update_error(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+	original := "(A) Call Mom @Phone\n"
+
+	require.NoError(t, os.WriteFile(pathFile, []byte(original), PermReadWrite))
+
+	errUpdate := errors.New("forced error")
+
+	err := UpdatePath(pathFile, func(tasklist *TaskList) error {
+		*tasklist = NewTaskList()
+
+		return errUpdate
+	})
+
+	require.Error(t, err, "error of the update function should be returned")
+	require.ErrorIs(t, err, errUpdate, "the returned error should wrap the original error")
+
+	raw, err := os.ReadFile(pathFile)
+	require.NoError(t, err)
+	require.Equal(t, original, string(raw), "the file should be untouched on error")
+}
+
+func TestUpdatePath_load_error(t *testing.T) {
+	t.Parallel()
+
+	called := false
+
+	err := UpdatePath(testCopyToTemp(t, testInputTasklistDueDateError), func(*TaskList) error {
+		called = true
+
+		return nil
+	})
+
+	require.Error(t, err, "invalid task file should return an error")
+	require.Contains(t, err.Error(), `parsing time "2014-02-32": day out of range`)
+	require.False(t, called, "update function should not be called on load error")
+}
+
+func TestUpdatePath_lock_error(t *testing.T) {
+	t.Parallel()
+
+	err := UpdatePath("/path/to/unknown/dir/todo.txt", func(*TaskList) error {
+		return nil
+	})
+
+	require.Error(t, err, "missing directory should fail to lock")
+	require.Contains(t, err.Error(), "lock file")
+}
+
+func TestUpdatePath_concurrent_writers(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+
+	const numWriters = 20
+
+	var waitGroup sync.WaitGroup
+
+	for range numWriters {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			err := UpdatePath(pathFile, func(tasklist *TaskList) error {
+				task := NewTask()
+				task.Todo = "concurrent task"
+
+				tasklist.AddTask(&task)
+
+				return nil
+			})
+			require.NoError(t, err)
+		}()
+	}
+
+	waitGroup.Wait()
+
+	tasklist, err := LoadFromPath(pathFile)
+	require.NoError(t, err)
+	require.Len(t, tasklist, numWriters, "none of the concurrent writes should be lost")
+	require.NoFileExists(t, pathFile+lockSuffix, "lock file should be removed once done")
+}
+
+// ----------------------------------------------------------------------------
+//  UpdateLinesPath()
+// ----------------------------------------------------------------------------
+
+func TestUpdateLinesPath(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+
+	require.NoError(t, os.WriteFile(pathFile, []byte("Call Mom\r\n\n# comment\nBuy milk\n"), 0o600))
+
+	err := UpdateLinesPath(pathFile, func(lines []string) ([]string, error) {
+		require.Equal(t, []string{"Call Mom", "", "# comment", "Buy milk"}, lines)
+
+		lines[0] = emptyStr
+
+		return lines, nil
+	})
+	require.NoError(t, err)
+
+	raw, err := os.ReadFile(pathFile)
+	require.NoError(t, err)
+	require.Equal(t, joinLines([]string{"", "", "# comment", "Buy milk"}), string(raw),
+		"blank lines should be kept")
+
+	// Non-existing file and error
+	pathFile = testGetPathFileTemp(t, testOutput)
+	errUpdate := errors.New("forced error")
+
+	err = UpdateLinesPath(pathFile, func(lines []string) ([]string, error) {
+		require.Empty(t, lines, "non-existing file should have no lines")
+
+		return []string{"New task"}, errUpdate
+	})
+	require.ErrorIs(t, err, errUpdate)
+
+	_, err = os.Stat(pathFile)
+	require.ErrorIs(t, err, os.ErrNotExist, "file should not be written on error")
+
+	err = UpdateLinesPath("/path/to/unknown/dir/todo.txt", func(lines []string) ([]string, error) {
+		return lines, nil
+	})
+	require.ErrorContains(t, err, "lock file")
+}
+
+// ----------------------------------------------------------------------------
+//  lockPath()
+// ----------------------------------------------------------------------------
+
+func Test_lockPath(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+	pathLock := pathFile + lockSuffix
+
+	unlock, err := lockPath(pathFile)
+	require.NoError(t, err)
+
+	acquired := make(chan func() error)
+
+	go func() {
+		unlockSecond, err := lockPath(pathFile)
+		if err == nil {
+			acquired <- unlockSecond
+		}
+	}()
+
+	require.NoError(t, unlock())
+
+	select {
+	case unlockSecond := <-acquired:
+		require.FileExists(t, pathLock, "waiter should lock the file at the path")
+		require.NoError(t, unlockSecond())
+	case <-time.After(5 * time.Second):
+		t.Fatal("the second lock should be acquired after the release")
+	}
+
+	require.NoFileExists(t, pathLock, "lock file should be removed on unlock")
+}
+
+func Test_lockPaths(t *testing.T) {
+	t.Parallel()
+
+	pathA := testGetPathFileTemp(t, testOutput)
+	pathB := pathA + ".other"
+
+	unlock, err := lockPaths(pathB, pathA, pathB)
+	require.NoError(t, err, "file given twice should be locked once")
+	require.FileExists(t, pathA+lockSuffix)
+	require.FileExists(t, pathB+lockSuffix)
+
+	acquired := make(chan func() error)
+
+	go func() {
+		unlockSecond, err := lockPaths(pathA, pathB)
+		if err == nil {
+			acquired <- unlockSecond
+		}
+	}()
+
+	require.NoError(t, unlock())
+
+	select {
+	case unlockSecond := <-acquired:
+		require.NoError(t, unlockSecond())
+	case <-time.After(5 * time.Second):
+		t.Fatal("the locks should be acquired after the release")
+	}
+
+	require.NoFileExists(t, pathA+lockSuffix)
+	require.NoFileExists(t, pathB+lockSuffix)
+
+	// Locks taken so far are released on error
+	_, err = lockPaths(pathA, filepath.Join(pathA, "unknown", "list.txt"))
+	require.Error(t, err)
+	require.NoFileExists(t, pathA+lockSuffix)
+}
+
+// ----------------------------------------------------------------------------
+//  lockWithFile()
+// ----------------------------------------------------------------------------
+
+func Test_lockWithFile(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+
+	unlock, err := lockWithFile(pathFile)
+	require.NoError(t, err, "failed to get the first lock")
+	require.FileExists(t, pathFile+lockSuffix, "lock file should be created")
+
+	acquired := make(chan struct{})
+
+	go func() {
+		unlockSecond, err := lockWithFile(pathFile)
+		if err == nil {
+			close(acquired)
+
+			_ = unlockSecond()
+		}
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("the second lock should wait until the first lock is released")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, unlock(), "failed to release the first lock")
+
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the second lock should be acquired after the release")
+	}
+}
+
+func Test_lockWithFile_stale_lock(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+	pathLock := pathFile + lockSuffix
+
+	require.NoError(t, os.WriteFile(pathLock, nil, PermReadWrite))
+
+	old := time.Now().Add(-2 * lockStaleAge)
+	require.NoError(t, os.Chtimes(pathLock, old, old))
+
+	unlock, err := lockWithFile(pathFile)
+	require.NoError(t, err, "abandoned lock file should be removed")
+
+	token, err := os.ReadFile(pathLock)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(token), strconv.Itoa(os.Getpid())+" "),
+		"lock file should hold the PID of its holder")
+
+	require.NoError(t, unlock())
+	require.NoFileExists(t, pathLock, "lock file should be removed on unlock")
+}
+
+func Test_lockWithFile_taken_over(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+	pathLock := pathFile + lockSuffix
+
+	unlock, err := lockWithFile(pathFile)
+	require.NoError(t, err)
+
+	// Removed as stale and taken by another process
+	require.NoError(t, os.WriteFile(pathLock, []byte(lockToken()), PermReadWrite))
+
+	require.ErrorContains(t, unlock(), "taken over")
+	require.FileExists(t, pathLock, "lock file of another process should be kept")
+}
+
+func Test_breakStaleLock(t *testing.T) {
+	t.Parallel()
+
+	pathLock := testGetPathFileTemp(t, testOutput) + lockSuffix
+	pathBreak := pathLock + lockBreakSuffix
+	old := time.Now().Add(-2 * lockStaleAge)
+
+	require.False(t, breakStaleLock(pathLock), "missing lock file should not be stale")
+
+	require.NoError(t, os.WriteFile(pathLock, []byte(lockToken()), PermReadWrite))
+	require.False(t, breakStaleLock(pathLock), "refreshed lock file should be kept")
+
+	require.NoError(t, os.Chtimes(pathLock, old, old))
+
+	// Another process is removing it
+	require.NoError(t, os.WriteFile(pathBreak, nil, PermReadWrite))
+	require.False(t, breakStaleLock(pathLock))
+	require.FileExists(t, pathLock)
+
+	// ... and crashed meanwhile
+	require.NoError(t, os.Chtimes(pathBreak, old, old))
+	require.False(t, breakStaleLock(pathLock))
+	require.NoFileExists(t, pathBreak, "abandoned break file should be removed")
+
+	require.True(t, breakStaleLock(pathLock))
+	require.NoFileExists(t, pathLock)
+	require.NoFileExists(t, pathBreak)
+}
+
+func Test_lockWithFile_error(t *testing.T) {
+	t.Parallel()
+
+	unlock, err := lockWithFile("/path/to/unknown/dir/todo.txt")
+
+	require.Error(t, err, "missing directory should fail to create the lock file")
+	require.Nil(t, unlock)
+}
+
+// ----------------------------------------------------------------------------
+//  writeFileAtomic()
+// ----------------------------------------------------------------------------
+
+func Test_writeFileAtomic_error(t *testing.T) {
+	t.Parallel()
+
+	err := writeFileAtomic("/path/to/unknown/dir/todo.txt", []byte("task"))
+
+	require.Error(t, err, "missing directory should fail to create temporary file")
+	require.Contains(t, err.Error(), "failed to create temporary file")
+}
@@ -0,0 +1,276 @@
+package todo
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: MarkdownGroupBy
+// ----------------------------------------------------------------------------
+
+// MarkdownGroupBy represents how WriteMarkdown groups the tasks under headings.
+type MarkdownGroupBy string
+
+// Groupings of the Markdown format.
+const (
+	MarkdownGroupNone    MarkdownGroupBy = ""        // A single checklist without headings.
+	MarkdownGroupProject MarkdownGroupBy = "project" // A heading per project, such as "## +Family".
+	MarkdownGroupContext MarkdownGroupBy = "context" // A heading per context, such as "## @Phone".
+)
+
+// markdownHeadingLevel is the default level of the group headings.
+const markdownHeadingLevel = 2
+
+var (
+	// rxMarkdownItem matches a checklist item, such as "- [x] text" or "1. [ ] text".
+	rxMarkdownItem = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+\[([ xX])\]\s+(.*)$`)
+	// rxMarkdownHeading matches an ATX heading, such as "## text ##".
+	rxMarkdownHeading = regexp.MustCompile(`^ {0,3}#{1,6}(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	// rxMarkdownFence matches the opening and closing lines of a code block.
+	rxMarkdownFence = regexp.MustCompile("^ {0,3}(```|~~~)")
+)
+
+// ----------------------------------------------------------------------------
+//  Type: MarkdownOptions
+// ----------------------------------------------------------------------------
+
+// MarkdownOptions are the options of the Markdown format. The zero value is a
+// single checklist on writing, and uses the headings as projects on reading.
+type MarkdownOptions struct {
+	// GroupBy groups the tasks under a heading per project or context on
+	// writing. Each task is listed once, under its first project or context,
+	// and the tasks without any are listed first, before the headings.
+	GroupBy MarkdownGroupBy
+	// HeadingLevel is the level of the group headings, from 1 to 6. Defaults
+	// to 2 ("##").
+	HeadingLevel int
+	// IgnoreHeadings does not add the heading above an item to the task on
+	// reading.
+	IgnoreHeadings bool
+}
+
+// headingPrefix returns the prefix of the group headings, such as "## ".
+func (opts MarkdownOptions) headingPrefix() string {
+	level := opts.HeadingLevel
+	if level < 1 || level > 6 {
+		level = markdownHeadingLevel
+	}
+
+	return strings.Repeat("#", level) + " "
+}
+
+// ----------------------------------------------------------------------------
+//  Public functions
+// ----------------------------------------------------------------------------
+
+// LoadFromMarkdown loads a TaskList from the checklist items of a Markdown
+// document, such as "- [ ] (A) Call Mom @Phone" or "- [x] Learn Go". Other
+// lines, including the ones in fenced code blocks, are ignored.
+//
+// The text after the checkbox is parsed as a todo.txt line and the checkbox
+// tells whether the task is completed. The leading "x" of an unchecked item,
+// unless followed by the completed date, is kept in the text escaped with a
+// backslash, as in "\x marks the spot". Unless IgnoreHeadings is set, the
+// nearest heading above an item is added to the task: as a context if it starts
+// with "@", otherwise as a project. Whitespaces and colons of the heading are
+// replaced by "_". Tasks get their position in the list as ID, starting from 1.
+func LoadFromMarkdown(reader io.Reader, opts MarkdownOptions) (TaskList, error) {
+	tasklist := NewTaskList()
+	scanner := bufio.NewScanner(reader)
+	heading := emptyStr
+	fence := emptyStr
+	lineNum := 0
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNum++
+
+		if match := rxMarkdownFence.FindStringSubmatch(line); match != nil {
+			switch {
+			case isEmpty(fence):
+				fence = match[1]
+			case fence == match[1]:
+				fence = emptyStr
+			}
+
+			continue
+		}
+
+		if isNotEmpty(fence) {
+			continue
+		}
+
+		if match := rxMarkdownHeading.FindStringSubmatch(line); match != nil {
+			heading = markdownHeadingWord(match[1])
+
+			continue
+		}
+
+		match := rxMarkdownItem.FindStringSubmatch(line)
+		if match == nil || isEmpty(strings.TrimSpace(match[2])) {
+			continue
+		}
+
+		if opts.IgnoreHeadings {
+			heading = emptyStr
+		}
+
+		task, err := markdownToTask(match[2], match[1] != " ", heading)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineNum)
+		}
+
+		task.ID = len(tasklist) + 1
+		tasklist = append(tasklist, *task)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read Markdown")
+	}
+
+	return tasklist, nil
+}
+
+// ----------------------------------------------------------------------------
+//  TaskList.WriteMarkdown()
+// ----------------------------------------------------------------------------
+
+// WriteMarkdown writes the TaskList as a GitHub-flavoured Markdown checklist,
+// such as "- [ ] (A) Call Mom @Phone" and "- [x] 2024-01-03 Learn Go". The
+// text after the checkbox is the todo.txt line without the "x " marker, so the
+// checklist reads back as the same tasks with LoadFromMarkdown. See
+// MarkdownOptions for the grouping.
+func (tasklist *TaskList) WriteMarkdown(writer io.Writer, opts MarkdownOptions) error {
+	var groupOf func(task *Task) []string
+
+	switch opts.GroupBy {
+	case MarkdownGroupNone:
+	case MarkdownGroupProject:
+		groupOf = func(task *Task) []string { return prefixAll(task.Projects, "+") }
+	case MarkdownGroupContext:
+		groupOf = func(task *Task) []string { return prefixAll(task.Contexts, "@") }
+	default:
+		return errors.New("unknown Markdown grouping: " + string(opts.GroupBy))
+	}
+
+	ungrouped := []*Task{}
+	groups := map[string][]*Task{}
+	names := map[string]bool{}
+
+	for i := range *tasklist {
+		task := &(*tasklist)[i]
+
+		var keys []string
+		if groupOf != nil {
+			keys = groupOf(task)
+		}
+
+		if len(keys) == 0 {
+			ungrouped = append(ungrouped, task)
+
+			continue
+		}
+
+		groups[keys[0]] = append(groups[keys[0]], task)
+		names[keys[0]] = true
+	}
+
+	var builder strings.Builder
+
+	writeMarkdownItems(&builder, ungrouped)
+
+	for _, name := range sortedKeys(names) {
+		if builder.Len() > 0 {
+			builder.WriteString(NewLine)
+		}
+
+		builder.WriteString(opts.headingPrefix() + name + NewLine + NewLine)
+		writeMarkdownItems(&builder, groups[name])
+	}
+
+	_, err := io.WriteString(writer, builder.String())
+
+	return errors.Wrap(err, "failed to write Markdown")
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// markdownHeadingWord returns the heading text as a context ("@word") or a
+// project ("+word") to add to the tasks under the heading.
+func markdownHeadingWord(text string) string {
+	text = strings.TrimSpace(text)
+
+	prefix := "+"
+	if strings.HasPrefix(text, "@") {
+		prefix = "@"
+	}
+
+	word := sanitizeWord(strings.TrimLeft(text, "+@"))
+	if isEmpty(word) {
+		return emptyStr
+	}
+
+	return prefix + word
+}
+
+// markdownToTask parses the text of a checklist item, adding the heading word.
+// An unchecked item of a completed task, with the "x" and the completed date,
+// is reopened. The leading "x" of another unchecked item, such as "x marks the
+// spot", is escaped to be kept in the text.
+func markdownToTask(text string, checked bool, heading string) (*Task, error) {
+	text = strings.TrimSpace(text)
+
+	switch {
+	case checked && !strings.HasPrefix(text, "x "):
+		text = "x " + text
+	case !checked && completedRx.MatchString(text) && !completedDateRx.MatchString(text):
+		text = escapeLeadingMarker(text)
+	}
+
+	if isNotEmpty(heading) {
+		text += " " + heading
+	}
+
+	task, err := ParseTask(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if !checked && task.Completed {
+		task.Reopen()
+	}
+
+	return task, nil
+}
+
+// prefixAll returns the items with the prefix.
+func prefixAll(items []string, prefix string) []string {
+	prefixed := make([]string, len(items))
+	for i, item := range items {
+		prefixed[i] = prefix + item
+	}
+
+	return prefixed
+}
+
+// writeMarkdownItems writes the tasks as checklist items.
+func writeMarkdownItems(builder *strings.Builder, tasks []*Task) {
+	for _, task := range tasks {
+		checkbox := "- [ ] "
+		line := task.String()
+
+		if task.Completed {
+			checkbox = "- [x] "
+			line = strings.TrimPrefix(line, "x ")
+		}
+
+		builder.WriteString(checkbox + line + NewLine)
+	}
+}
@@ -0,0 +1,169 @@
+package todo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskList_WriteMarkdown(t *testing.T) {
+	t.Parallel()
+
+	tasklist := testMustLoad(t, `(A) 2024-01-01 Call Mom @Phone +Family
+x 2024-01-03 2024-01-02 Learn Go +Study +Work
+Read book
+Fix fence @Home +Family
+`)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, tasklist.WriteMarkdown(&buf, MarkdownOptions{}))
+
+	expect := `- [ ] (A) 2024-01-01 Call Mom @Phone +Family
+- [x] 2024-01-03 2024-01-02 Learn Go +Study +Work
+- [ ] Read book
+- [ ] Fix fence @Home +Family
+`
+	require.Equal(t, strings.ReplaceAll(expect, "\n", NewLine), buf.String())
+
+	// Grouped by project
+	buf.Reset()
+
+	require.NoError(t, tasklist.WriteMarkdown(&buf, MarkdownOptions{GroupBy: MarkdownGroupProject}))
+
+	expect = `- [ ] Read book
+
+## +Family
+
+- [ ] (A) 2024-01-01 Call Mom @Phone +Family
+- [ ] Fix fence @Home +Family
+
+## +Study
+
+- [x] 2024-01-03 2024-01-02 Learn Go +Study +Work
+`
+	require.Equal(t, strings.ReplaceAll(expect, "\n", NewLine), buf.String(), "tasks should be listed once")
+
+	// Grouped by context
+	buf.Reset()
+
+	require.NoError(t, tasklist.WriteMarkdown(&buf, MarkdownOptions{GroupBy: MarkdownGroupContext, HeadingLevel: 3}))
+
+	expect = `- [x] 2024-01-03 2024-01-02 Learn Go +Study +Work
+- [ ] Read book
+
+### @Home
+
+- [ ] Fix fence @Home +Family
+
+### @Phone
+
+- [ ] (A) 2024-01-01 Call Mom @Phone +Family
+`
+	require.Equal(t, strings.ReplaceAll(expect, "\n", NewLine), buf.String())
+
+	err := tasklist.WriteMarkdown(&buf, MarkdownOptions{GroupBy: "unknown"})
+	require.ErrorContains(t, err, "unknown Markdown grouping: unknown")
+}
+
+func TestLoadFromMarkdown_round_trip(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{testInputTask, testInputSort, testInputFilter, testInputTasklist} {
+		expect, err := LoadFromPath(path)
+		require.NoError(t, err)
+
+		for _, groupBy := range []MarkdownGroupBy{MarkdownGroupNone, MarkdownGroupProject, MarkdownGroupContext} {
+			var buf bytes.Buffer
+
+			require.NoError(t, expect.WriteMarkdown(&buf, MarkdownOptions{GroupBy: groupBy}))
+
+			actual, err := LoadFromMarkdown(&buf, MarkdownOptions{})
+			require.NoError(t, err)
+			require.Len(t, actual, len(expect))
+
+			if groupBy == MarkdownGroupNone {
+				require.Equal(t, expect.String(), actual.String(), "%s should round-trip", path)
+
+				continue
+			}
+
+			// Grouping changes the order of the tasks
+			for i := range actual {
+				found := false
+
+				for j := range expect {
+					found = found || expect[j].String() == actual[i].String()
+				}
+
+				require.True(t, found, "%s: task %q should round-trip", path, actual[i].String())
+			}
+		}
+	}
+}
+
+func TestLoadFromMarkdown(t *testing.T) {
+	t.Parallel()
+
+	input := "# Release plan\n" +
+		"\n" +
+		"Some notes with a - [ ] not at the start.\n" +
+		"\n" +
+		"- [ ] (A) Write changelog due:2024-01-05\n" +
+		"* [X] 2024-01-03 Tag version\n" +
+		"\n" +
+		"## Website redesign ##\n" +
+		"\n" +
+		"1. [ ] Update logo +Brand\n" +
+		"   - [x] Pick colours\n" +
+		"- [ ] x 2024-01-03 Reopened task\n" +
+		"- [ ]\n" +
+		"- regular item\n" +
+		"\n" +
+		"```markdown\n" +
+		"- [ ] Example in code\n" +
+		"```\n" +
+		"\n" +
+		"## @Phone\n" +
+		"\n" +
+		"+ [ ] Call Mom\n"
+
+	tasklist, err := LoadFromMarkdown(strings.NewReader(input), MarkdownOptions{})
+	require.NoError(t, err)
+
+	expect := `(A) Write changelog +Release_plan due:2024-01-05
+x 2024-01-03 Tag version +Release_plan
+Update logo +Brand +Website_redesign
+x Pick colours +Website_redesign
+Reopened task +Website_redesign
+Call Mom @Phone
+`
+	require.Equal(t, strings.ReplaceAll(expect, "\n", NewLine), tasklist.String())
+	require.Equal(t, 6, tasklist[5].ID)
+
+	// Leading "x" of an unchecked item
+	tasklist, err = LoadFromMarkdown(strings.NewReader("- [ ] x marks the spot\n- [x] Found it\n"), MarkdownOptions{})
+	require.NoError(t, err)
+	require.False(t, tasklist[0].Completed, "text should not be read as the completion mark")
+	require.Equal(t, `\x marks the spot`, tasklist[0].String())
+
+	var buf bytes.Buffer
+
+	require.NoError(t, tasklist.WriteMarkdown(&buf, MarkdownOptions{}))
+
+	reloaded, err := LoadFromMarkdown(&buf, MarkdownOptions{})
+	require.NoError(t, err)
+	require.Equal(t, tasklist.String(), reloaded.String(), "escaped text should round-trip")
+
+	// Without headings
+	tasklist, err = LoadFromMarkdown(strings.NewReader(input), MarkdownOptions{IgnoreHeadings: true})
+	require.NoError(t, err)
+	require.Equal(t, "Write changelog", tasklist[0].Todo)
+	require.Empty(t, tasklist[0].Projects)
+
+	// Errors
+	_, err = LoadFromMarkdown(strings.NewReader("# List\n- [ ] 2024-02-30 Invalid date\n"), MarkdownOptions{})
+	require.ErrorContains(t, err, "line 2")
+}
@@ -0,0 +1,585 @@
+package todo
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: MergeConflict
+// ----------------------------------------------------------------------------
+
+// MergeConflict represents a task that could not be merged automatically.
+//
+// Ours and Theirs are the versions of the task from the respective list. One
+// of them is nil if the task was deleted on that side while it was modified on
+// the other side. Base is nil if the task was added on both sides.
+type MergeConflict struct {
+	Base   *Task       // Base version of the task.
+	Ours   *Task       // Ours version of the task.
+	Theirs *Task       // Theirs version of the task.
+	Fields []TaskField // Fields changed differently on both sides. Empty on delete/modify conflicts.
+	Index  int         // Index of the kept task in MergeResult.Merged.
+}
+
+// ----------------------------------------------------------------------------
+//  Type: MergeResult
+// ----------------------------------------------------------------------------
+
+// MergeResult is the result of a three-way merge.
+type MergeResult struct {
+	// Merged is the merged TaskList. For conflicting fields the value of "ours"
+	// is used, and a task modified on one side but deleted on the other is kept.
+	Merged TaskList
+	// Conflicts are the tasks that need to be resolved by the user.
+	Conflicts []MergeConflict
+}
+
+// HasConflicts returns true if the merge has any conflict.
+func (result MergeResult) HasConflicts() bool {
+	return len(result.Conflicts) > 0
+}
+
+// String returns the merged list in todo.txt format.
+//
+// Conflicting tasks are surrounded by comment lines similar to the conflict
+// markers of git, followed by the alternative version of "theirs". Since the
+// markers are comments, the output can be loaded as it is (if IgnoreComments
+// is 'true'), but both versions of a conflicting task will be present.
+//
+//	# <<<<<<< ours
+//	(A) Call Mom
+//	# =======
+//	(B) Call Mom
+//	# >>>>>>> theirs
+func (result MergeResult) String() string {
+	conflicts := result.conflictsByIndex()
+
+	var strBldr strings.Builder
+
+	for i := range result.Merged {
+		for _, line := range result.lines(i, conflicts) {
+			strBldr.WriteString(line + NewLine)
+		}
+	}
+
+	return strBldr.String()
+}
+
+// alternatives returns both versions of the conflicting task based on the kept
+// task. nil is returned for the deleted side.
+func (conflict MergeConflict) alternatives(kept Task) (*Task, *Task) {
+	if conflict.Ours == nil {
+		return nil, &kept
+	}
+
+	if conflict.Theirs == nil {
+		return &kept, nil
+	}
+
+	theirs := cloneTask(kept)
+
+	for _, field := range conflict.Fields {
+		copyField(&theirs, conflict.Theirs, field)
+	}
+
+	return &kept, &theirs
+}
+
+// ----------------------------------------------------------------------------
+//  Public functions
+// ----------------------------------------------------------------------------
+
+// Merge performs a three-way merge of two TaskLists ("ours" and "theirs") that
+// were both derived from a common ancestor ("base").
+//
+// Tasks are matched by their UIDTag, their text or a fuzzy similarity of their
+// text. Changes of matched tasks are merged field by field: for example, if
+// one side completed a task while the other side changed its priority, both
+// changes are applied. Contexts, projects and additional tags are merged per
+// item. A field changed differently on both sides, or a task deleted on one
+// side but modified on the other, is reported as a conflict.
+//
+// The merged list keeps the order of "ours", followed by the tasks added in
+// "theirs". The task IDs are renumbered from 1.
+func Merge(base, ours, theirs TaskList) MergeResult {
+	result, _ := merge(base, ours, theirs)
+
+	return result
+}
+
+// MergeFiles performs a three-way merge of todo.txt files and writes the result
+// to oursPath. It is compatible with a git merge driver, which is expected to
+// leave the result in the file of "ours" (%A).
+//
+// The tasks are merged as by Merge. The other lines, blank lines and comments
+// (if IgnoreComments is 'true'), are merged line by line: the file keeps the
+// lines of "ours" in place, without the comments removed in "theirs", followed
+// by the tasks and then the comments added in "theirs".
+//
+// If the merge has conflicts, the conflicting tasks are surrounded by conflict
+// markers as described in MergeResult.String() and the returned MergeResult
+// reports the conflicts.
+//
+// To use it as a merge driver, see the "todotxt-merge" command.
+func MergeFiles(basePath, oursPath, theirsPath string) (MergeResult, error) {
+	files := make([][]string, 3)
+	lists := make([]TaskList, 3)
+
+	for i, path := range []string{basePath, oursPath, theirsPath} {
+		raw, err := os.ReadFile(path) //nolint:gosec // paths are provided by user, same as LoadFromPath
+		if err != nil {
+			return MergeResult{}, errors.Wrap(err, "failed to open file: "+path)
+		}
+
+		lines := splitLines(raw)
+
+		tasklist, err := LoadFromString(strings.Join(lines, "\n"))
+		if err != nil {
+			return MergeResult{}, err
+		}
+
+		files[i], lists[i] = lines, tasklist
+	}
+
+	result, origins := merge(lists[0], lists[1], lists[2])
+	merged := result.mergeLines(files[0], files[1], files[2], origins)
+
+	return result, writeFileAtomic(oursPath, []byte(joinLines(merged)))
+}
+
+// ----------------------------------------------------------------------------
+//  Private methods
+// ----------------------------------------------------------------------------
+
+// add appends the task to the merged list and records the conflict if any.
+func (result *MergeResult) add(task Task, conflict *MergeConflict) {
+	if conflict != nil {
+		conflict.Index = len(result.Merged)
+		result.Conflicts = append(result.Conflicts, *conflict)
+	}
+
+	result.Merged = append(result.Merged, task)
+}
+
+// mergeDeleted handles a task deleted on one side. The task is removed if the
+// other side did not change it, otherwise it is kept as a conflict.
+func (result *MergeResult) mergeDeleted(base, ours, theirs *Task) {
+	kept := ours
+	if kept == nil {
+		kept = theirs
+	}
+
+	if kept.String() == base.String() {
+		return
+	}
+
+	result.add(cloneTask(*kept), &MergeConflict{
+		Base:   base,
+		Ours:   ours,
+		Theirs: theirs,
+		Fields: nil,
+		Index:  0,
+	})
+}
+
+// merge3 merges the changes of ours and theirs field by field. A nil base is
+// treated as an empty task.
+func (result *MergeResult) merge3(base, ours, theirs *Task) {
+	baseTask := new(Task)
+	if base != nil {
+		baseTask = base
+	}
+
+	merged := cloneTask(*ours)
+
+	var conflicts []TaskField
+
+	for _, scalar := range scalarFields {
+		valBase, valOurs, valTheirs := scalar.get(baseTask), scalar.get(ours), scalar.get(theirs)
+
+		switch {
+		case valOurs == valTheirs, valTheirs == valBase:
+			// keep ours
+		case valOurs == valBase:
+			scalar.set(&merged, theirs)
+		default:
+			conflicts = append(conflicts, scalar.field)
+		}
+	}
+
+	merged.Contexts = mergeSets(baseTask.Contexts, ours.Contexts, theirs.Contexts)
+	merged.Projects = mergeSets(baseTask.Projects, ours.Projects, theirs.Projects)
+
+	tags, conflictTags := mergeTags(baseTask.AdditionalTags, ours.AdditionalTags, theirs.AdditionalTags)
+	merged.AdditionalTags = tags
+
+	if conflictTags {
+		conflicts = append(conflicts, FieldTags)
+	}
+
+	if merged.String() != ours.String() {
+		merged.Original = merged.String()
+	}
+
+	if len(conflicts) == 0 {
+		result.add(merged, nil)
+
+		return
+	}
+
+	result.add(merged, &MergeConflict{
+		Base:   base,
+		Ours:   ours,
+		Theirs: theirs,
+		Fields: conflicts,
+		Index:  0,
+	})
+}
+
+// conflictsByIndex returns the conflicts by the index of their merged task.
+func (result MergeResult) conflictsByIndex() map[int]MergeConflict {
+	conflicts := make(map[int]MergeConflict, len(result.Conflicts))
+	for _, conflict := range result.Conflicts {
+		conflicts[conflict.Index] = conflict
+	}
+
+	return conflicts
+}
+
+// lines returns the lines of the merged task of the index, surrounded by the
+// conflict markers if it is conflicting.
+func (result MergeResult) lines(index int, conflicts map[int]MergeConflict) []string {
+	task := result.Merged[index]
+
+	conflict, found := conflicts[index]
+	if !found {
+		return []string{task.String()}
+	}
+
+	ours, theirs := conflict.alternatives(task)
+	lines := []string{"# <<<<<<< ours"}
+
+	if ours != nil {
+		lines = append(lines, ours.String())
+	}
+
+	lines = append(lines, "# =======")
+
+	if theirs != nil {
+		lines = append(lines, theirs.String())
+	}
+
+	return append(lines, "# >>>>>>> theirs")
+}
+
+// mergeLines returns the lines of the merged file from the lines of the files
+// and the origins of the merged tasks returned by merge.
+func (result MergeResult) mergeLines(base, ours, theirs []string, origins []int) []string {
+	conflicts := result.conflictsByIndex()
+	mergedOf := make(map[int]int, len(origins))
+
+	for i, j := range origins {
+		if j >= 0 {
+			mergedOf[j] = i
+		}
+	}
+
+	inBase, inOurs, inTheirs := commentSet(base), commentSet(ours), commentSet(theirs)
+	merged := []string{}
+	taskIdx := 0
+
+	for _, line := range ours {
+		text := strings.Trim(line, whitespaces)
+
+		switch {
+		case isEmpty(text):
+			merged = append(merged, line)
+		case isComment(text):
+			if !inBase[text] || inTheirs[text] {
+				merged = append(merged, line) // not removed in theirs
+			}
+		default:
+			if i, found := mergedOf[taskIdx]; found {
+				merged = append(merged, result.lines(i, conflicts)...)
+			}
+
+			taskIdx++
+		}
+	}
+
+	for i, j := range origins {
+		if j < 0 {
+			merged = append(merged, result.lines(i, conflicts)...)
+		}
+	}
+
+	for _, line := range theirs {
+		text := strings.Trim(line, whitespaces)
+		if isComment(text) && !inBase[text] && !inOurs[text] {
+			merged = append(merged, line)
+			inOurs[text] = true // added once
+		}
+	}
+
+	return merged
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// commentSet returns the comment lines, trimmed, as a set.
+func commentSet(lines []string) map[string]bool {
+	set := map[string]bool{}
+
+	for _, line := range lines {
+		if text := strings.Trim(line, whitespaces); isComment(text) {
+			set[text] = true
+		}
+	}
+
+	return set
+}
+
+// copyField copies the given field from src to dst.
+func copyField(dst, src *Task, field TaskField) {
+	for _, scalar := range scalarFields {
+		if scalar.field == field {
+			scalar.set(dst, src)
+		}
+	}
+
+	switch field {
+	case FieldContexts:
+		dst.Contexts = append([]string{}, src.Contexts...)
+	case FieldProjects:
+		dst.Projects = append([]string{}, src.Projects...)
+	case FieldTags:
+		dst.AdditionalTags = cloneTask(*src).AdditionalTags
+	case FieldCompleted, FieldPriority, FieldCreatedDate, FieldTodo, FieldDueDate:
+		// already copied as scalar field
+	}
+}
+
+// invertMatches inverts the result of matchTasks.
+func invertMatches(matches []int, size int) []int {
+	inverted := make([]int, size)
+	for i := range inverted {
+		inverted[i] = -1
+	}
+
+	for i, j := range matches {
+		if j >= 0 {
+			inverted[j] = i
+		}
+	}
+
+	return inverted
+}
+
+// isComment returns true if the trimmed line is a comment, which is not a task
+// if IgnoreComments is 'true'.
+func isComment(text string) bool {
+	return IgnoreComments && strings.HasPrefix(text, "#")
+}
+
+// merge is Merge, also returning for each merged task the index of the task in
+// ours it comes from, or -1 for the tasks added in theirs.
+func merge(base, ours, theirs TaskList) (MergeResult, []int) {
+	oursToBase := matchTasks(ours, base)
+	theirsToBase := matchTasks(theirs, base)
+	baseToTheirs := invertMatches(theirsToBase, len(base))
+	baseToOurs := invertMatches(oursToBase, len(base))
+
+	result := MergeResult{Merged: TaskList{}}
+
+	// Tasks added on both sides are merged against an empty base. Fuzzy matching
+	// is not used here since they are likely different tasks.
+	addedOurs, addedTheirs := unmatchedIndexes(oursToBase), unmatchedIndexes(theirsToBase)
+	addedPairs, _ := matchTasksExact(pick(theirs, addedTheirs), pick(ours, addedOurs))
+	addedOursToTheirs := map[int]int{}
+	addedInBoth := map[int]bool{}
+
+	for i, j := range addedPairs {
+		if j >= 0 {
+			addedOursToTheirs[addedOurs[j]] = addedTheirs[i]
+			addedInBoth[addedTheirs[i]] = true
+		}
+	}
+
+	origins := []int{}
+
+	for j := range ours {
+		baseIdx := oursToBase[j]
+		count := len(result.Merged)
+
+		switch {
+		case baseIdx < 0:
+			if k, found := addedOursToTheirs[j]; found {
+				result.merge3(nil, &ours[j], &theirs[k])
+			} else {
+				result.add(cloneTask(ours[j]), nil)
+			}
+		case baseToTheirs[baseIdx] < 0:
+			result.mergeDeleted(&base[baseIdx], &ours[j], nil)
+		default:
+			result.merge3(&base[baseIdx], &ours[j], &theirs[baseToTheirs[baseIdx]])
+		}
+
+		if len(result.Merged) > count {
+			origins = append(origins, j)
+		}
+	}
+
+	for k := range theirs {
+		baseIdx := theirsToBase[k]
+		count := len(result.Merged)
+
+		switch {
+		case baseIdx >= 0 && baseToOurs[baseIdx] < 0:
+			result.mergeDeleted(&base[baseIdx], nil, &theirs[k])
+		case baseIdx < 0 && !addedInBoth[k]:
+			result.add(cloneTask(theirs[k]), nil)
+		}
+
+		if len(result.Merged) > count {
+			origins = append(origins, -1)
+		}
+	}
+
+	for i := range result.Merged {
+		result.Merged[i].ID = i + 1
+	}
+
+	return result, origins
+}
+
+// mergeSets merges the items of ours and theirs. An item is kept if it was
+// added on either side and not removed on either side.
+func mergeSets(base, ours, theirs []string) []string {
+	inBase, inOurs, inTheirs := toSet(base), toSet(ours), toSet(theirs)
+
+	var merged []string
+
+	for _, item := range sortedKeys(inBase, inOurs, inTheirs) {
+		if merge3Bool(inBase[item], inOurs[item], inTheirs[item]) {
+			merged = append(merged, item)
+		}
+	}
+
+	return merged
+}
+
+// mergeTags merges the additional tags key by key. It returns true if any key
+// was changed differently on both sides, in which case the value of ours is
+// kept.
+func mergeTags(base, ours, theirs map[string]string) (map[string]string, bool) {
+	keys := sortedKeys(tagKeys(base), tagKeys(ours), tagKeys(theirs))
+	merged := make(map[string]string, len(keys))
+	conflict := false
+
+	for _, key := range keys {
+		valBase, inBase := base[key]
+		valOurs, inOurs := ours[key]
+		valTheirs, inTheirs := theirs[key]
+
+		switch {
+		case inOurs == inTheirs && valOurs == valTheirs, inTheirs == inBase && valTheirs == valBase:
+			if inOurs {
+				merged[key] = valOurs
+			}
+		case inOurs == inBase && valOurs == valBase:
+			if inTheirs {
+				merged[key] = valTheirs
+			}
+		default:
+			conflict = true
+
+			if inOurs {
+				merged[key] = valOurs
+			}
+		}
+	}
+
+	if len(merged) == 0 {
+		merged = nil
+	}
+
+	return merged, conflict
+}
+
+// merge3Bool merges a boolean value. The side which differs from base wins.
+func merge3Bool(base, ours, theirs bool) bool {
+	if ours == base {
+		return theirs
+	}
+
+	return ours
+}
+
+// pick returns the tasks of the given indexes.
+func pick(tasklist TaskList, indexes []int) TaskList {
+	picked := make(TaskList, len(indexes))
+	for i, index := range indexes {
+		picked[i] = tasklist[index]
+	}
+
+	return picked
+}
+
+// sortedKeys returns the sorted union of the keys of the given sets.
+func sortedKeys(sets ...map[string]bool) []string {
+	union := map[string]bool{}
+
+	for _, set := range sets {
+		for key := range set {
+			union[key] = true
+		}
+	}
+
+	keys := make([]string, 0, len(union))
+	for key := range union {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// tagKeys returns the keys of the tags as a set.
+func tagKeys(tags map[string]string) map[string]bool {
+	set := make(map[string]bool, len(tags))
+	for key := range tags {
+		set[key] = true
+	}
+
+	return set
+}
+
+// toSet converts the slice to a set.
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+
+	return set
+}
+
+// unmatchedIndexes returns the indexes without a counterpart.
+func unmatchedIndexes(matches []int) []int {
+	var unmatched []int
+
+	for i, j := range matches {
+		if j < 0 {
+			unmatched = append(unmatched, i)
+		}
+	}
+
+	return unmatched
+}
@@ -0,0 +1,260 @@
+package todo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testMustLoad loads the TaskList from the given string or fails the test.
+func testMustLoad(t *testing.T, str string) TaskList {
+	t.Helper()
+
+	tasklist, err := LoadFromString(str)
+	require.NoError(t, err, "failed to load tasklist during test setup")
+
+	return tasklist
+}
+
+// ----------------------------------------------------------------------------
+//  Merge()
+// ----------------------------------------------------------------------------
+
+func TestMerge_field_level_changes(t *testing.T) {
+	t.Parallel()
+
+	base := testMustLoad(t, `
+		(B) Call Mom @Phone +Family
+		Pick up milk @GroceryStore
+		Plan backyard herb garden @Home
+	`)
+	// Ours completed a task and added a context
+	ours := testMustLoad(t, `
+		x 2024-01-02 (B) Call Mom @Phone +Family
+		Pick up milk @GroceryStore @Town
+		Plan backyard herb garden @Home
+	`)
+	// Theirs changed the priority and added a due date
+	theirs := testMustLoad(t, `
+		(A) Call Mom @Phone +Family
+		Pick up milk @GroceryStore due:2024-01-10
+		Plan backyard herb garden @Home
+	`)
+
+	result := Merge(base, ours, theirs)
+
+	require.False(t, result.HasConflicts(), "field level changes should merge cleanly")
+	checkTaskListOrder(t, result.Merged, []string{
+		"x 2024-01-02 (A) Call Mom @Phone +Family",
+		"Pick up milk @GroceryStore @Town due:2024-01-10",
+		"Plan backyard herb garden @Home",
+	})
+
+	for i, task := range result.Merged {
+		require.Equal(t, i+1, task.ID, "IDs should be renumbered")
+	}
+}
+
+func TestMerge_added_and_deleted(t *testing.T) {
+	t.Parallel()
+
+	base := testMustLoad(t, `
+		Task one
+		Task two
+		Task three
+	`)
+	ours := testMustLoad(t, `
+		Task one
+		Task three
+		Ours new task
+		Both new task +Ours
+	`)
+	theirs := testMustLoad(t, `
+		Task one
+		Task two
+		Theirs new task
+		Both new task @Theirs
+	`)
+
+	result := Merge(base, ours, theirs)
+
+	require.False(t, result.HasConflicts(), "additions and unchanged deletions should merge cleanly")
+	checkTaskListOrder(t, result.Merged, []string{
+		"Task one",
+		"Ours new task",
+		"Both new task @Theirs +Ours",
+		"Theirs new task",
+	})
+}
+
+func TestMerge_match_by_uid_and_fuzzy_text(t *testing.T) {
+	t.Parallel()
+
+	base := testMustLoad(t, `
+		Write report uid:1
+		Organize the annual company picnic
+	`)
+	ours := testMustLoad(t, `
+		Write the quarterly report uid:1
+		Organize the annual company picnic!
+	`)
+	theirs := testMustLoad(t, `
+		(A) Write report uid:1
+		(C) Organize the annual company picnic
+	`)
+
+	result := Merge(base, ours, theirs)
+
+	require.False(t, result.HasConflicts())
+	checkTaskListOrder(t, result.Merged, []string{
+		"(A) Write the quarterly report uid:1",
+		"(C) Organize the annual company picnic!",
+	})
+}
+
+func TestMerge_conflicts(t *testing.T) {
+	t.Parallel()
+
+	base := testMustLoad(t, `
+		(B) Call Mom key:base
+		Pick up milk
+		Plan backyard herb garden
+	`)
+	ours := testMustLoad(t, `
+		(A) Call Mom key:ours
+		Pick up milk @Town
+	`)
+	theirs := testMustLoad(t, `
+		(C) Call Mom key:theirs
+		Plan backyard herb garden @Home
+	`)
+
+	result := Merge(base, ours, theirs)
+
+	require.True(t, result.HasConflicts())
+	require.Len(t, result.Conflicts, 3)
+
+	// Both changed the priority and tag differently
+	conflict := result.Conflicts[0]
+	require.Equal(t, []TaskField{FieldPriority, FieldTags}, conflict.Fields)
+	require.Equal(t, 0, conflict.Index)
+	require.Equal(t, "(B) Call Mom key:base", conflict.Base.String())
+	require.Equal(t, "(A) Call Mom key:ours", conflict.Ours.String())
+	require.Equal(t, "(C) Call Mom key:theirs", conflict.Theirs.String())
+
+	// Ours modified, theirs deleted
+	conflict = result.Conflicts[1]
+	require.Empty(t, conflict.Fields)
+	require.Nil(t, conflict.Theirs)
+	require.Equal(t, 1, conflict.Index)
+
+	// Ours deleted, theirs modified
+	conflict = result.Conflicts[2]
+	require.Nil(t, conflict.Ours)
+	require.Equal(t, 2, conflict.Index)
+
+	checkTaskListOrder(t, result.Merged, []string{
+		"(A) Call Mom key:ours",
+		"Pick up milk @Town",
+		"Plan backyard herb garden @Home",
+	})
+
+	expect := "# <<<<<<< ours\n(A) Call Mom key:ours\n# =======\n(C) Call Mom key:theirs\n# >>>>>>> theirs\n" +
+		"# <<<<<<< ours\nPick up milk @Town\n# =======\n# >>>>>>> theirs\n" +
+		"# <<<<<<< ours\n# =======\nPlan backyard herb garden @Home\n# >>>>>>> theirs\n"
+	require.Equal(t, strings.ReplaceAll(expect, "\n", NewLine), result.String())
+
+	// The output should be loadable and contain both versions
+	reloaded, err := LoadFromString(result.String())
+	require.NoError(t, err)
+	require.Len(t, reloaded, 4)
+}
+
+func TestMerge_tags(t *testing.T) {
+	t.Parallel()
+
+	base := testMustLoad(t, "Task a:1 b:1 c:1")
+	ours := testMustLoad(t, "Task a:2 b:1 d:1")
+	theirs := testMustLoad(t, "Task a:1 e:1")
+
+	result := Merge(base, ours, theirs)
+
+	require.False(t, result.HasConflicts())
+	checkTaskListOrder(t, result.Merged, []string{"Task a:2 d:1 e:1"})
+
+	// Removing all tags results in nil tags
+	result = Merge(base, testMustLoad(t, "Task"), base)
+
+	require.False(t, result.HasConflicts())
+	require.Nil(t, result.Merged[0].AdditionalTags)
+}
+
+// ----------------------------------------------------------------------------
+//  MergeFiles()
+// ----------------------------------------------------------------------------
+
+func TestMergeFiles(t *testing.T) {
+	t.Parallel()
+
+	pathDir := t.TempDir()
+	paths := map[string]string{
+		"base":   "(B) Call Mom\nPick up milk\n",
+		"ours":   "x (B) Call Mom\nPick up milk\n",
+		"theirs": "(A) Call Mom\nPick up milk\nWater plants\n",
+	}
+
+	for name, contents := range paths {
+		paths[name] = filepath.Join(pathDir, name+".txt")
+		require.NoError(t, os.WriteFile(paths[name], []byte(contents), PermReadWrite))
+	}
+
+	result, err := MergeFiles(paths["base"], paths["ours"], paths["theirs"])
+	require.NoError(t, err)
+	require.False(t, result.HasConflicts())
+
+	raw, err := os.ReadFile(paths["ours"])
+	require.NoError(t, err)
+	require.Equal(t, result.String(), string(raw), "the result should be written to ours")
+
+	merged, err := LoadFromPath(paths["ours"])
+	require.NoError(t, err)
+	checkTaskListOrder(t, merged, []string{"x (A) Call Mom", "Pick up milk", "Water plants"})
+}
+
+func TestMergeFiles_comments_and_blank_lines(t *testing.T) {
+	t.Parallel()
+
+	pathDir := t.TempDir()
+	paths := map[string]string{
+		"base":   "# Home\n(B) Call Mom\n\nPick up milk\n# Old note\n# Work\nPay bills\n",
+		"ours":   "# Home\nx (B) Call Mom\n\nPick up milk\n# Old note\n# Work\nPay bills\n# Ours note\n",
+		"theirs": "# Home\n(A) Call Mom\n\nPick up milk\n# Work\n# Theirs note\nWater plants\n",
+	}
+
+	for name, contents := range paths {
+		paths[name] = filepath.Join(pathDir, name+".txt")
+		require.NoError(t, os.WriteFile(paths[name], []byte(contents), PermReadWrite))
+	}
+
+	result, err := MergeFiles(paths["base"], paths["ours"], paths["theirs"])
+	require.NoError(t, err)
+	require.False(t, result.HasConflicts())
+
+	raw, err := os.ReadFile(paths["ours"])
+	require.NoError(t, err)
+	require.Equal(t, joinLines([]string{
+		"# Home", "x (A) Call Mom", "", "Pick up milk", "# Work", "# Ours note",
+		"Water plants", "# Theirs note",
+	}), string(raw), "comments and blank lines should be merged line by line")
+}
+
+func TestMergeFiles_error(t *testing.T) {
+	t.Parallel()
+
+	_, err := MergeFiles(testInputTasklist, "some_file_that_does_not_exists.txt", testInputTasklist)
+
+	require.Error(t, err, "missing file should be an error")
+}
@@ -0,0 +1,446 @@
+package todo
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Constants
+// ----------------------------------------------------------------------------
+
+const (
+	// orgActiveDateLayout is the layout of the active timestamps.
+	orgActiveDateLayout = "<2006-01-02 Mon>"
+	// orgInactiveDateLayout is the layout of the inactive timestamps.
+	orgInactiveDateLayout = "[2006-01-02 Mon]"
+	// orgPropCreated is the property of the created date.
+	orgPropCreated = "CREATED"
+	// orgPropContexts is the property of the contexts which are not valid tags.
+	orgPropContexts = "CONTEXTS"
+	// orgPropProjects is the property of the projects which are not valid tags.
+	orgPropProjects = "PROJECTS"
+)
+
+var (
+	// rxOrgHeadline matches a headline and captures the stars and the title.
+	rxOrgHeadline = regexp.MustCompile(`^(\*+)\s+(.*?)\s*$`)
+	// rxOrgTags matches the tags at the end of the title, such as ":a:b:".
+	rxOrgTags = regexp.MustCompile(`(?:^|\s+)(:(?:[^\s:]+:)+)$`)
+	// rxOrgPriority matches the priority cookie at the start of the title.
+	rxOrgPriority = regexp.MustCompile(`^\[#([A-Z])\]\s*`)
+	// rxOrgPlanning matches a planning keyword with its timestamp.
+	rxOrgPlanning = regexp.MustCompile(`(DEADLINE|SCHEDULED|CLOSED):\s*[<\[](\d{4}-\d{2}-\d{2})[^>\]]*[>\]]`)
+	// rxOrgProperty matches a property of the properties drawer.
+	rxOrgProperty = regexp.MustCompile(`^:([^\s:]+):(?:\s+(.*?))?\s*$`)
+	// rxOrgTag matches a valid tag.
+	rxOrgTag = regexp.MustCompile(`^[\p{L}\p{N}_@#%]+$`)
+)
+
+// ----------------------------------------------------------------------------
+//  Type: OrgOptions
+// ----------------------------------------------------------------------------
+
+// OrgOptions are the options of the Org-mode format. The zero value uses the
+// "TODO" and "DONE" keywords and writes the tasks as top-level headlines.
+type OrgOptions struct {
+	// TodoKeywords are the keywords of the open tasks. Defaults to "TODO".
+	// WriteOrg uses the first one.
+	TodoKeywords []string
+	// DoneKeywords are the keywords of the completed tasks. Defaults to
+	// "DONE". WriteOrg uses the first one.
+	DoneKeywords []string
+	// GroupByProject writes the tasks under a top-level headline per project.
+	// Each task is written once, under its first project, and the tasks
+	// without project are written first, as top-level headlines.
+	GroupByProject bool
+}
+
+// doneKeywords returns the keywords of the completed tasks or the default ones.
+func (opts OrgOptions) doneKeywords() []string {
+	if len(opts.DoneKeywords) == 0 {
+		return []string{"DONE"}
+	}
+
+	return opts.DoneKeywords
+}
+
+// todoKeywords returns the keywords of the open tasks or the default ones.
+func (opts OrgOptions) todoKeywords() []string {
+	if len(opts.TodoKeywords) == 0 {
+		return []string{"TODO"}
+	}
+
+	return opts.TodoKeywords
+}
+
+// ----------------------------------------------------------------------------
+//  Public functions
+// ----------------------------------------------------------------------------
+
+// LoadFromOrg loads a TaskList from the headlines of an Org-mode document with
+// a TODO or DONE keyword (see OrgOptions), such as:
+//
+//	#+TODO: TODO | DONE
+//	* TODO [#A] Call Mom :@Phone:Family:
+//	DEADLINE: <2024-01-05 Fri> SCHEDULED: <2024-01-03 Wed>
+//	:PROPERTIES:
+//	:CREATED: [2024-01-01 Mon]
+//	:uid:     42
+//	:END:
+//
+// The tags starting with "@" are contexts and the other ones are projects. The
+// DEADLINE is the due date, the SCHEDULED date is the ThresholdTag tag and the
+// CLOSED date is the completed date. The properties are additional tags, except
+// CREATED which is the created date, and CONTEXTS and PROJECTS which hold the
+// ones which are not valid tags. Whitespaces and colons of the values are
+// replaced by "_".
+//
+// The headlines without keyword are projects of the task headlines nested
+// under them. Any other line, such as the notes of a task, is ignored. The ID
+// of the tasks is their position, starting from 1.
+func LoadFromOrg(reader io.Reader, opts OrgOptions) (TaskList, error) {
+	type heading struct {
+		project string
+		level   int
+	}
+
+	var (
+		parents  []heading
+		current  *Task
+		inDrawer bool
+	)
+
+	tasklist := NewTaskList()
+	scanner := bufio.NewScanner(reader)
+	lineNum, taskLine := 0, 0
+
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+
+		// Parse the todo.txt line to make sure it is valid, and that the title
+		// is not read as the completion mark, priority or dates
+		task, err := reparseTask(current)
+		if err != nil {
+			return errors.Wrapf(err, "line %d", taskLine)
+		}
+
+		task.ID = len(tasklist) + 1
+		tasklist = append(tasklist, *task)
+		current = nil
+
+		return nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNum++
+
+		match := rxOrgHeadline.FindStringSubmatch(line)
+		if match == nil {
+			if current == nil {
+				continue
+			}
+
+			if err := parseOrgLine(current, strings.TrimSpace(line), &inDrawer); err != nil {
+				return nil, errors.Wrapf(err, "line %d", lineNum)
+			}
+
+			continue
+		}
+
+		if err := finish(); err != nil {
+			return nil, err
+		}
+
+		level := len(match[1])
+
+		for len(parents) > 0 && parents[len(parents)-1].level >= level {
+			parents = parents[:len(parents)-1]
+		}
+
+		task, isTask := orgHeadlineToTask(match[2], opts)
+		if !isTask {
+			title := rxOrgTags.ReplaceAllString(match[2], emptyStr)
+			parents = append(parents, heading{project: sanitizeWord(title), level: level})
+
+			continue
+		}
+
+		for _, parent := range parents {
+			if isNotEmpty(parent.project) {
+				task.Projects = append(task.Projects, parent.project)
+			}
+		}
+
+		parents = append(parents, heading{project: emptyStr, level: level})
+		current, inDrawer, taskLine = task, false, lineNum
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read Org")
+	}
+
+	if err := finish(); err != nil {
+		return nil, err
+	}
+
+	return tasklist, nil
+}
+
+// ----------------------------------------------------------------------------
+//  TaskList.WriteOrg()
+// ----------------------------------------------------------------------------
+
+// WriteOrg writes the TaskList as Org-mode headlines. It is the reverse of
+// LoadFromOrg, so the document reads back as the same tasks. Contexts and
+// projects which are not valid tags are written to the CONTEXTS and PROJECTS
+// properties.
+func (tasklist *TaskList) WriteOrg(writer io.Writer, opts OrgOptions) error {
+	ungrouped := []*Task{}
+	groups := map[string][]*Task{}
+	names := map[string]bool{}
+
+	for i := range *tasklist {
+		task := &(*tasklist)[i]
+
+		if !opts.GroupByProject || !task.HasProjects() {
+			ungrouped = append(ungrouped, task)
+
+			continue
+		}
+
+		groups[task.Projects[0]] = append(groups[task.Projects[0]], task)
+		names[task.Projects[0]] = true
+	}
+
+	var builder strings.Builder
+
+	for _, task := range ungrouped {
+		writeOrgTask(&builder, task, 1, emptyStr, opts)
+	}
+
+	for _, name := range sortedKeys(names) {
+		builder.WriteString("* " + name + NewLine)
+
+		for _, task := range groups[name] {
+			writeOrgTask(&builder, task, 2, name, opts)
+		}
+	}
+
+	_, err := io.WriteString(writer, builder.String())
+
+	return errors.Wrap(err, "failed to write Org")
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// orgHeadlineToTask returns the task of the headline title, or false if the
+// title has no TODO or DONE keyword or no text.
+func orgHeadlineToTask(title string, opts OrgOptions) (*Task, bool) {
+	task := new(Task)
+
+	if match := rxOrgTags.FindStringSubmatch(title); match != nil {
+		title = strings.TrimSuffix(title, match[0])
+
+		for _, tag := range strings.Split(strings.Trim(match[1], ":"), ":") {
+			if context, isContext := strings.CutPrefix(tag, contextPrefix); isContext {
+				task.Contexts = append(task.Contexts, context)
+			} else {
+				task.Projects = append(task.Projects, tag)
+			}
+		}
+	}
+
+	keyword, title, _ := strings.Cut(title, " ")
+
+	switch {
+	case slices.Contains(opts.todoKeywords(), keyword):
+	case slices.Contains(opts.doneKeywords(), keyword):
+		task.Completed = true
+	default:
+		return nil, false
+	}
+
+	title = strings.TrimSpace(title)
+
+	if match := rxOrgPriority.FindStringSubmatch(title); match != nil {
+		task.Priority = match[1]
+		title = strings.TrimPrefix(title, match[0])
+	}
+
+	if isEmpty(title) {
+		return nil, false
+	}
+
+	task.Todo = title
+
+	return task, true
+}
+
+// parseOrgLine parses a line of the section of a task headline: the planning
+// line and the properties drawer.
+func parseOrgLine(task *Task, line string, inDrawer *bool) error {
+	switch {
+	case *inDrawer && strings.EqualFold(line, ":END:"):
+		*inDrawer = false
+	case *inDrawer:
+		match := rxOrgProperty.FindStringSubmatch(line)
+		if match == nil {
+			return nil
+		}
+
+		return setOrgProperty(task, match[1], match[2])
+	case strings.EqualFold(line, ":PROPERTIES:"):
+		*inDrawer = true
+	default:
+		for _, match := range rxOrgPlanning.FindAllStringSubmatch(line, -1) {
+			date, err := parseTime(match[2])
+			if err != nil {
+				return errors.Wrap(err, "invalid "+match[1]+" date")
+			}
+
+			switch match[1] {
+			case "DEADLINE":
+				task.DueDate = date
+			case "SCHEDULED":
+				setTag(task, ThresholdTag, formatDate(date))
+			case "CLOSED":
+				task.CompletedDate = date
+			}
+		}
+	}
+
+	return nil
+}
+
+// setOrgProperty sets the property of the properties drawer to the task.
+func setOrgProperty(task *Task, key, value string) error {
+	switch key {
+	case orgPropCreated:
+		match := rxOrgPlanning.FindStringSubmatch("CLOSED: " + value)
+		if match == nil {
+			return errors.New("invalid CREATED date: " + value)
+		}
+
+		date, err := parseTime(match[2])
+		if err != nil {
+			return errors.Wrap(err, "invalid CREATED date")
+		}
+
+		task.CreatedDate = date
+	case orgPropContexts:
+		task.Contexts = append(task.Contexts, strings.Fields(value)...)
+	case orgPropProjects:
+		task.Projects = append(task.Projects, strings.Fields(value)...)
+	default:
+		setTag(task, sanitizeWord(key), sanitizeWord(value))
+	}
+
+	return nil
+}
+
+// writeOrgTask writes the task as a headline of the level. The project of the
+// parent headline is omitted from the tags.
+func writeOrgTask(builder *strings.Builder, task *Task, level int, parent string, opts OrgOptions) {
+	keyword := opts.todoKeywords()[0]
+	if task.Completed {
+		keyword = opts.doneKeywords()[0]
+	}
+
+	headline := strings.Repeat("*", level) + " " + keyword
+
+	if task.HasPriority() {
+		headline += " [#" + task.Priority + "]"
+	}
+
+	headline += " " + task.Todo
+
+	var tags, contexts, projects []string
+
+	for _, context := range task.Contexts {
+		if rxOrgTag.MatchString(context) {
+			tags = append(tags, contextPrefix+context)
+		} else {
+			contexts = append(contexts, context)
+		}
+	}
+
+	for _, project := range task.Projects {
+		switch {
+		case project == parent:
+			continue
+		case rxOrgTag.MatchString(project) && !strings.HasPrefix(project, contextPrefix):
+			tags = append(tags, project)
+		default:
+			projects = append(projects, project)
+		}
+	}
+
+	if len(tags) > 0 {
+		headline += " :" + strings.Join(tags, ":") + ":"
+	}
+
+	builder.WriteString(headline + NewLine)
+
+	var planning []string
+
+	if task.HasDueDate() {
+		planning = append(planning, "DEADLINE: "+task.DueDate.Format(orgActiveDateLayout))
+	}
+
+	if date, err := parseTime(task.AdditionalTags[ThresholdTag]); err == nil {
+		planning = append(planning, "SCHEDULED: "+date.Format(orgActiveDateLayout))
+	}
+
+	if task.Completed && task.HasCompletedDate() {
+		planning = append(planning, "CLOSED: "+task.CompletedDate.Format(orgInactiveDateLayout))
+	}
+
+	if len(planning) > 0 {
+		builder.WriteString(strings.Join(planning, " ") + NewLine)
+	}
+
+	props := [][2]string{}
+
+	if task.HasCreatedDate() {
+		props = append(props, [2]string{orgPropCreated, task.CreatedDate.Format(orgInactiveDateLayout)})
+	}
+
+	if len(contexts) > 0 {
+		props = append(props, [2]string{orgPropContexts, strings.Join(contexts, " ")})
+	}
+
+	if len(projects) > 0 {
+		props = append(props, [2]string{orgPropProjects, strings.Join(projects, " ")})
+	}
+
+	for _, key := range sortedKeys(tagKeys(task.AdditionalTags)) {
+		if _, err := parseTime(task.AdditionalTags[key]); key == ThresholdTag && err == nil {
+			continue // written as SCHEDULED
+		}
+
+		props = append(props, [2]string{key, task.AdditionalTags[key]})
+	}
+
+	if len(props) == 0 {
+		return
+	}
+
+	builder.WriteString(":PROPERTIES:" + NewLine)
+
+	for _, prop := range props {
+		builder.WriteString(":" + prop[0] + ": " + prop[1] + NewLine)
+	}
+
+	builder.WriteString(":END:" + NewLine)
+}
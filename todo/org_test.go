@@ -0,0 +1,160 @@
+package todo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskList_WriteOrg(t *testing.T) {
+	t.Parallel()
+
+	tasklist := testMustLoad(t, `(A) 2024-01-01 Call Mom @Phone +Family +Home.Chores due:2024-01-05 t:2024-01-03 uid:42
+x 2024-01-03 2024-01-02 Learn Go +Study
+Read book t:soon
+`)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, tasklist.WriteOrg(&buf, OrgOptions{}))
+
+	expect := `* TODO [#A] Call Mom :@Phone:Family:
+DEADLINE: <2024-01-05 Fri> SCHEDULED: <2024-01-03 Wed>
+:PROPERTIES:
+:CREATED: [2024-01-01 Mon]
+:PROJECTS: Home.Chores
+:uid: 42
+:END:
+* DONE Learn Go :Study:
+CLOSED: [2024-01-03 Wed]
+:PROPERTIES:
+:CREATED: [2024-01-02 Tue]
+:END:
+* TODO Read book
+:PROPERTIES:
+:t: soon
+:END:
+`
+	require.Equal(t, strings.ReplaceAll(expect, "\n", NewLine), buf.String())
+
+	// Grouped by project, with custom keywords
+	buf.Reset()
+
+	require.NoError(t, tasklist.WriteOrg(&buf, OrgOptions{
+		GroupByProject: true, TodoKeywords: []string{"NEXT", "TODO"}, DoneKeywords: []string{"FINISHED"},
+	}))
+
+	expect = `* NEXT Read book
+:PROPERTIES:
+:t: soon
+:END:
+* Family
+** NEXT [#A] Call Mom :@Phone:
+DEADLINE: <2024-01-05 Fri> SCHEDULED: <2024-01-03 Wed>
+:PROPERTIES:
+:CREATED: [2024-01-01 Mon]
+:PROJECTS: Home.Chores
+:uid: 42
+:END:
+* Study
+** FINISHED Learn Go
+CLOSED: [2024-01-03 Wed]
+:PROPERTIES:
+:CREATED: [2024-01-02 Tue]
+:END:
+`
+	require.Equal(t, strings.ReplaceAll(expect, "\n", NewLine), buf.String())
+}
+
+func TestLoadFromOrg_round_trip(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{testInputTask, testInputSort, testInputFilter, testInputTasklist} {
+		expect, err := LoadFromPath(path)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+
+		require.NoError(t, expect.WriteOrg(&buf, OrgOptions{}))
+
+		actual, err := LoadFromOrg(&buf, OrgOptions{})
+		require.NoError(t, err)
+		require.Equal(t, expect.String(), actual.String(), "%s should round-trip", path)
+
+		// Grouping changes the order of the tasks
+		buf.Reset()
+
+		require.NoError(t, expect.WriteOrg(&buf, OrgOptions{GroupByProject: true}))
+
+		actual, err = LoadFromOrg(&buf, OrgOptions{})
+		require.NoError(t, err)
+		require.ElementsMatch(t, strings.Split(expect.String(), NewLine), strings.Split(actual.String(), NewLine),
+			"%s should round-trip", path)
+	}
+}
+
+func TestLoadFromOrg(t *testing.T) {
+	t.Parallel()
+
+	input := `#+TITLE: Plans
+Some text before the first headline.
+
+* Release plan :work:
+** TODO [#B] Write changelog :@Desk:
+   DEADLINE: <2024-01-05 Fri 10:00 +1w>
+   Notes about the changelog.
+   :PROPERTIES:
+   :CREATED:  [2024-01-01 Mon 09:30]
+   :owner:    Bob Smith
+   :CONTEXTS: Home.Office
+   :END:
+*** DONE Tag version
+    CLOSED: [2024-01-03 Wed 12:00] SCHEDULED: <2024-01-02 Tue>
+** Website
+*** TODO Update logo :Brand:
+* TODO Call Mom
+* TODO
+* WAITING Not a task
+`
+
+	tasklist, err := LoadFromOrg(strings.NewReader(input), OrgOptions{})
+	require.NoError(t, err)
+
+	expect := `(B) 2024-01-01 Write changelog @Desk @Home.Office +Release_plan owner:Bob_Smith due:2024-01-05
+x 2024-01-03 Tag version +Release_plan t:2024-01-02
+Update logo +Brand +Release_plan +Website
+Call Mom
+`
+	require.Equal(t, strings.ReplaceAll(expect, "\n", NewLine), tasklist.String())
+	require.Equal(t, 4, tasklist[3].ID)
+
+	// Custom keywords
+	tasklist, err = LoadFromOrg(strings.NewReader(input), OrgOptions{
+		TodoKeywords: []string{"WAITING"}, DoneKeywords: []string{"DONE"},
+	})
+	require.NoError(t, err)
+	require.Len(t, tasklist, 2)
+	require.Equal(t, "Not a task", tasklist[1].Todo)
+
+	// Leading markers of the title
+	tasklist, err = LoadFromOrg(strings.NewReader("* TODO x marks the spot\n* TODO (B) foo\n* TODO [#A] (B) bar\n"),
+		OrgOptions{})
+	require.NoError(t, err)
+	require.False(t, tasklist[0].Completed, "title should not be read as the completion mark")
+	require.Equal(t, `\x marks the spot`, tasklist[0].String())
+	require.False(t, tasklist[1].HasPriority(), "title should not be read as the priority without cookie")
+	require.Equal(t, `\(B) foo`, tasklist[1].String())
+	require.Equal(t, "(A) (B) bar", tasklist[2].String(), "title after the priority cookie should not be escaped")
+
+	// Errors
+	for input, line := range map[string]string{
+		"* TODO foo\nDEADLINE: <2024-02-30 Fri>\n":                      "line 2",
+		"* TODO foo\n:PROPERTIES:\n:CREATED: yesterday\n:END:\n":        "line 3",
+		"* TODO foo\n:PROPERTIES:\n:CREATED: [2024-02-30 Fri]\n:END:\n": "line 3",
+	} {
+		_, err := LoadFromOrg(strings.NewReader(input), OrgOptions{})
+		require.ErrorContains(t, err, line, "invalid input should fail: %q", input)
+	}
+}
@@ -0,0 +1,402 @@
+package todo
+
+import (
+	"bufio"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Constants
+// ----------------------------------------------------------------------------
+
+const (
+	// ansiReset is the ANSI sequence resetting all the styles.
+	ansiReset = "\033[0m"
+	// htmlClassPrefix is the prefix of the HTML classes.
+	htmlClassPrefix = "todo-"
+)
+
+// ThemeColors are the colour names of todo.sh and their ANSI sequences, as in
+// its todo.cfg. They can be used as "$NAME" in the config read by WithConfig.
+//
+//nolint:gochecknoglobals // it is intentionally global as a constant table
+var ThemeColors = map[string]string{
+	"BLACK":        "\033[0;30m",
+	"RED":          "\033[0;31m",
+	"GREEN":        "\033[0;32m",
+	"BROWN":        "\033[0;33m",
+	"BLUE":         "\033[0;34m",
+	"PURPLE":       "\033[0;35m",
+	"CYAN":         "\033[0;36m",
+	"LIGHT_GREY":   "\033[0;37m",
+	"DARK_GREY":    "\033[1;30m",
+	"LIGHT_RED":    "\033[1;31m",
+	"LIGHT_GREEN":  "\033[1;32m",
+	"YELLOW":       "\033[1;33m",
+	"LIGHT_BLUE":   "\033[1;34m",
+	"LIGHT_PURPLE": "\033[1;35m",
+	"LIGHT_CYAN":   "\033[1;36m",
+	"WHITE":        "\033[1;37m",
+	"DEFAULT":      ansiReset,
+	"NONE":         emptyStr,
+}
+
+var (
+	// rxThemeAssign matches an assignment of a shell config, such as
+	// "export PRI_A=$YELLOW".
+	rxThemeAssign = regexp.MustCompile(`^\s*(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+	// rxThemeVar matches a variable reference, such as "$YELLOW" or "${YELLOW}".
+	rxThemeVar = regexp.MustCompile(`^\$(?:\{(\w+)\}|(\w+))$`)
+	// rxThemeEscape matches the textual forms of the escape character.
+	rxThemeEscape = regexp.MustCompile(`\\+(?:033|e|x1[bB])`)
+)
+
+// ----------------------------------------------------------------------------
+//  Type: Theme
+// ----------------------------------------------------------------------------
+
+// Theme maps the segment types and the states of the tasks to ANSI styles. The
+// styles are ANSI escape sequences, such as ThemeColors["YELLOW"]. An empty
+// style is no style.
+//
+// Like todo.sh, the style of the priority or of a completed task applies to
+// the whole line, and the styles of the segments apply on top of it.
+type Theme struct {
+	// Segments are the styles of the segments by type.
+	Segments map[TaskSegmentType]string
+	// Priorities are the styles of the lines of the open tasks by priority
+	// letter (todo.sh PRI_A to PRI_Z).
+	Priorities map[string]string
+	// OtherPriority is the style of the lines of the priorities without own
+	// style (todo.sh PRI_X).
+	OtherPriority string
+	// Done is the style of the lines of the completed tasks (todo.sh
+	// COLOR_DONE). The segments of completed tasks are not styled.
+	Done string
+	// Overdue is the style of the due date of an overdue task.
+	Overdue string
+	// DueToday is the style of the due date of a task due today.
+	DueToday string
+	// Number is the style of the line numbers (todo.sh COLOR_NUMBER).
+	Number string
+}
+
+// DefaultTheme returns the default theme, which follows the defaults of
+// todo.sh for the priorities and the completed tasks.
+func DefaultTheme() Theme {
+	return Theme{
+		Segments: map[TaskSegmentType]string{
+			SegmentContext: ThemeColors["CYAN"],
+			SegmentProject: ThemeColors["PURPLE"],
+			SegmentTag:     ThemeColors["LIGHT_GREY"],
+			SegmentDueDate: ThemeColors["LIGHT_GREY"],
+		},
+		Priorities: map[string]string{
+			"A": ThemeColors["YELLOW"],
+			"B": ThemeColors["GREEN"],
+			"C": ThemeColors["LIGHT_BLUE"],
+		},
+		OtherPriority: ThemeColors["WHITE"],
+		Done:          ThemeColors["LIGHT_GREY"],
+		Overdue:       ThemeColors["LIGHT_RED"],
+		DueToday:      ThemeColors["YELLOW"],
+		Number:        emptyStr,
+	}
+}
+
+// WithConfig returns a copy of the theme with the colours of a todo.sh config
+// file (todo.cfg). It reads the assignments, such as:
+//
+//	export PRI_A=$YELLOW
+//	export COLOR_CONTEXT='\\033[0;36m'
+//
+// The values are the names of ThemeColors or of the variables assigned before,
+// or escape sequences with "\033", "\e" or "\x1b". See WithEnv for the
+// variables used. Other lines are ignored.
+func (theme Theme) WithConfig(reader io.Reader) (Theme, error) {
+	vars := map[string]string{}
+	for name, value := range ThemeColors {
+		vars[name] = value
+	}
+
+	scanner := bufio.NewScanner(reader)
+
+	for scanner.Scan() {
+		match := rxThemeAssign.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+
+		value := unquoteShell(match[2])
+		if ref := rxThemeVar.FindStringSubmatch(value); ref != nil {
+			value = vars[ref[1]+ref[2]]
+		}
+
+		vars[match[1]] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return theme, errors.Wrap(err, "failed to read theme config")
+	}
+
+	return theme.WithEnv(func(key string) (string, bool) {
+		value, found := vars[key]
+
+		return value, found
+	}), nil
+}
+
+// WithEnv returns a copy of the theme with the colours of the variables of
+// todo.sh, looked up by the function such as os.LookupEnv:
+//
+//   - PRI_A to PRI_Z: the lines of the priority. PRI_X: the other priorities.
+//   - COLOR_DONE: the lines of the completed tasks.
+//   - COLOR_CONTEXT, COLOR_PROJECT: the contexts and the projects.
+//   - COLOR_DATE: the created and the completed dates.
+//   - COLOR_META: the tags and the due date.
+//   - COLOR_NUMBER: the line numbers.
+//   - COLOR_OVERDUE, COLOR_DUE_TODAY: the due date of the overdue tasks and
+//     of the tasks due today. They are not in todo.sh.
+//
+// The values are escape sequences with "\033", "\e" or "\x1b".
+func (theme Theme) WithEnv(lookup func(key string) (string, bool)) Theme {
+	result := theme.clone()
+
+	get := func(key string, apply func(style string)) {
+		if value, found := lookup(key); found {
+			apply(rxThemeEscape.ReplaceAllString(value, "\033"))
+		}
+	}
+
+	for letter := 'A'; letter <= 'Z'; letter++ {
+		if letter == 'X' {
+			continue
+		}
+
+		get("PRI_"+string(letter), func(style string) { result.Priorities[string(letter)] = style })
+	}
+
+	get("PRI_X", func(style string) { result.OtherPriority = style })
+	get("COLOR_DONE", func(style string) { result.Done = style })
+	get("COLOR_NUMBER", func(style string) { result.Number = style })
+	get("COLOR_OVERDUE", func(style string) { result.Overdue = style })
+	get("COLOR_DUE_TODAY", func(style string) { result.DueToday = style })
+
+	for key, segTypes := range map[string][]TaskSegmentType{
+		"COLOR_CONTEXT": {SegmentContext},
+		"COLOR_PROJECT": {SegmentProject},
+		"COLOR_DATE":    {SegmentCreatedDate, SegmentCompletedDate},
+		"COLOR_META":    {SegmentTag, SegmentDueDate},
+	} {
+		get(key, func(style string) {
+			for _, segType := range segTypes {
+				result.Segments[segType] = style
+			}
+		})
+	}
+
+	return result
+}
+
+// clone returns a deep copy of the theme.
+func (theme Theme) clone() Theme {
+	result := theme
+	result.Segments = make(map[TaskSegmentType]string, len(theme.Segments))
+	result.Priorities = make(map[string]string, len(theme.Priorities))
+
+	for segType, style := range theme.Segments {
+		result.Segments[segType] = style
+	}
+
+	for letter, style := range theme.Priorities {
+		result.Priorities[letter] = style
+	}
+
+	return result
+}
+
+// lineStyle returns the style of the whole line of the task.
+func (theme Theme) lineStyle(task *Task) string {
+	switch {
+	case task.Completed:
+		return theme.Done
+	case !task.HasPriority():
+		return emptyStr
+	}
+
+	if style, found := theme.Priorities[task.Priority]; found {
+		return style
+	}
+
+	return theme.OtherPriority
+}
+
+// ----------------------------------------------------------------------------
+//  Type: Renderer
+// ----------------------------------------------------------------------------
+
+// Renderer renders tasks with syntax highlighting, based on Task.Segments, as
+// ANSI colours for terminals or as HTML spans.
+type Renderer struct {
+	// Clock is used to tell the overdue tasks and the tasks due today.
+	// Defaults to the real time.
+	Clock Clock
+	// Theme is the theme of the ANSI output.
+	Theme Theme
+	// NoColor disables the ANSI styles.
+	NoColor bool
+}
+
+// NewRenderer returns a Renderer with DefaultTheme. The colours are disabled
+// if the NO_COLOR environment variable is set and not empty (see
+// https://no-color.org).
+func NewRenderer() *Renderer {
+	return &Renderer{
+		Clock:   nil,
+		Theme:   DefaultTheme(),
+		NoColor: isNotEmpty(os.Getenv("NO_COLOR")),
+	}
+}
+
+// Color returns the text styled as a segment of the type. It is a ColorFunc,
+// so it can be set as Formatter.Color.
+func (r *Renderer) Color(segType TaskSegmentType, text string) string {
+	return r.style(r.Theme.Segments[segType], text, emptyStr)
+}
+
+// RenderNumber returns the line number styled by Theme.Number.
+func (r *Renderer) RenderNumber(number string) string {
+	return r.style(r.Theme.Number, number, emptyStr)
+}
+
+// RenderTask returns the task in todo.txt format with ANSI styles.
+func (r *Renderer) RenderTask(task *Task) string {
+	segments := task.Segments()
+	parts := make([]string, len(segments))
+	line := r.Theme.lineStyle(task)
+
+	for i, segment := range segments {
+		parts[i] = segment.Display
+
+		if !task.Completed {
+			parts[i] = r.style(r.segmentStyle(task, segment.Type), segment.Display, line)
+		}
+	}
+
+	return r.style(line, strings.Join(parts, " "), emptyStr)
+}
+
+// RenderHTML returns the task in todo.txt format as HTML spans. The task is a
+// span with the class "todo-task" and the classes of its state:
+// "todo-priority-a" (lower case letter), "todo-done", "todo-overdue" and
+// "todo-due-today". Each segment is a span with the class "todo-segment" and
+// the class of its type, such as "todo-priority", "todo-context" or
+// "todo-due-date". The text is HTML-escaped. The styles are left to CSS, so
+// Theme and NoColor are not used.
+func (r *Renderer) RenderHTML(task *Task) string {
+	classes := []string{htmlClassPrefix + "task"}
+
+	switch {
+	case task.Completed:
+		classes = append(classes, htmlClassPrefix+"done")
+	case task.HasPriority():
+		classes = append(classes, htmlClassPrefix+"priority-"+strings.ToLower(task.Priority))
+	}
+
+	if state := r.dueState(task); isNotEmpty(state) {
+		classes = append(classes, htmlClassPrefix+state)
+	}
+
+	segments := task.Segments()
+	parts := make([]string, len(segments))
+
+	for i, segment := range segments {
+		parts[i] = `<span class="` + htmlClassPrefix + "segment " + htmlClassPrefix + segmentClass(segment.Type) +
+			`">` + html.EscapeString(segment.Display) + "</span>"
+	}
+
+	return `<span class="` + strings.Join(classes, " ") + `">` + strings.Join(parts, " ") + "</span>"
+}
+
+// dueState returns "overdue" or "due-today" for an open task with a due date,
+// or an empty string.
+func (r *Renderer) dueState(task *Task) string {
+	if task.Completed || !task.HasDueDate() {
+		return emptyStr
+	}
+
+	switch days := daysFromToday(r.Clock, task.DueDate); {
+	case days < 0:
+		return "overdue"
+	case days == 0:
+		return "due-today"
+	default:
+		return emptyStr
+	}
+}
+
+// segmentStyle returns the style of the segment of the task.
+func (r *Renderer) segmentStyle(task *Task, segType TaskSegmentType) string {
+	if segType == SegmentDueDate {
+		switch r.dueState(task) {
+		case "overdue":
+			return r.Theme.Overdue
+		case "due-today":
+			return r.Theme.DueToday
+		}
+	}
+
+	return r.Theme.Segments[segType]
+}
+
+// style returns the text with the style, followed by the outer style to
+// restore, or the text as is if disabled or without style.
+func (r *Renderer) style(style, text, outer string) string {
+	if r.NoColor || isEmpty(style) {
+		return text
+	}
+
+	return style + text + ansiReset + outer
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// segmentClass returns the HTML class of the segment type in kebab case, such
+// as "due-date".
+func segmentClass(segType TaskSegmentType) string {
+	var builder strings.Builder
+
+	for i, char := range segType.String() {
+		if i > 0 && char >= 'A' && char <= 'Z' {
+			builder.WriteByte('-')
+		}
+
+		builder.WriteRune(char)
+	}
+
+	return strings.ToLower(builder.String())
+}
+
+// unquoteShell removes the comment and the quotes of a shell value.
+func unquoteShell(value string) string {
+	value = strings.TrimSpace(value)
+
+	switch {
+	case strings.HasPrefix(value, "'"):
+		value, _, _ = strings.Cut(value[1:], "'")
+	case strings.HasPrefix(value, `"`):
+		value, _, _ = strings.Cut(value[1:], `"`)
+	default:
+		value, _, _ = strings.Cut(value, " #")
+	}
+
+	return strings.TrimSpace(value)
+}
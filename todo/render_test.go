@@ -0,0 +1,170 @@
+package todo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testRenderer returns a Renderer with the default theme and a fake clock on
+// 2024-01-16.
+func testRenderer() *Renderer {
+	return &Renderer{
+		Clock:   &fakeClock{now: time.Date(2024, 1, 16, 10, 0, 0, 0, time.Local)},
+		Theme:   DefaultTheme(),
+		NoColor: false,
+	}
+}
+
+func TestRenderer_RenderTask(t *testing.T) {
+	t.Parallel()
+
+	renderer := testRenderer()
+	tasklist := testMustLoad(t, `(A) Call Mom @Phone +Family due:2024-01-15
+(K) Pay bills due:2024-01-16
+x 2024-01-03 Buy seeds +Garden
+Read book level:1 due:2024-01-20
+`)
+
+	const (
+		yellow = "\033[1;33m"
+		reset  = "\033[0m"
+	)
+
+	require.Equal(t, yellow+"(A) Call Mom "+
+		"\033[0;36m@Phone"+reset+yellow+" "+
+		"\033[0;35m+Family"+reset+yellow+" "+
+		"\033[1;31mdue:2024-01-15"+reset+yellow+reset,
+		renderer.RenderTask(&tasklist[0]), "overdue date should be red on the priority colour")
+
+	require.Equal(t, "\033[1;37m(K) Pay bills "+yellow+"due:2024-01-16"+reset+"\033[1;37m"+reset,
+		renderer.RenderTask(&tasklist[1]), "other priorities should be PRI_X")
+
+	require.Equal(t, "\033[0;37mx 2024-01-03 Buy seeds +Garden"+reset,
+		renderer.RenderTask(&tasklist[2]), "completed task should be in a single colour")
+
+	require.Equal(t, "Read book \033[0;37mlevel:1"+reset+" \033[0;37mdue:2024-01-20"+reset,
+		renderer.RenderTask(&tasklist[3]))
+
+	// NO_COLOR
+	renderer.NoColor = true
+
+	for i := range tasklist {
+		require.Equal(t, tasklist[i].String(), renderer.RenderTask(&tasklist[i]))
+	}
+
+	require.Equal(t, "1", renderer.RenderNumber("1"))
+}
+
+func TestRenderer_Color(t *testing.T) {
+	t.Parallel()
+
+	renderer := testRenderer()
+
+	formatter, err := NewFormatter("{{colored .}}")
+	require.NoError(t, err)
+
+	formatter.Color = renderer.Color
+
+	actual, err := formatter.FormatTask(&testMustLoad(t, "Call Mom @Phone\n")[0])
+	require.NoError(t, err)
+	require.Contains(t, actual, "\033[0;36m@Phone\033[0m", "renderer should be the colour hook of templates")
+	require.Equal(t, "text", renderer.Color(SegmentTodoText, "text"))
+}
+
+func TestRenderer_RenderHTML(t *testing.T) {
+	t.Parallel()
+
+	renderer := testRenderer()
+	tasklist := testMustLoad(t, `(A) Call <Mom> @Phone due:2024-01-16
+x 2024-01-03 Buy seeds due:2024-01-01
+`)
+
+	require.Equal(t, `<span class="todo-task todo-priority-a todo-due-today">`+
+		`<span class="todo-segment todo-priority">(A)</span> `+
+		`<span class="todo-segment todo-todo-text">Call &lt;Mom&gt;</span> `+
+		`<span class="todo-segment todo-context">@Phone</span> `+
+		`<span class="todo-segment todo-due-date">due:2024-01-16</span></span>`,
+		renderer.RenderHTML(&tasklist[0]))
+
+	require.Equal(t, `<span class="todo-task todo-done">`+
+		`<span class="todo-segment todo-is-completed">x</span> `+
+		`<span class="todo-segment todo-completed-date">2024-01-03</span> `+
+		`<span class="todo-segment todo-todo-text">Buy seeds</span> `+
+		`<span class="todo-segment todo-due-date">due:2024-01-01</span></span>`,
+		renderer.RenderHTML(&tasklist[1]), "completed task should not be overdue")
+}
+
+func TestTheme_WithConfig(t *testing.T) {
+	t.Parallel()
+
+	config := `# === Color Map ===
+export BLACK='\\033[0;30m'
+export MY_ORANGE='\\033[38;5;208m'
+
+# Priorities
+export PRI_A=$MY_ORANGE
+export PRI_B=${GREEN}   # comment
+export PRI_X=$NONE
+export COLOR_DONE="\e[2m"
+export COLOR_PROJECT=$RED
+COLOR_CONTEXT='\x1b[4m'
+export COLOR_DATE=$BLUE
+export COLOR_META=$NONE
+export COLOR_NUMBER=$DARK_GREY
+export TODO_DIR="$HOME/todo"
+`
+
+	theme, err := DefaultTheme().WithConfig(strings.NewReader(config))
+	require.NoError(t, err)
+
+	require.Equal(t, "\033[38;5;208m", theme.Priorities["A"])
+	require.Equal(t, "\033[0;32m", theme.Priorities["B"])
+	require.Equal(t, "\033[1;34m", theme.Priorities["C"], "unset priority should be kept")
+	require.Empty(t, theme.OtherPriority)
+	require.Equal(t, "\033[2m", theme.Done)
+	require.Equal(t, "\033[0;31m", theme.Segments[SegmentProject])
+	require.Equal(t, "\033[4m", theme.Segments[SegmentContext])
+	require.Equal(t, "\033[0;34m", theme.Segments[SegmentCreatedDate])
+	require.Equal(t, "\033[0;34m", theme.Segments[SegmentCompletedDate])
+	require.Empty(t, theme.Segments[SegmentTag])
+	require.Empty(t, theme.Segments[SegmentDueDate])
+	require.Equal(t, "\033[1;30m", theme.Number)
+
+	require.Equal(t, "\033[1;33m", DefaultTheme().Priorities["A"], "default theme should not be changed")
+
+	renderer := &Renderer{Clock: nil, Theme: theme, NoColor: false}
+	require.Equal(t, "\033[1;30m12\033[0m", renderer.RenderNumber("12"))
+}
+
+func TestTheme_WithEnv(t *testing.T) {
+	t.Parallel()
+
+	env := map[string]string{
+		"PRI_Z":           `\\033[0;35m`,
+		"COLOR_OVERDUE":   `\033[41m`,
+		"COLOR_DUE_TODAY": "",
+	}
+
+	theme := DefaultTheme().WithEnv(func(key string) (string, bool) {
+		value, found := env[key]
+
+		return value, found
+	})
+
+	require.Equal(t, "\033[0;35m", theme.Priorities["Z"])
+	require.Equal(t, "\033[41m", theme.Overdue)
+	require.Empty(t, theme.DueToday)
+	require.Equal(t, "\033[1;33m", theme.Priorities["A"])
+}
+
+//nolint:paralleltest // t.Setenv can not be used with t.Parallel
+func TestNewRenderer(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	require.True(t, NewRenderer().NoColor)
+
+	t.Setenv("NO_COLOR", "")
+	require.False(t, NewRenderer().NoColor, "empty NO_COLOR should be ignored")
+}
@@ -0,0 +1,250 @@
+package todo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ReportTimeLayout is the timestamp format of the lines in report.txt.
+const ReportTimeLayout = "2006-01-02T15:04:05"
+
+// ----------------------------------------------------------------------------
+//  Type: ReportEntry
+// ----------------------------------------------------------------------------
+
+// ReportEntry is a line of report.txt as written by the "report" command of
+// todo.sh. It holds the number of open and done tasks at the given time.
+//
+//	2024-01-02T15:04:05 12 34
+type ReportEntry struct {
+	Time time.Time `json:"time"` // Time of the report.
+	Open int       `json:"open"` // Open is the number of tasks in todo.txt.
+	Done int       `json:"done"` // Done is the number of tasks in done.txt.
+}
+
+// NewReportEntry returns a ReportEntry of the given lists at the time of the
+// clock. If clock is nil, the real time is used.
+//
+// Open is the number of not completed tasks in todo and Done is the number of
+// tasks in done plus the completed tasks in todo, as todo.sh archives the
+// completed tasks before the report. Unlike todo.sh, which counts the lines of
+// the files, the comment lines are not counted as they are not tasks; use
+// Workspace.Report for the counts of todo.sh.
+func NewReportEntry(todo, done TaskList, clock Clock) ReportEntry {
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	open := len(todo.Filter(FilterNotCompleted))
+
+	return ReportEntry{
+		Time: clock.Now(),
+		Open: open,
+		Done: len(todo) - open + len(done),
+	}
+}
+
+// ParseReportEntry parses a line of report.txt.
+func ParseReportEntry(line string) (ReportEntry, error) {
+	fields := strings.Fields(line)
+
+	const numFields = 3
+
+	if len(fields) != numFields {
+		return ReportEntry{}, errors.New("invalid report line: " + line)
+	}
+
+	//nolint:gosmopolitan // todo.sh writes the local time
+	parsed, err := time.ParseInLocation(ReportTimeLayout, fields[0], time.Local)
+	if err != nil {
+		return ReportEntry{}, errors.Wrap(err, "failed to parse time of report line")
+	}
+
+	open, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return ReportEntry{}, errors.Wrap(err, "failed to parse open count of report line")
+	}
+
+	done, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return ReportEntry{}, errors.Wrap(err, "failed to parse done count of report line")
+	}
+
+	return ReportEntry{Time: parsed, Open: open, Done: done}, nil
+}
+
+// SameCounts returns true if both entries have the same counts regardless of
+// the time.
+func (entry ReportEntry) SameCounts(other ReportEntry) bool {
+	return entry.Open == other.Open && entry.Done == other.Done
+}
+
+// String returns the entry in report.txt format.
+func (entry ReportEntry) String() string {
+	return fmt.Sprintf("%s %d %d", entry.Time.Format(ReportTimeLayout), entry.Open, entry.Done)
+}
+
+// ----------------------------------------------------------------------------
+//  Type: Report
+// ----------------------------------------------------------------------------
+
+// Report is the history of report.txt in chronological order.
+type Report []ReportEntry
+
+// LoadReport loads a Report from io.Reader. Blank lines are ignored.
+func LoadReport(reader io.Reader) (Report, error) {
+	report := Report{}
+	scanner := bufio.NewScanner(reader)
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.Trim(scanner.Text(), whitespaces)
+		if isEmpty(line) {
+			continue
+		}
+
+		entry, err := ParseReportEntry(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineNum)
+		}
+
+		report = append(report, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to load report")
+	}
+
+	return report, nil
+}
+
+// LoadReportFromPath loads a Report from a file (most likely "report.txt").
+// A non-existing file is an empty Report.
+func LoadReportFromPath(filename string) (Report, error) {
+	//nolint:gosec // filename is provided by user, same as LoadFromPath
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return Report{}, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open file: "+filename)
+	}
+
+	defer func() { _ = file.Close() }()
+
+	return LoadReport(file)
+}
+
+// AppendReportPath appends the entry to the report file, unless the counts are
+// the same as the last entry, like todo.sh does. It returns the last entry of
+// the file after the operation.
+//
+// The file is locked during the operation and replaced atomically.
+func AppendReportPath(filename string, entry ReportEntry) (ReportEntry, error) {
+	unlock, err := lockPath(filename)
+	if err != nil {
+		return ReportEntry{}, err
+	}
+
+	defer func() { _ = unlock() }()
+
+	report, err := LoadReportFromPath(filename)
+	if err != nil {
+		return ReportEntry{}, err
+	}
+
+	if last, found := report.Last(); found && last.SameCounts(entry) {
+		return last, nil
+	}
+
+	report = append(report, entry)
+
+	return entry, writeFileAtomic(filename, []byte(report.String()))
+}
+
+// Daily returns the report with the last entry of each day. It is useful to
+// plot a chart with one point per day.
+func (report Report) Daily() Report {
+	daily := Report{}
+
+	for _, entry := range report {
+		if last, found := daily.Last(); found && sameDay(last.Time, entry.Time) {
+			daily[len(daily)-1] = entry
+
+			continue
+		}
+
+		daily = append(daily, entry)
+	}
+
+	return daily
+}
+
+// Last returns the last entry of the report. It returns false if the report is
+// empty.
+func (report Report) Last() (ReportEntry, bool) {
+	if len(report) == 0 {
+		return ReportEntry{}, false
+	}
+
+	return report[len(report)-1], true
+}
+
+// Series returns the report as time series, suitable for charts.
+func (report Report) Series() ReportSeries {
+	series := ReportSeries{
+		Time: make([]time.Time, len(report)),
+		Open: make([]int, len(report)),
+		Done: make([]int, len(report)),
+	}
+
+	for i, entry := range report {
+		series.Time[i] = entry.Time
+		series.Open[i] = entry.Open
+		series.Done[i] = entry.Done
+	}
+
+	return series
+}
+
+// String returns the report in report.txt format.
+func (report Report) String() string {
+	var strBldr strings.Builder
+
+	for _, entry := range report {
+		strBldr.WriteString(entry.String() + NewLine)
+	}
+
+	return strBldr.String()
+}
+
+// ----------------------------------------------------------------------------
+//  Type: ReportSeries
+// ----------------------------------------------------------------------------
+
+// ReportSeries is a Report as time series. The slices have the same length and
+// the same index refers to the same entry.
+type ReportSeries struct {
+	Time []time.Time `json:"time"`
+	Open []int       `json:"open"`
+	Done []int       `json:"done"`
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// sameDay returns true if both times are on the same calendar day.
+func sameDay(timeA, timeB time.Time) bool {
+	yearA, monthA, dayA := timeA.Date()
+	yearB, monthB, dayB := timeB.Date()
+
+	return yearA == yearB && monthA == monthB && dayA == dayB
+}
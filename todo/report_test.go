@@ -0,0 +1,125 @@
+package todo
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReportEntry(t *testing.T) {
+	t.Parallel()
+
+	todo := testMustLoad(t, "Call Mom\nx Pick up milk\nLearn Go\n")
+	done := testMustLoad(t, "x Water plants\nx Feed cat\n")
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local)}
+
+	entry := NewReportEntry(todo, done, clock)
+
+	require.Equal(t, 2, entry.Open)
+	require.Equal(t, 3, entry.Done, "completed tasks in todo should be counted as done")
+	require.Equal(t, "2024-01-02T15:04:05 2 3", entry.String())
+
+	entry = NewReportEntry(NewTaskList(), NewTaskList(), nil)
+	require.False(t, entry.Time.IsZero(), "nil clock should use the real time")
+}
+
+func TestParseReportEntry(t *testing.T) {
+	t.Parallel()
+
+	entry, err := ParseReportEntry("2024-01-02T15:04:05 12 34")
+	require.NoError(t, err)
+	require.Equal(t, ReportEntry{Time: time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local), Open: 12, Done: 34}, entry)
+
+	for _, line := range []string{
+		"2024-01-02T15:04:05 12",
+		"2024-01-02 12 34",
+		"2024-01-02T15:04:05 a 34",
+		"2024-01-02T15:04:05 12 b",
+	} {
+		_, err := ParseReportEntry(line)
+		require.Error(t, err, "invalid line should fail: %q", line)
+	}
+}
+
+func TestLoadReport(t *testing.T) {
+	t.Parallel()
+
+	input := "2024-01-01T09:00:00 3 1\r\n\n2024-01-01T18:00:00 2 2\n2024-01-03T10:00:00 4 2\n"
+
+	report, err := LoadReport(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, report, 3, "blank lines should be ignored")
+	require.Equal(t, strings.ReplaceAll(
+		"2024-01-01T09:00:00 3 1\n2024-01-01T18:00:00 2 2\n2024-01-03T10:00:00 4 2\n", "\n", NewLine),
+		report.String())
+
+	last, found := report.Last()
+	require.True(t, found)
+	require.Equal(t, 4, last.Open)
+
+	_, found = Report{}.Last()
+	require.False(t, found)
+
+	_, err = LoadReport(strings.NewReader("2024-01-01T09:00:00 3 1\nfoo\n"))
+	require.ErrorContains(t, err, "line 2")
+}
+
+func TestReport_Daily_and_Series(t *testing.T) {
+	t.Parallel()
+
+	report, err := LoadReport(strings.NewReader(
+		"2024-01-01T09:00:00 3 1\n2024-01-01T18:00:00 2 2\n2024-01-03T10:00:00 4 2\n"))
+	require.NoError(t, err)
+
+	daily := report.Daily()
+	require.Len(t, daily, 2)
+	require.Equal(t, "2024-01-01T18:00:00 2 2", daily[0].String(), "the last entry of the day should be kept")
+
+	series := daily.Series()
+	require.Equal(t, []int{2, 4}, series.Open)
+	require.Equal(t, []int{2, 2}, series.Done)
+	require.Len(t, series.Time, 2)
+
+	encoded, err := json.Marshal(Report{}.Series())
+	require.NoError(t, err)
+	require.JSONEq(t, `{"time":[],"open":[],"done":[]}`, string(encoded))
+}
+
+func TestAppendReportPath(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "report.txt")
+
+	report, err := LoadReportFromPath(path)
+	require.NoError(t, err, "missing file should be an empty report")
+	require.Empty(t, report)
+
+	first := ReportEntry{Time: time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local), Open: 3, Done: 1}
+	same := ReportEntry{Time: time.Date(2024, 1, 2, 9, 0, 0, 0, time.Local), Open: 3, Done: 1}
+	changed := ReportEntry{Time: time.Date(2024, 1, 3, 9, 0, 0, 0, time.Local), Open: 2, Done: 2}
+
+	last, err := AppendReportPath(path, first)
+	require.NoError(t, err)
+	require.Equal(t, first, last)
+
+	last, err = AppendReportPath(path, same)
+	require.NoError(t, err)
+	require.Equal(t, first, last, "same counts should not be appended")
+
+	last, err = AppendReportPath(path, changed)
+	require.NoError(t, err)
+	require.Equal(t, changed, last)
+
+	require.Equal(t, "2024-01-01T09:00:00 3 1\n2024-01-03T09:00:00 2 2\n", testReadFile(t, path))
+
+	// Broken file
+	require.NoError(t, os.WriteFile(path, []byte("broken\n"), PermReadWrite))
+
+	_, err = AppendReportPath(path, changed)
+	require.Error(t, err)
+}
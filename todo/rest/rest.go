@@ -0,0 +1,726 @@
+/*
+Package rest serves a todo.txt file as a JSON REST API, so that several programs
+such as dashboards and chat bots can share a todo.txt file without handling the
+file themselves.
+
+The tasks are encoded as by todo.Task.MarshalJSON (see todo.JSONSchema). The
+routes, relative to the prefix of the handler (e.g. "/tasks/"), are:
+
+  - GET /: lists the tasks. See the query parameters below.
+  - POST /: creates a task from a JSON task, such as {"text": "Call Mom"}.
+  - GET /{id}: returns the task.
+  - PATCH /{id}: updates the task with a JSON merge patch (RFC 7396) of its
+    fields, such as {"priority": "A", "due_date": null}. A patch with "text"
+    replaces the whole task with the parsed text.
+  - POST /{id}/complete and POST /{id}/reopen: completes or reopens the task.
+  - DELETE /{id}: removes the task.
+
+The list accepts the query parameters:
+
+  - q: space separated terms which the task must contain, ignoring case. A term
+    starting with "-" excludes the tasks containing it.
+  - context, project: the task must have the context or the project. Repeat
+    the parameter to require several of them.
+  - priority: the task must have one of the priorities. Repeatable.
+  - status: "open", "done" or "all" (default).
+  - due: "overdue", "today", "any" or "none".
+  - sort: comma separated keys among "id", "text", "priority", "created",
+    "completed", "due", "context" and "project", with a leading "-" for the
+    descending order, such as "priority,-due".
+
+The ID of a task is its position in the file, as in todo.TaskList, so it
+changes when a task above it is removed. Each task has an ETag derived from its
+content and the list has an ETag derived from the file. Send it back in an
+"If-Match" header to apply a change only if the task, or the list for POST /,
+was not changed in the meantime; otherwise the response is "412 Precondition
+Failed". With RequireIfMatch, changes without "If-Match" are rejected with
+"428 Precondition Required".
+
+Changes are written via todo.UpdatePath, which locks the file and replaces it
+atomically.
+
+Example usage:
+
+	handler := rest.NewHandler("/home/me/todo/todo.txt", "/tasks/")
+
+	log.Fatal(http.ListenAndServe("127.0.0.1:8080", handler))
+
+The handler does no authentication; serve it on localhost or behind a reverse
+proxy which does.
+*/
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KEINOS/go-todotxt/todo"
+	"github.com/pkg/errors"
+)
+
+const (
+	emptyStr = ""
+
+	// contentTypeJSON is the content type of the responses.
+	contentTypeJSON = "application/json; charset=utf-8"
+	// maxBodySize is the maximum size of a request body.
+	maxBodySize = 1 << 20
+)
+
+// Errors of the update operations, mapped to HTTP status codes.
+var (
+	errNotFound             = errors.New("task not found")
+	errPrecondition         = errors.New("precondition failed")
+	errPreconditionRequired = errors.New("If-Match header is required")
+	errBadRequest           = errors.New("bad request")
+)
+
+// ----------------------------------------------------------------------------
+//  Type: Handler
+// ----------------------------------------------------------------------------
+
+// Handler is an http.Handler serving a todo.txt file as a JSON REST API.
+type Handler struct {
+	// Clock is used for the completed date and the "due" filter. Defaults to
+	// the real time.
+	Clock todo.Clock
+	// Path is the path of the todo.txt file. It is created on the first change
+	// if it does not exist.
+	Path string
+	// Prefix is the URL path of the list, with a trailing slash.
+	Prefix string
+	// RequireIfMatch rejects the changes without an "If-Match" header.
+	RequireIfMatch bool
+
+	mux *http.ServeMux
+}
+
+// NewHandler returns a Handler serving the todo.txt file at the URL path prefix
+// (e.g. "/tasks/"). An empty prefix is "/".
+func NewHandler(path, prefix string) *Handler {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	handler := &Handler{Clock: nil, Path: path, Prefix: prefix, RequireIfMatch: false, mux: http.NewServeMux()}
+
+	handler.mux.HandleFunc("GET "+prefix+"{$}", handler.list)
+	handler.mux.HandleFunc("POST "+prefix+"{$}", handler.create)
+	handler.mux.HandleFunc("GET "+prefix+"{id}", handler.get)
+	handler.mux.HandleFunc("PATCH "+prefix+"{id}", handler.patch)
+	handler.mux.HandleFunc("DELETE "+prefix+"{id}", handler.delete)
+	handler.mux.HandleFunc("POST "+prefix+"{id}/complete", handler.complete)
+	handler.mux.HandleFunc("POST "+prefix+"{id}/reopen", handler.reopen)
+
+	return handler
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	h.mux.ServeHTTP(writer, req)
+}
+
+// ----------------------------------------------------------------------------
+//  Methods: request handlers
+// ----------------------------------------------------------------------------
+
+// complete completes the task.
+func (h *Handler) complete(writer http.ResponseWriter, req *http.Request) {
+	h.update(writer, req, func(task *todo.Task) error {
+		if !task.Completed {
+			task.Completed = true
+			task.CompletedDate = h.now()
+		}
+
+		return nil
+	})
+}
+
+// create adds the task of the body to the list.
+func (h *Handler) create(writer http.ResponseWriter, req *http.Request) {
+	var task todo.Task
+
+	if err := decodeBody(req, &task); err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	task, err := validate(task)
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	err = todo.UpdatePath(h.Path, func(tasklist *todo.TaskList) error {
+		stamp, err := todo.StampPath(h.Path)
+		if err != nil {
+			return err
+		}
+
+		if err := h.checkIfMatch(req, listETag(stamp)); err != nil {
+			return err
+		}
+
+		tasklist.AddTask(&task)
+
+		return nil
+	})
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	writer.Header().Set("Location", h.Prefix+strconv.Itoa(task.ID))
+	writeJSON(writer, http.StatusCreated, ETag(&task), task)
+}
+
+// delete removes the task.
+func (h *Handler) delete(writer http.ResponseWriter, req *http.Request) {
+	id, found := taskID(req)
+	if !found {
+		writeError(writer, errNotFound)
+
+		return
+	}
+
+	err := todo.UpdatePath(h.Path, func(tasklist *todo.TaskList) error {
+		index := findTask(*tasklist, id)
+		if index < 0 {
+			return errNotFound
+		}
+
+		if err := h.checkIfMatch(req, ETag(&(*tasklist)[index])); err != nil {
+			return err
+		}
+
+		*tasklist = append((*tasklist)[:index], (*tasklist)[index+1:]...)
+
+		return nil
+	})
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// get returns the task.
+func (h *Handler) get(writer http.ResponseWriter, req *http.Request) {
+	tasklist, _, err := h.load()
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	id, found := taskID(req)
+
+	index := findTask(tasklist, id)
+	if !found || index < 0 {
+		writeError(writer, errNotFound)
+
+		return
+	}
+
+	etag := ETag(&tasklist[index])
+	if containsETag(req.Header.Get("If-None-Match"), etag) {
+		writer.Header().Set("ETag", etag)
+		writer.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, etag, tasklist[index])
+}
+
+// list returns the tasks filtered and sorted by the query parameters.
+func (h *Handler) list(writer http.ResponseWriter, req *http.Request) {
+	tasklist, stamp, err := h.load()
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	etag := listETag(stamp)
+	if containsETag(req.Header.Get("If-None-Match"), etag) {
+		writer.Header().Set("ETag", etag)
+		writer.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	query := req.URL.Query()
+
+	predicate, err := h.filter(query)
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	tasklist = tasklist.Filter(predicate)
+
+	if sortKeys := query.Get("sort"); sortKeys != emptyStr {
+		flags, err := parseSort(sortKeys)
+		if err != nil {
+			writeError(writer, err)
+
+			return
+		}
+
+		_ = tasklist.Sort(flags[0], flags[1:]...) // known flags only
+	}
+
+	writeJSON(writer, http.StatusOK, etag, tasklist)
+}
+
+// patch applies the JSON merge patch of the body to the task.
+func (h *Handler) patch(writer http.ResponseWriter, req *http.Request) {
+	var patch map[string]json.RawMessage
+
+	if err := decodeBody(req, &patch); err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	h.update(writer, req, func(task *todo.Task) error {
+		patched, err := applyPatch(*task, patch)
+		if err != nil {
+			return err
+		}
+
+		*task = patched
+
+		return nil
+	})
+}
+
+// reopen reopens the task.
+func (h *Handler) reopen(writer http.ResponseWriter, req *http.Request) {
+	h.update(writer, req, func(task *todo.Task) error {
+		task.Reopen()
+
+		return nil
+	})
+}
+
+// ----------------------------------------------------------------------------
+//  Methods: helpers
+// ----------------------------------------------------------------------------
+
+// checkIfMatch returns an error if the "If-Match" header does not match the
+// current ETag, or if it is missing and required.
+func (h *Handler) checkIfMatch(req *http.Request, current string) error {
+	ifMatch := req.Header.Get("If-Match")
+
+	switch {
+	case ifMatch == emptyStr && h.RequireIfMatch:
+		return errPreconditionRequired
+	case ifMatch == emptyStr || ifMatch == "*":
+		return nil
+	case !containsETag(ifMatch, current):
+		return errPrecondition
+	}
+
+	return nil
+}
+
+// filter returns the predicate of the query parameters of the list.
+func (h *Handler) filter(query map[string][]string) (todo.Predicate, error) {
+	predicates := []todo.Predicate{}
+
+	for _, context := range query["context"] {
+		predicates = append(predicates, todo.FilterByContext(strings.TrimPrefix(context, "@")))
+	}
+
+	for _, project := range query["project"] {
+		predicates = append(predicates, todo.FilterByProject(strings.TrimPrefix(project, "+")))
+	}
+
+	if priorities := query["priority"]; len(priorities) > 0 {
+		predicates = append(predicates, func(task todo.Task) bool {
+			for _, priority := range priorities {
+				if strings.EqualFold(task.Priority, priority) {
+					return true
+				}
+			}
+
+			return false
+		})
+	}
+
+	if terms := strings.Fields(strings.Join(query["q"], " ")); len(terms) > 0 {
+		predicates = append(predicates, func(task todo.Task) bool { return matchTerms(task.String(), terms) })
+	}
+
+	status, err := statusFilter(firstValue(query, "status"))
+	if err != nil {
+		return nil, err
+	}
+
+	due, err := h.dueFilter(firstValue(query, "due"))
+	if err != nil {
+		return nil, err
+	}
+
+	predicates = append(predicates, status, due)
+
+	return func(task todo.Task) bool {
+		for _, predicate := range predicates {
+			if !predicate(task) {
+				return false
+			}
+		}
+
+		return true
+	}, nil
+}
+
+// dueFilter returns the predicate of the "due" query parameter.
+func (h *Handler) dueFilter(value string) (todo.Predicate, error) {
+	now := h.now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	day := func(task todo.Task) time.Time {
+		return time.Date(task.DueDate.Year(), task.DueDate.Month(), task.DueDate.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	switch value {
+	case emptyStr:
+		return func(todo.Task) bool { return true }, nil
+	case "overdue":
+		return func(task todo.Task) bool {
+			return !task.Completed && task.HasDueDate() && day(task).Before(today)
+		}, nil
+	case "today":
+		return func(task todo.Task) bool { return task.HasDueDate() && day(task).Equal(today) }, nil
+	case "any":
+		return todo.FilterHasDueDate, nil
+	case "none":
+		return todo.FilterNot(todo.FilterHasDueDate), nil
+	default:
+		return nil, errors.Wrap(errBadRequest, "invalid due: "+value)
+	}
+}
+
+// load loads the task list and the stamp of the file. A non-existing file is an
+// empty list.
+func (h *Handler) load() (todo.TaskList, todo.FileStamp, error) {
+	tasklist, stamp, err := todo.LoadFromPathWithStamp(h.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return todo.NewTaskList(), todo.FileStamp{}, nil
+	}
+
+	return tasklist, stamp, err
+}
+
+// now returns the current time of the clock.
+func (h *Handler) now() time.Time {
+	if h.Clock == nil {
+		return time.Now()
+	}
+
+	return h.Clock.Now()
+}
+
+// update applies the change to the task of the request under the "If-Match"
+// precondition and responds with the changed task.
+func (h *Handler) update(writer http.ResponseWriter, req *http.Request, change func(task *todo.Task) error) {
+	id, found := taskID(req)
+	if !found {
+		writeError(writer, errNotFound)
+
+		return
+	}
+
+	var updated todo.Task
+
+	err := todo.UpdatePath(h.Path, func(tasklist *todo.TaskList) error {
+		index := findTask(*tasklist, id)
+		if index < 0 {
+			return errNotFound
+		}
+
+		task := &(*tasklist)[index]
+		if err := h.checkIfMatch(req, ETag(task)); err != nil {
+			return err
+		}
+
+		if err := change(task); err != nil {
+			return err
+		}
+
+		validated, err := validate(*task)
+		if err != nil {
+			return err
+		}
+
+		validated.ID = task.ID
+		*task = validated
+		updated = validated
+
+		return nil
+	})
+	if err != nil {
+		writeError(writer, err)
+
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, ETag(&updated), updated)
+}
+
+// ----------------------------------------------------------------------------
+//  Public functions
+// ----------------------------------------------------------------------------
+
+// ETag returns the entity tag of the task, derived from its todo.txt line. Any
+// change of the task changes the tag.
+func ETag(task *todo.Task) string {
+	sum := sha256.Sum256([]byte(task.String()))
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// applyPatch returns the task with the JSON merge patch applied to its JSON
+// encoding. A patch with "text" replaces the task with the parsed text.
+func applyPatch(task todo.Task, patch map[string]json.RawMessage) (todo.Task, error) {
+	fields := map[string]json.RawMessage{}
+
+	if _, found := patch["text"]; !found {
+		data, err := json.Marshal(task)
+		if err != nil {
+			return todo.Task{}, errors.Wrap(err, "failed to encode task")
+		}
+
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return todo.Task{}, errors.Wrap(err, "failed to encode task")
+		}
+
+		// Derived from the fields, so that they do not override them
+		delete(fields, "text")
+		delete(fields, "original")
+	}
+
+	for key, value := range patch {
+		if string(value) == "null" {
+			delete(fields, key)
+
+			continue
+		}
+
+		fields[key] = value
+	}
+
+	fields["id"] = json.RawMessage(strconv.Itoa(task.ID))
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return todo.Task{}, errors.Wrap(err, "failed to encode task")
+	}
+
+	var patched todo.Task
+
+	if err := json.Unmarshal(data, &patched); err != nil {
+		return todo.Task{}, errors.Wrap(errBadRequest, err.Error())
+	}
+
+	return patched, nil
+}
+
+// containsETag returns true if the comma separated list of the header holds the
+// ETag. Weak tags are compared as strong ones.
+func containsETag(header, etag string) bool {
+	for _, item := range strings.Split(header, ",") {
+		if item = strings.TrimSpace(item); item == "*" || strings.TrimPrefix(item, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decodeBody decodes the JSON body of the request.
+func decodeBody(req *http.Request, value any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, req.Body, maxBodySize))
+
+	if err := decoder.Decode(value); err != nil {
+		return errors.Wrap(errBadRequest, "invalid JSON body: "+err.Error())
+	}
+
+	return nil
+}
+
+// findTask returns the index of the task with the ID, or -1 if not found.
+func findTask(tasklist todo.TaskList, id int) int {
+	for i := range tasklist {
+		if tasklist[i].ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// firstValue returns the first value of the query parameter.
+func firstValue(query map[string][]string, key string) string {
+	if values := query[key]; len(values) > 0 {
+		return values[0]
+	}
+
+	return emptyStr
+}
+
+// listETag returns the entity tag of the list, derived from the file.
+func listETag(stamp todo.FileStamp) string {
+	return `"` + stamp.Hash + `"`
+}
+
+// matchTerms returns true if the text contains all the terms, ignoring case.
+// A term starting with "-" excludes the texts containing the rest of it.
+func matchTerms(text string, terms []string) bool {
+	text = strings.ToLower(text)
+
+	for _, term := range terms {
+		term = strings.ToLower(term)
+
+		if exclude, found := strings.CutPrefix(term, "-"); found && exclude != emptyStr {
+			if strings.Contains(text, exclude) {
+				return false
+			}
+
+			continue
+		}
+
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseSort returns the sort flags of the comma separated sort keys.
+func parseSort(value string) ([]todo.TaskSortByType, error) {
+	keys := map[string][2]todo.TaskSortByType{
+		"id":        {todo.SortTaskIDAsc, todo.SortTaskIDDesc},
+		"text":      {todo.SortTodoTextAsc, todo.SortTodoTextDesc},
+		"priority":  {todo.SortPriorityAsc, todo.SortPriorityDesc},
+		"created":   {todo.SortCreatedDateAsc, todo.SortCreatedDateDesc},
+		"completed": {todo.SortCompletedDateAsc, todo.SortCompletedDateDesc},
+		"due":       {todo.SortDueDateAsc, todo.SortDueDateDesc},
+		"context":   {todo.SortContextAsc, todo.SortContextDesc},
+		"project":   {todo.SortProjectAsc, todo.SortProjectDesc},
+	}
+
+	flags := []todo.TaskSortByType{}
+
+	for _, key := range strings.Split(value, ",") {
+		name, desc := strings.CutPrefix(strings.TrimSpace(key), "-")
+
+		pair, found := keys[name]
+		if !found {
+			return nil, errors.Wrap(errBadRequest, "invalid sort key: "+key)
+		}
+
+		if desc {
+			flags = append(flags, pair[1])
+		} else {
+			flags = append(flags, pair[0])
+		}
+	}
+
+	return flags, nil
+}
+
+// statusFilter returns the predicate of the "status" query parameter.
+func statusFilter(value string) (todo.Predicate, error) {
+	switch value {
+	case emptyStr, "all":
+		return func(todo.Task) bool { return true }, nil
+	case "open":
+		return todo.FilterNotCompleted, nil
+	case "done":
+		return todo.FilterCompleted, nil
+	default:
+		return nil, errors.Wrap(errBadRequest, "invalid status: "+value)
+	}
+}
+
+// taskID returns the ID of the path of the request.
+func taskID(req *http.Request) (int, bool) {
+	id, err := strconv.Atoi(req.PathValue("id"))
+
+	return id, err == nil && id > 0
+}
+
+// validate returns the task as it will be read back from the file, or an error
+// if it can not be.
+func validate(task todo.Task) (todo.Task, error) {
+	if strings.TrimSpace(task.Todo) == emptyStr {
+		return todo.Task{}, errors.Wrap(errBadRequest, "task text is empty")
+	}
+
+	parsed, err := todo.ParseTask(task.String())
+	if err != nil {
+		return todo.Task{}, errors.Wrap(errBadRequest, "invalid task: "+err.Error())
+	}
+
+	return *parsed, nil
+}
+
+// writeError writes the error as a JSON object with the HTTP status code of the
+// error, such as {"error": "task not found"}.
+func writeError(writer http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, errNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, errPrecondition):
+		status = http.StatusPreconditionFailed
+	case errors.Is(err, errPreconditionRequired):
+		status = http.StatusPreconditionRequired
+	case errors.Is(err, errBadRequest):
+		status = http.StatusBadRequest
+	}
+
+	writeJSON(writer, status, emptyStr, map[string]string{"error": errors.Cause(err).Error()})
+}
+
+// writeJSON writes the value as JSON with the status code and the ETag, if
+// not empty.
+func writeJSON(writer http.ResponseWriter, status int, etag string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", contentTypeJSON)
+
+	if etag != emptyStr {
+		writer.Header().Set("ETag", etag)
+	}
+
+	writer.WriteHeader(status)
+	_, _ = writer.Write(append(data, '\n'))
+}
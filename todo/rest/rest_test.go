@@ -0,0 +1,306 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KEINOS/go-todotxt/todo"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a test helper that implements the todo.Clock interface.
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+const testTodoTxt = `(A) 2024-01-01 Call Mom @Phone +Family due:2024-01-02
+(B) Pay bills +Family due:2024-01-01
+x 2024-01-01 Buy milk @Store
+Learn Go +Study
+`
+
+// testHandler returns a Handler of a temporary todo.txt file with the content.
+func testHandler(t *testing.T, content string) *Handler {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "todo.txt")
+	require.NoError(t, os.WriteFile(path, []byte(content), todo.PermReadWrite))
+
+	handler := NewHandler(path, "/tasks")
+	handler.Clock = &fakeClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	return handler
+}
+
+// testDo serves the request and returns the recorded response.
+func testDo(t *testing.T, handler http.Handler, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+// testTexts returns the "text" fields of the JSON list of the response.
+func testTexts(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+
+	var tasks []struct {
+		Text string `json:"text"`
+	}
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tasks), rec.Body.String())
+
+	texts := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		texts = append(texts, task.Text)
+	}
+
+	return texts
+}
+
+// testReadFile returns the content of the file of the handler, with "\n" as
+// the line separator.
+func testReadFile(t *testing.T, handler *Handler) string {
+	t.Helper()
+
+	raw, err := os.ReadFile(handler.Path)
+	require.NoError(t, err)
+
+	return strings.ReplaceAll(string(raw), todo.NewLine, "\n")
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "/tasks/", NewHandler("todo.txt", "/tasks").Prefix)
+	require.Equal(t, "/", NewHandler("todo.txt", "").Prefix)
+}
+
+func TestHandler_list(t *testing.T) {
+	t.Parallel()
+
+	handler := testHandler(t, testTodoTxt)
+
+	rec := testDo(t, handler, http.MethodGet, "/tasks/", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, contentTypeJSON, rec.Header().Get("Content-Type"))
+	require.Len(t, testTexts(t, rec), 4)
+
+	etag := rec.Header().Get("ETag")
+	require.Regexp(t, `^"[0-9a-f]+"$`, etag)
+
+	rec = testDo(t, handler, http.MethodGet, "/tasks/", "", "If-None-Match", etag)
+	require.Equal(t, http.StatusNotModified, rec.Code)
+
+	for query, expect := range map[string][]string{
+		"?project=Family&status=open": {
+			"(A) 2024-01-01 Call Mom @Phone +Family due:2024-01-02",
+			"(B) Pay bills +Family due:2024-01-01",
+		},
+		"?context=@Store":        {"x 2024-01-01 Buy milk @Store"},
+		"?priority=b&priority=C": {"(B) Pay bills +Family due:2024-01-01"},
+		"?status=done":           {"x 2024-01-01 Buy milk @Store"},
+		"?due=overdue":           {"(B) Pay bills +Family due:2024-01-01"},
+		"?due=today":             {"(A) 2024-01-01 Call Mom @Phone +Family due:2024-01-02"},
+		"?due=none&q=-milk":      {"Learn Go +Study"},
+		"?q=CALL+mom":            {"(A) 2024-01-01 Call Mom @Phone +Family due:2024-01-02"},
+		"?due=any&sort=due,-priority": {
+			"(B) Pay bills +Family due:2024-01-01",
+			"(A) 2024-01-01 Call Mom @Phone +Family due:2024-01-02",
+		},
+		"?sort=-id&status=open": {
+			"Learn Go +Study",
+			"(B) Pay bills +Family due:2024-01-01",
+			"(A) 2024-01-01 Call Mom @Phone +Family due:2024-01-02",
+		},
+	} {
+		rec := testDo(t, handler, http.MethodGet, "/tasks/"+query, "")
+		require.Equal(t, http.StatusOK, rec.Code, query)
+		require.Equal(t, expect, testTexts(t, rec), query)
+	}
+
+	for _, query := range []string{"?status=later", "?due=soon", "?sort=size"} {
+		rec := testDo(t, handler, http.MethodGet, "/tasks/"+query, "")
+		require.Equal(t, http.StatusBadRequest, rec.Code, query)
+		require.Contains(t, rec.Body.String(), `"error":`)
+	}
+}
+
+func TestHandler_list_missing_file(t *testing.T) {
+	t.Parallel()
+
+	handler := NewHandler(filepath.Join(t.TempDir(), "todo.txt"), "/")
+
+	rec := testDo(t, handler, http.MethodGet, "/", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "[]\n", rec.Body.String())
+}
+
+func TestHandler_get(t *testing.T) {
+	t.Parallel()
+
+	handler := testHandler(t, testTodoTxt)
+
+	rec := testDo(t, handler, http.MethodGet, "/tasks/4", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var task todo.Task
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &task))
+	require.Equal(t, 4, task.ID)
+	require.Equal(t, "Learn Go +Study", task.String())
+	require.Equal(t, ETag(&task), rec.Header().Get("ETag"))
+
+	rec = testDo(t, handler, http.MethodGet, "/tasks/4", "", "If-None-Match", `W/`+ETag(&task))
+	require.Equal(t, http.StatusNotModified, rec.Code)
+
+	for _, path := range []string{"/tasks/5", "/tasks/0", "/tasks/abc"} {
+		rec = testDo(t, handler, http.MethodGet, path, "")
+		require.Equal(t, http.StatusNotFound, rec.Code, path)
+		require.Equal(t, `{"error":"task not found"}`+"\n", rec.Body.String())
+	}
+}
+
+func TestHandler_create(t *testing.T) {
+	t.Parallel()
+
+	handler := testHandler(t, "Call Mom\n")
+
+	rec := testDo(t, handler, http.MethodPost, "/tasks/", `{"todo": "Pay bills", "priority": "B", "projects": ["Family"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Equal(t, "/tasks/2", rec.Header().Get("Location"))
+	require.NotEmpty(t, rec.Header().Get("ETag"))
+	require.Contains(t, rec.Body.String(), `"text":"(B) Pay bills +Family"`)
+	require.Equal(t, "Call Mom\n(B) Pay bills +Family\n", testReadFile(t, handler))
+
+	rec = testDo(t, handler, http.MethodPost, "/tasks/", `{"text": "Buy milk @Store"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "/tasks/3", rec.Header().Get("Location"))
+
+	for _, body := range []string{`{"text": ""}`, `{"text": "Pay due:2024-02-31"}`, `[`} {
+		rec = testDo(t, handler, http.MethodPost, "/tasks/", body)
+		require.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+
+	require.Equal(t, "Call Mom\n(B) Pay bills +Family\nBuy milk @Store\n", testReadFile(t, handler))
+}
+
+func TestHandler_create_IfMatch(t *testing.T) {
+	t.Parallel()
+
+	handler := testHandler(t, "Call Mom\n")
+	handler.RequireIfMatch = true
+
+	rec := testDo(t, handler, http.MethodPost, "/tasks/", `{"text": "Pay bills"}`)
+	require.Equal(t, http.StatusPreconditionRequired, rec.Code)
+
+	etag := testDo(t, handler, http.MethodGet, "/tasks/", "").Header().Get("ETag")
+
+	rec = testDo(t, handler, http.MethodPost, "/tasks/", `{"text": "Pay bills"}`, "If-Match", etag)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	// The list has changed since
+	rec = testDo(t, handler, http.MethodPost, "/tasks/", `{"text": "Buy milk"}`, "If-Match", etag)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	require.Equal(t, "Call Mom\nPay bills\n", testReadFile(t, handler))
+}
+
+func TestHandler_patch(t *testing.T) {
+	t.Parallel()
+
+	handler := testHandler(t, testTodoTxt)
+
+	rec := testDo(t, handler, http.MethodPatch, "/tasks/2", `{"priority": "C", "due_date": null, "contexts": ["Home"]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), `"text":"(C) Pay bills @Home +Family"`)
+	require.Contains(t, testReadFile(t, handler), "\n(C) Pay bills @Home +Family\n")
+
+	// Text replaces the task
+	rec = testDo(t, handler, http.MethodPatch, "/tasks/4", `{"text": "Learn Rust +Study"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, testReadFile(t, handler), "\nLearn Rust +Study\n")
+
+	rec = testDo(t, handler, http.MethodPatch, "/tasks/4", `{"todo": ""}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = testDo(t, handler, http.MethodPatch, "/tasks/4", `{"priority": 1}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = testDo(t, handler, http.MethodPatch, "/tasks/9", `{"priority": "A"}`)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandler_complete_reopen(t *testing.T) {
+	t.Parallel()
+
+	handler := testHandler(t, "(A) Call Mom\n")
+
+	rec := testDo(t, handler, http.MethodPost, "/tasks/1/complete", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "x 2024-01-02 Call Mom\n", testReadFile(t, handler), "priority should be removed")
+
+	etag := rec.Header().Get("ETag")
+
+	// Completing again is a no-op
+	rec = testDo(t, handler, http.MethodPost, "/tasks/1/complete", "", "If-Match", etag)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, etag, rec.Header().Get("ETag"))
+
+	rec = testDo(t, handler, http.MethodPost, "/tasks/1/reopen", "", "If-Match", etag)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "Call Mom\n", testReadFile(t, handler))
+
+	// Stale ETag
+	rec = testDo(t, handler, http.MethodPost, "/tasks/1/complete", "", "If-Match", etag)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	require.Equal(t, "Call Mom\n", testReadFile(t, handler))
+}
+
+func TestHandler_delete(t *testing.T) {
+	t.Parallel()
+
+	handler := testHandler(t, testTodoTxt)
+
+	rec := testDo(t, handler, http.MethodDelete, "/tasks/3", "", "If-Match", `"stale"`)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = testDo(t, handler, http.MethodDelete, "/tasks/3", "", "If-Match", "*")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.NotContains(t, testReadFile(t, handler), "Buy milk")
+
+	rec = testDo(t, handler, http.MethodDelete, "/tasks/4", "")
+	require.Equal(t, http.StatusNotFound, rec.Code, "tasks below should be renumbered")
+
+	rec = testDo(t, handler, http.MethodPut, "/tasks/1", "")
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestETag(t *testing.T) {
+	t.Parallel()
+
+	task1, err := todo.ParseTask("Call Mom")
+	require.NoError(t, err)
+
+	task2, err := todo.ParseTask("Call Mom @Phone")
+	require.NoError(t, err)
+
+	require.Regexp(t, `^"[0-9a-f]{32}"$`, ETag(task1))
+	require.NotEqual(t, ETag(task1), ETag(task2))
+}
@@ -0,0 +1,59 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/KEINOS/go-todotxt/todo"
+	"github.com/pkg/errors"
+)
+
+// BurnPoint is the state of a task list at the end of a day.
+//
+// For a burndown chart plot Open, for a burnup chart plot Done against Total.
+type BurnPoint struct {
+	Date  string `json:"date"`  // Date of the point in "YYYY-MM-DD" format.
+	Total int    `json:"total"` // Total is the number of tasks created until the date.
+	Done  int    `json:"done"`  // Done is the number of tasks completed until the date.
+	Open  int    `json:"open"`  // Open is Total minus Done.
+}
+
+// Burndown returns one BurnPoint per day from the date of "from" to the date of
+// "to", both inclusive. If "to" is zero, today of the Clock in opts is used.
+//
+// Tasks without a created date are counted from the start. Completed tasks
+// without a completed date are counted as done from the start, and completed
+// tasks are counted as created at the latest on their completion.
+func Burndown(tasklist todo.TaskList, from, to time.Time, opts Options) ([]BurnPoint, error) {
+	if to.IsZero() {
+		to = opts.now()
+	}
+
+	first, last := dayOf(from), dayOf(to)
+	if last.Before(first) {
+		return nil, errors.New("end date is before start date: " + formatDay(last) + " < " + formatDay(first))
+	}
+
+	points := []BurnPoint{}
+
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		point := BurnPoint{Date: formatDay(day), Total: 0, Done: 0, Open: 0}
+
+		for _, task := range tasklist {
+			done := task.Completed && (!task.HasCompletedDate() || !dayOf(task.CompletedDate).After(day))
+			created := !task.HasCreatedDate() || !dayOf(task.CreatedDate).After(day)
+
+			if done {
+				point.Done++
+			}
+
+			if done || created {
+				point.Total++
+			}
+		}
+
+		point.Open = point.Total - point.Done
+		points = append(points, point)
+	}
+
+	return points, nil
+}
@@ -0,0 +1,38 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBurndown(t *testing.T) {
+	t.Parallel()
+
+	tasklist := testMustLoad(t, `2024-01-02 Call Mom
+x 2024-01-03 2024-01-01 Buy seeds
+x 2024-01-02 Water plants
+Read book
+x Old task
+`)
+	from := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	to := time.Date(2024, 1, 3, 0, 0, 0, 0, time.Local)
+
+	points, err := Burndown(tasklist, from, to, Options{})
+	require.NoError(t, err)
+	require.Equal(t, []BurnPoint{
+		{Date: "2024-01-01", Total: 4, Done: 1, Open: 3},
+		{Date: "2024-01-02", Total: 5, Done: 2, Open: 3},
+		{Date: "2024-01-03", Total: 5, Done: 3, Open: 2},
+	}, points)
+
+	// Until today of the clock
+	points, err = Burndown(tasklist, to, time.Time{}, Options{Clock: testClock(), AgingBuckets: nil})
+	require.NoError(t, err)
+	require.Len(t, points, 15)
+	require.Equal(t, "2024-01-17", points[14].Date)
+
+	_, err = Burndown(tasklist, to, from, Options{})
+	require.ErrorContains(t, err, "end date is before start date")
+}
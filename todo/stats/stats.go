@@ -0,0 +1,282 @@
+/*
+Package stats computes statistics of todo.txt task lists, such as the open and
+done counts per project and context, the weekly throughput, the lead time, the
+overdue tasks, the aging of open tasks and burndown/burnup series.
+
+The results are plain structs which can be encoded to JSON as they are. Dates
+are encoded in todo.txt format ("YYYY-MM-DD"). All the date math is done with
+the Clock of the Options, so the results are reproducible in tests.
+
+Example usage:
+
+	tasklist, err := todo.LoadFromPath("todo.txt")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	summary := stats.Compute(tasklist, stats.Options{})
+
+	fmt.Println(summary.Total.Open, summary.Overdue)
+*/
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/KEINOS/go-todotxt/todo"
+)
+
+// DefaultAgingBuckets are the upper bounds in days of the aging buckets used if
+// Options.AgingBuckets is empty.
+//
+//nolint:gochecknoglobals // it is intentionally global as a default
+var DefaultAgingBuckets = []int{7, 30, 90}
+
+// ----------------------------------------------------------------------------
+//  Type: Options
+// ----------------------------------------------------------------------------
+
+// Options are the options of Compute.
+type Options struct {
+	// Clock is used to calculate the overdue tasks and the age of open tasks.
+	// Defaults to the real time.
+	Clock todo.Clock
+	// AgingBuckets are the inclusive upper bounds in days of the aging buckets,
+	// in ascending order. A last bucket without upper bound is always added.
+	// Defaults to DefaultAgingBuckets.
+	AgingBuckets []int
+}
+
+// now returns the current time of the Clock.
+func (opts Options) now() time.Time {
+	if opts.Clock == nil {
+		return time.Now()
+	}
+
+	return opts.Clock.Now()
+}
+
+// ----------------------------------------------------------------------------
+//  Types: Results
+// ----------------------------------------------------------------------------
+
+// Counts holds the number of open and done tasks.
+type Counts struct {
+	Open int `json:"open"`
+	Done int `json:"done"`
+}
+
+// WeekCount is the number of tasks completed in a week.
+type WeekCount struct {
+	Week  string `json:"week"`  // Week is the Monday of the week in "YYYY-MM-DD" format.
+	Count int    `json:"count"` // Count of the completed tasks in the week.
+}
+
+// LeadTime is the time from creation to completion of the completed tasks.
+// Only tasks with both the created and the completed dates are counted.
+type LeadTime struct {
+	Count       int     `json:"count"`        // Count of the counted tasks.
+	AverageDays float64 `json:"average_days"` // AverageDays is the average lead time in days.
+	MinDays     int     `json:"min_days"`     // MinDays is the shortest lead time in days.
+	MaxDays     int     `json:"max_days"`     // MaxDays is the longest lead time in days.
+}
+
+// AgingBucket is the number of open tasks whose age, the days since creation, is
+// within MinDays and MaxDays. MaxDays is -1 for the last bucket, which has no
+// upper bound.
+type AgingBucket struct {
+	MinDays int `json:"min_days"`
+	MaxDays int `json:"max_days"`
+	Count   int `json:"count"`
+}
+
+// Summary is the result of Compute.
+type Summary struct {
+	Projects   map[string]Counts `json:"projects"`   // Projects holds the counts per project.
+	Contexts   map[string]Counts `json:"contexts"`   // Contexts holds the counts per context.
+	Throughput []WeekCount       `json:"throughput"` // Throughput per week, from the first to the last completion.
+	Aging      []AgingBucket     `json:"aging"`      // Aging of the open tasks with a created date.
+	LeadTime   LeadTime          `json:"lead_time"`  // LeadTime of the completed tasks.
+	Total      Counts            `json:"total"`      // Total counts of the task list.
+	Overdue    int               `json:"overdue"`    // Overdue is the number of open tasks past their due date.
+	Undated    int               `json:"undated"`    // Undated is the number of open tasks without a created date.
+}
+
+// ----------------------------------------------------------------------------
+//  Public functions
+// ----------------------------------------------------------------------------
+
+// Compute returns the statistics of the task list. To include archived tasks,
+// pass the tasks of both todo.txt and done.txt.
+func Compute(tasklist todo.TaskList, opts Options) Summary {
+	today := dayOf(opts.now())
+	summary := Summary{
+		Projects:   map[string]Counts{},
+		Contexts:   map[string]Counts{},
+		Throughput: throughput(tasklist),
+		Aging:      newAgingBuckets(opts.AgingBuckets),
+		LeadTime:   LeadTime{Count: 0, AverageDays: 0, MinDays: 0, MaxDays: 0},
+		Total:      Counts{Open: 0, Done: 0},
+		Overdue:    0,
+		Undated:    0,
+	}
+
+	leadTotal := 0
+
+	for _, task := range tasklist {
+		countTask(&summary.Total, task)
+
+		for _, project := range task.Projects {
+			counts := summary.Projects[project]
+			countTask(&counts, task)
+			summary.Projects[project] = counts
+		}
+
+		for _, context := range task.Contexts {
+			counts := summary.Contexts[context]
+			countTask(&counts, task)
+			summary.Contexts[context] = counts
+		}
+
+		if task.Completed {
+			if task.HasCompletedDate() && task.HasCreatedDate() {
+				days := daysBetween(task.CreatedDate, task.CompletedDate)
+				leadTotal += days
+
+				addLeadTime(&summary.LeadTime, days)
+			}
+
+			continue
+		}
+
+		if task.HasDueDate() && dayOf(task.DueDate).Before(today) {
+			summary.Overdue++
+		}
+
+		if !task.HasCreatedDate() {
+			summary.Undated++
+
+			continue
+		}
+
+		addAging(summary.Aging, daysBetween(task.CreatedDate, today))
+	}
+
+	if summary.LeadTime.Count > 0 {
+		summary.LeadTime.AverageDays = float64(leadTotal) / float64(summary.LeadTime.Count)
+	}
+
+	return summary
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// addAging counts the age in the bucket it belongs to.
+func addAging(buckets []AgingBucket, age int) {
+	for i := range buckets {
+		if buckets[i].MaxDays < 0 || age <= buckets[i].MaxDays {
+			buckets[i].Count++
+
+			return
+		}
+	}
+}
+
+// addLeadTime counts the lead time in days, except the average.
+func addLeadTime(leadTime *LeadTime, days int) {
+	if leadTime.Count == 0 || days < leadTime.MinDays {
+		leadTime.MinDays = days
+	}
+
+	if leadTime.Count == 0 || days > leadTime.MaxDays {
+		leadTime.MaxDays = days
+	}
+
+	leadTime.Count++
+}
+
+// countTask counts the task as open or done.
+func countTask(counts *Counts, task todo.Task) {
+	if task.Completed {
+		counts.Done++
+	} else {
+		counts.Open++
+	}
+}
+
+// dayOf returns the calendar day of the time as midnight UTC, so that days can
+// be compared and subtracted regardless of time zones and daylight saving time.
+func dayOf(t time.Time) time.Time {
+	year, month, day := t.Date()
+
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// daysBetween returns the number of calendar days from a to b.
+func daysBetween(a, b time.Time) int {
+	return int(dayOf(b).Sub(dayOf(a)) / (24 * time.Hour))
+}
+
+// formatDay returns the day in todo.txt date format.
+func formatDay(day time.Time) string {
+	return day.Format(todo.DateLayout)
+}
+
+// newAgingBuckets returns empty buckets of the given upper bounds.
+func newAgingBuckets(bounds []int) []AgingBucket {
+	if len(bounds) == 0 {
+		bounds = DefaultAgingBuckets
+	}
+
+	buckets := make([]AgingBucket, 0, len(bounds)+1)
+	minDays := 0
+
+	for _, maxDays := range bounds {
+		buckets = append(buckets, AgingBucket{MinDays: minDays, MaxDays: maxDays, Count: 0})
+		minDays = maxDays + 1
+	}
+
+	return append(buckets, AgingBucket{MinDays: minDays, MaxDays: -1, Count: 0})
+}
+
+// throughput returns the number of completed tasks per week, including the
+// weeks without completions between the first and the last one.
+func throughput(tasklist todo.TaskList) []WeekCount {
+	perWeek := map[time.Time]int{}
+
+	for _, task := range tasklist {
+		if task.HasCompletedDate() {
+			perWeek[weekOf(task.CompletedDate)]++
+		}
+	}
+
+	weeks := make([]time.Time, 0, len(perWeek))
+	for week := range perWeek {
+		weeks = append(weeks, week)
+	}
+
+	sort.Slice(weeks, func(i, j int) bool { return weeks[i].Before(weeks[j]) })
+
+	result := []WeekCount{}
+
+	if len(weeks) == 0 {
+		return result
+	}
+
+	for week := weeks[0]; !week.After(weeks[len(weeks)-1]); week = week.AddDate(0, 0, 7) {
+		result = append(result, WeekCount{Week: formatDay(week), Count: perWeek[week]})
+	}
+
+	return result
+}
+
+// weekOf returns the Monday of the week of the time.
+func weekOf(t time.Time) time.Time {
+	day := dayOf(t)
+	offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+
+	return day.AddDate(0, 0, -offset)
+}
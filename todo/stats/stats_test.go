@@ -0,0 +1,114 @@
+package stats
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/KEINOS/go-todotxt/todo"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a test helper that implements the todo.Clock interface.
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+// testClock is the clock of the tests, on Wednesday 2024-01-17.
+func testClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 17, 10, 0, 0, 0, time.Local)}
+}
+
+// testMustLoad returns the TaskList of the string or fails the test.
+func testMustLoad(t *testing.T, str string) todo.TaskList {
+	t.Helper()
+
+	tasklist, err := todo.LoadFromString(str)
+	require.NoError(t, err)
+
+	return tasklist
+}
+
+const testTodoTxt = `2024-01-16 Call Mom @Phone +Family
+2024-01-01 Plan garden +Garden due:2024-01-10
+2023-10-01 Fix fence +Garden @Home
+Read book
+x 2024-01-03 2024-01-01 Buy seeds +Garden @Store
+x 2024-01-16 2024-01-06 Water plants +Garden @Home due:2024-01-10
+x 2024-01-17 2024-01-17 Pay bills
+x Old task
+`
+
+func TestCompute(t *testing.T) {
+	t.Parallel()
+
+	summary := Compute(testMustLoad(t, testTodoTxt), Options{Clock: testClock(), AgingBuckets: nil})
+
+	require.Equal(t, Counts{Open: 4, Done: 4}, summary.Total)
+	require.Equal(t, map[string]Counts{
+		"Family": {Open: 1, Done: 0},
+		"Garden": {Open: 2, Done: 2},
+	}, summary.Projects)
+	require.Equal(t, map[string]Counts{
+		"Phone": {Open: 1, Done: 0},
+		"Home":  {Open: 1, Done: 1},
+		"Store": {Open: 0, Done: 1},
+	}, summary.Contexts)
+
+	require.Equal(t, 1, summary.Overdue, "only open tasks should be overdue")
+	require.Equal(t, 1, summary.Undated)
+
+	require.Equal(t, []WeekCount{
+		{Week: "2024-01-01", Count: 1},
+		{Week: "2024-01-08", Count: 0},
+		{Week: "2024-01-15", Count: 2},
+	}, summary.Throughput, "weeks should start on Monday and include empty weeks")
+
+	require.Equal(t, LeadTime{Count: 3, AverageDays: 4, MinDays: 0, MaxDays: 10}, summary.LeadTime)
+
+	require.Equal(t, []AgingBucket{
+		{MinDays: 0, MaxDays: 7, Count: 1},
+		{MinDays: 8, MaxDays: 30, Count: 1},
+		{MinDays: 31, MaxDays: 90, Count: 0},
+		{MinDays: 91, MaxDays: -1, Count: 1},
+	}, summary.Aging)
+}
+
+func TestCompute_custom_buckets(t *testing.T) {
+	t.Parallel()
+
+	summary := Compute(testMustLoad(t, testTodoTxt), Options{Clock: testClock(), AgingBuckets: []int{1}})
+
+	require.Equal(t, []AgingBucket{
+		{MinDays: 0, MaxDays: 1, Count: 1},
+		{MinDays: 2, MaxDays: -1, Count: 2},
+	}, summary.Aging)
+}
+
+func TestCompute_empty(t *testing.T) {
+	t.Parallel()
+
+	summary := Compute(todo.NewTaskList(), Options{})
+
+	encoded, err := json.Marshal(summary)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"projects": {},
+		"contexts": {},
+		"throughput": [],
+		"aging": [
+			{"min_days": 0, "max_days": 7, "count": 0},
+			{"min_days": 8, "max_days": 30, "count": 0},
+			{"min_days": 31, "max_days": 90, "count": 0},
+			{"min_days": 91, "max_days": -1, "count": 0}
+		],
+		"lead_time": {"count": 0, "average_days": 0, "min_days": 0, "max_days": 0},
+		"total": {"open": 0, "done": 0},
+		"overdue": 0,
+		"undated": 0
+	}`, string(encoded))
+}
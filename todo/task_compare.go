@@ -0,0 +1,258 @@
+package todo
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: TaskField
+// ----------------------------------------------------------------------------
+
+// TaskField represents a field of a Task that is compared field by field, such
+// as on merge.
+type TaskField string
+
+// Fields of a Task which are compared individually.
+const (
+	FieldCompleted   TaskField = "completed"    // Completed flag and CompletedDate.
+	FieldPriority    TaskField = "priority"     // Priority.
+	FieldCreatedDate TaskField = "created_date" // CreatedDate.
+	FieldTodo        TaskField = "todo"         // Todo text.
+	FieldContexts    TaskField = "contexts"     // Contexts.
+	FieldProjects    TaskField = "projects"     // Projects.
+	FieldTags        TaskField = "tags"         // AdditionalTags.
+	FieldDueDate     TaskField = "due_date"     // DueDate.
+)
+
+// ----------------------------------------------------------------------------
+//  Field accessors
+// ----------------------------------------------------------------------------
+
+// fuzzyMatchThreshold is the minimum similarity of the todo texts for two tasks
+// to be considered the same task, if nothing else matches.
+const fuzzyMatchThreshold = 0.75
+
+// scalarField gets and copies a single valued field of a task. The value is
+// formatted as a string so it can be compared.
+type scalarField struct {
+	get   func(task *Task) string
+	set   func(dst, src *Task)
+	field TaskField
+}
+
+// scalarFields is the list of single valued fields in the order of the task
+// segments.
+var scalarFields = []scalarField{
+	{
+		field: FieldCompleted,
+		get: func(task *Task) string {
+			if !task.Completed {
+				return emptyStr
+			}
+
+			return "x " + formatDate(task.CompletedDate)
+		},
+		set: func(dst, src *Task) {
+			dst.Completed = src.Completed
+			dst.CompletedDate = src.CompletedDate
+		},
+	},
+	{
+		field: FieldPriority,
+		get:   func(task *Task) string { return task.Priority },
+		set:   func(dst, src *Task) { dst.Priority = src.Priority },
+	},
+	{
+		field: FieldCreatedDate,
+		get:   func(task *Task) string { return formatDate(task.CreatedDate) },
+		set:   func(dst, src *Task) { dst.CreatedDate = src.CreatedDate },
+	},
+	{
+		field: FieldTodo,
+		get:   func(task *Task) string { return task.Todo },
+		set:   func(dst, src *Task) { dst.Todo = src.Todo },
+	},
+	{
+		field: FieldDueDate,
+		get:   func(task *Task) string { return formatDate(task.DueDate) },
+		set:   func(dst, src *Task) { dst.DueDate = src.DueDate },
+	},
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// cloneTask returns a deep copy of the given task.
+func cloneTask(task Task) Task {
+	clone := task
+
+	if task.Contexts != nil {
+		clone.Contexts = append([]string{}, task.Contexts...)
+	}
+
+	if task.Projects != nil {
+		clone.Projects = append([]string{}, task.Projects...)
+	}
+
+	if task.AdditionalTags != nil {
+		clone.AdditionalTags = make(map[string]string, len(task.AdditionalTags))
+		for key, value := range task.AdditionalTags {
+			clone.AdditionalTags[key] = value
+		}
+	}
+
+	return clone
+}
+
+// formatDate formats the date in todo.txt format. The zero date is an empty
+// string.
+func formatDate(date time.Time) string {
+	if date.IsZero() {
+		return emptyStr
+	}
+
+	return date.Format(DateLayout)
+}
+
+// matchTasks pairs the tasks of listA with the tasks of listB. It returns a
+// slice with the index in listB for each task in listA, or -1 if the task has
+// no counterpart.
+//
+// Tasks are matched in the following order of precedence: same UIDTag value,
+// same todo.txt line, same normalized todo text and finally the most similar
+// todo text above fuzzyMatchThreshold.
+func matchTasks(listA, listB TaskList) []int {
+	matches, usedB := matchTasksExact(listA, listB)
+
+	matchFuzzy(listA, listB, matches, usedB)
+
+	return matches
+}
+
+// matchTasksExact is similar to matchTasks but without the fuzzy matching. It
+// also returns which tasks of listB have been matched.
+func matchTasksExact(listA, listB TaskList) ([]int, []bool) {
+	matches := make([]int, len(listA))
+	usedB := make([]bool, len(listB))
+
+	for i := range matches {
+		matches[i] = -1
+	}
+
+	keyFuncs := []func(task *Task) string{
+		func(task *Task) string { return task.AdditionalTags[UIDTag] },
+		func(task *Task) string { return task.String() },
+		func(task *Task) string { return normalizeText(task.Todo) },
+	}
+
+	for _, keyFunc := range keyFuncs {
+		indexB := map[string][]int{}
+
+		for j := range listB {
+			if key := keyFunc(&listB[j]); !usedB[j] && isNotEmpty(key) {
+				indexB[key] = append(indexB[key], j)
+			}
+		}
+
+		for i := range listA {
+			key := keyFunc(&listA[i])
+			if matches[i] >= 0 || isEmpty(key) || len(indexB[key]) == 0 {
+				continue
+			}
+
+			matches[i], indexB[key] = indexB[key][0], indexB[key][1:]
+			usedB[matches[i]] = true
+		}
+	}
+
+	return matches, usedB
+}
+
+// matchFuzzy pairs the remaining tasks by the similarity of their todo texts,
+// most similar pairs first.
+func matchFuzzy(listA, listB TaskList, matches []int, usedB []bool) {
+	type candidate struct {
+		score float64
+		i, j  int
+	}
+
+	var candidates []candidate
+
+	for i := range listA {
+		if matches[i] >= 0 {
+			continue
+		}
+
+		for j := range listB {
+			if usedB[j] {
+				continue
+			}
+
+			score := similarity(listA[i].Todo, listB[j].Todo)
+			if score >= fuzzyMatchThreshold {
+				candidates = append(candidates, candidate{score: score, i: i, j: j})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(l, r int) bool {
+		return candidates[l].score > candidates[r].score
+	})
+
+	for _, cand := range candidates {
+		if matches[cand.i] < 0 && !usedB[cand.j] {
+			matches[cand.i] = cand.j
+			usedB[cand.j] = true
+		}
+	}
+}
+
+// normalizeText lowercases the text and collapses whitespaces.
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// similarity returns the Sørensen–Dice coefficient of the character bigrams of
+// the normalized texts, from 0 (nothing in common) to 1 (identical).
+func similarity(textA, textB string) float64 {
+	bigramsA := bigrams(normalizeText(textA))
+	bigramsB := bigrams(normalizeText(textB))
+
+	if len(bigramsA) == 0 || len(bigramsB) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(bigramsA))
+	for _, bigram := range bigramsA {
+		counts[bigram]++
+	}
+
+	shared := 0
+
+	for _, bigram := range bigramsB {
+		if counts[bigram] > 0 {
+			counts[bigram]--
+			shared++
+		}
+	}
+
+	return float64(2*shared) / float64(len(bigramsA)+len(bigramsB))
+}
+
+// bigrams returns the pairs of adjacent characters of the text.
+func bigrams(text string) []string {
+	runes := []rune(text)
+	if len(runes) < 2 {
+		return nil
+	}
+
+	result := make([]string, 0, len(runes)-1)
+	for i := range len(runes) - 1 {
+		result = append(result, string(runes[i:i+2]))
+	}
+
+	return result
+}
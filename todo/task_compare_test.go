@@ -0,0 +1,54 @@
+package todo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_matchTasks(t *testing.T) {
+	t.Parallel()
+
+	listA := testMustLoad(t, `
+		Call Mom uid:abc
+		Pick up milk @GroceryStore
+		pick  UP milk
+		Plan backyard herb garden
+		Something completely different
+	`)
+	listB := testMustLoad(t, `
+		Plan the backyard herb garden
+		Pick up milk
+		Pick up milk @GroceryStore
+		Call Dad uid:abc
+	`)
+
+	expect := []int{3, 2, 1, 0, -1}
+	actual := matchTasks(listA, listB)
+
+	require.Equal(t, expect, actual)
+}
+
+func Test_similarity(t *testing.T) {
+	t.Parallel()
+
+	require.InDelta(t, 1.0, similarity("Call Mom", "call  mom"), 0.0001, "normalized texts should be identical")
+	require.InDelta(t, 0.0, similarity("a", "Call Mom"), 0.0001, "too short texts have no bigrams")
+	require.InDelta(t, 0.0, similarity("abc", "xyz"), 0.0001)
+	require.Greater(t, similarity("Plan backyard herb garden", "Plan the backyard herb garden"), fuzzyMatchThreshold)
+	require.Less(t, similarity("Call Mom", "Call Dad"), fuzzyMatchThreshold)
+}
+
+func Test_cloneTask(t *testing.T) {
+	t.Parallel()
+
+	task, err := ParseTask("Call Mom @Phone +Family key:value")
+	require.NoError(t, err)
+
+	clone := cloneTask(*task)
+	clone.Contexts[0] = "Changed"
+	clone.Projects[0] = "Changed"
+	clone.AdditionalTags["key"] = "changed"
+
+	require.Equal(t, "Call Mom @Phone +Family key:value", task.String(), "the original should not be modified")
+}
@@ -0,0 +1,275 @@
+package todo
+
+import (
+	_ "embed" // for JSONSchema
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// JSONSchema is the JSON Schema (draft 2020-12) of a TaskList encoded by
+// TaskList.MarshalJSON. The schema of a single Task is at "#/$defs/task".
+//
+//go:embed task.schema.json
+var JSONSchema []byte
+
+// jsonPriorityRx matches a valid priority of the JSON encoding.
+var jsonPriorityRx = regexp.MustCompile(`^[A-Z]$`)
+
+// jsonTask is the JSON representation of a Task. See task.schema.json.
+type jsonTask struct {
+	CompletedDate *string           `json:"completed_date"`
+	Priority      *string           `json:"priority"`
+	CreatedDate   *string           `json:"created_date"`
+	DueDate       *string           `json:"due_date"`
+	Todo          *string           `json:"todo"`
+	Tags          map[string]string `json:"tags"`
+	Text          string            `json:"text"`
+	Original      string            `json:"original"`
+	Contexts      []string          `json:"contexts"`
+	Projects      []string          `json:"projects"`
+	ID            int               `json:"id"`
+	Completed     bool              `json:"completed"`
+}
+
+// ----------------------------------------------------------------------------
+//  Task
+// ----------------------------------------------------------------------------
+
+// MarshalJSON implements the json.Marshaler interface. See JSONSchema for the
+// format. For example:
+//
+//	{
+//	  "id": 1,
+//	  "text": "(A) 2024-01-01 Call Mom @Phone +Family due:2024-01-05",
+//	  "original": "(A) 2024-01-01 Call Mom @Phone +Family due:2024-01-05",
+//	  "completed": false,
+//	  "completed_date": null,
+//	  "priority": "A",
+//	  "created_date": "2024-01-01",
+//	  "due_date": "2024-01-05",
+//	  "todo": "Call Mom",
+//	  "contexts": ["Phone"],
+//	  "projects": ["Family"],
+//	  "tags": {}
+//	}
+//
+// "text" is the task in todo.txt format as String() returns and "original" is
+// the raw line the task was parsed from, which is "text" if not parsed.
+func (task Task) MarshalJSON() ([]byte, error) {
+	text := task.String()
+	todo := task.Todo
+
+	encoded := jsonTask{
+		CompletedDate: nil,
+		Priority:      nil,
+		CreatedDate:   jsonDate(task.CreatedDate),
+		DueDate:       jsonDate(task.DueDate),
+		Todo:          &todo,
+		Tags:          map[string]string{},
+		Text:          text,
+		Original:      task.Original,
+		Contexts:      append([]string{}, task.Contexts...),
+		Projects:      append([]string{}, task.Projects...),
+		ID:            task.ID,
+		Completed:     task.Completed,
+	}
+
+	if task.HasCompletedDate() {
+		encoded.CompletedDate = jsonDate(task.CompletedDate)
+	}
+
+	if task.HasPriority() {
+		priority := task.Priority
+		encoded.Priority = &priority
+	}
+
+	for key, value := range task.AdditionalTags {
+		encoded.Tags[key] = value
+	}
+
+	if isEmpty(encoded.Original) {
+		encoded.Original = text
+	}
+
+	return json.Marshal(encoded) //nolint:wrapcheck // no need to wrap
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. See JSONSchema for
+// the format.
+//
+// The fields are authoritative, so "text" is ignored if "todo" is present. If
+// "todo" is missing, the task is parsed from "text", or else from "original",
+// and only "id" is taken from the fields. Missing fields are left unset.
+func (task *Task) UnmarshalJSON(data []byte) error {
+	var decoded jsonTask
+
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return errors.Wrap(err, "failed to decode task")
+	}
+
+	if decoded.Todo == nil {
+		line := decoded.Text
+		if isEmpty(line) {
+			line = decoded.Original
+		}
+
+		if isEmpty(line) {
+			return errors.New("failed to decode task: none of todo, text or original is set")
+		}
+
+		parsed, err := ParseTask(line)
+		if err != nil {
+			return errors.Wrap(err, "failed to decode task")
+		}
+
+		parsed.ID = decoded.ID
+		*task = *parsed
+
+		return nil
+	}
+
+	return decoded.toTask(task)
+}
+
+// ----------------------------------------------------------------------------
+//  TaskList
+// ----------------------------------------------------------------------------
+
+// MarshalJSON implements the json.Marshaler interface. The TaskList is encoded
+// as an array of tasks, an empty array if nil. See Task.MarshalJSON.
+func (tasklist TaskList) MarshalJSON() ([]byte, error) {
+	if tasklist == nil {
+		return []byte("[]"), nil
+	}
+
+	return json.Marshal([]Task(tasklist)) //nolint:wrapcheck // no need to wrap
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. See Task.UnmarshalJSON.
+//
+// Tasks without an ID (or a zero ID) get their position in the list, starting
+// from 1, as LoadFromString does.
+func (tasklist *TaskList) UnmarshalJSON(data []byte) error {
+	var tasks []Task
+
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return errors.Wrap(err, "failed to decode task list")
+	}
+
+	for i := range tasks {
+		if tasks[i].ID == 0 {
+			tasks[i].ID = i + 1
+		}
+	}
+
+	*tasklist = tasks
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// jsonDate returns the date in todo.txt format, or nil if zero.
+func jsonDate(date time.Time) *string {
+	if date.IsZero() {
+		return nil
+	}
+
+	formatted := date.Format(DateLayout)
+
+	return &formatted
+}
+
+// parseJSONDate parses the date in todo.txt format. nil is the zero time.
+func parseJSONDate(field string, date *string) (time.Time, error) {
+	if date == nil || isEmpty(*date) {
+		return time.Time{}, nil
+	}
+
+	parsed, err := parseTime(*date)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "invalid "+field)
+	}
+
+	return parsed, nil
+}
+
+// toTask sets the fields of the task from the decoded JSON.
+func (decoded *jsonTask) toTask(task *Task) error {
+	newTask := Task{
+		DueDate:        time.Time{},
+		CompletedDate:  time.Time{},
+		CreatedDate:    time.Time{},
+		AdditionalTags: nil,
+		Original:       decoded.Original,
+		Priority:       emptyStr,
+		Todo:           *decoded.Todo,
+		Contexts:       nil,
+		Projects:       nil,
+		ID:             decoded.ID,
+		Completed:      decoded.Completed,
+		clock:          realClock{},
+	}
+
+	if decoded.Priority != nil && isNotEmpty(*decoded.Priority) {
+		if !jsonPriorityRx.MatchString(*decoded.Priority) {
+			return errors.New("failed to decode task: invalid priority: " + *decoded.Priority)
+		}
+
+		newTask.Priority = *decoded.Priority
+	}
+
+	var err error
+
+	for _, date := range []struct {
+		dst   *time.Time
+		src   *string
+		field string
+	}{
+		{&newTask.CompletedDate, decoded.CompletedDate, "completed_date"},
+		{&newTask.CreatedDate, decoded.CreatedDate, "created_date"},
+		{&newTask.DueDate, decoded.DueDate, "due_date"},
+	} {
+		*date.dst, err = parseJSONDate(date.field, date.src)
+		if err != nil {
+			return errors.Wrap(err, "failed to decode task")
+		}
+	}
+
+	if len(decoded.Contexts) > 0 {
+		newTask.Contexts = decoded.Contexts
+	}
+
+	if len(decoded.Projects) > 0 {
+		newTask.Projects = decoded.Projects
+	}
+
+	// "due" belongs to due_date, but accept it as a tag too
+	if due, found := decoded.Tags["due"]; found {
+		delete(decoded.Tags, "due")
+
+		if newTask.DueDate.IsZero() {
+			newTask.DueDate, err = parseJSONDate("due tag", &due)
+			if err != nil {
+				return errors.Wrap(err, "failed to decode task")
+			}
+		}
+	}
+
+	if len(decoded.Tags) > 0 {
+		newTask.AdditionalTags = decoded.Tags
+	}
+
+	if isEmpty(newTask.Original) {
+		newTask.Original = newTask.String()
+	}
+
+	*task = newTask
+
+	return nil
+}
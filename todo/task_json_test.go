@@ -0,0 +1,175 @@
+package todo
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTask_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	task, err := ParseTask("(A) 2024-01-01 Call Mom @Phone +Family due:2024-01-05 uid:42")
+	require.NoError(t, err)
+
+	task.ID = 1
+
+	encoded, err := json.Marshal(task)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"id": 1,
+		"text": "(A) 2024-01-01 Call Mom @Phone +Family uid:42 due:2024-01-05",
+		"original": "(A) 2024-01-01 Call Mom @Phone +Family due:2024-01-05 uid:42",
+		"completed": false,
+		"completed_date": null,
+		"priority": "A",
+		"created_date": "2024-01-01",
+		"due_date": "2024-01-05",
+		"todo": "Call Mom",
+		"contexts": ["Phone"],
+		"projects": ["Family"],
+		"tags": {"uid": "42"}
+	}`, string(encoded))
+
+	// Not parsed task
+	newTask := NewTaskWithClock(&fakeClock{now: time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)})
+	newTask.Todo = "Learn Go"
+	newTask.Complete()
+
+	encoded, err = json.Marshal(newTask)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"id": 0,
+		"text": "x 2024-01-02 2024-01-02 Learn Go",
+		"original": "x 2024-01-02 2024-01-02 Learn Go",
+		"completed": true,
+		"completed_date": "2024-01-02",
+		"priority": null,
+		"created_date": "2024-01-02",
+		"due_date": null,
+		"todo": "Learn Go",
+		"contexts": [],
+		"projects": [],
+		"tags": {}
+	}`, string(encoded), "empty values should be null or empty")
+}
+
+func TestTask_UnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	var task Task
+
+	// Fields are authoritative
+	err := json.Unmarshal([]byte(`{
+		"id": 3,
+		"text": "ignored",
+		"priority": "B",
+		"created_date": "2024-01-01",
+		"todo": "Call Mom",
+		"contexts": ["Phone"],
+		"tags": {"due": "2024-01-05", "uid": "42"}
+	}`), &task)
+	require.NoError(t, err)
+	require.Equal(t, 3, task.ID)
+	require.Equal(t, "(B) 2024-01-01 Call Mom @Phone uid:42 due:2024-01-05", task.String())
+	require.Equal(t, task.String(), task.Original, "missing original should be the task")
+	require.Equal(t, map[string]string{"uid": "42"}, task.AdditionalTags, "due tag should be the due date")
+
+	// Parse from text
+	err = json.Unmarshal([]byte(`{"id": 2, "text": "x 2024-01-02 Learn Go +Study"}`), &task)
+	require.NoError(t, err)
+	require.Equal(t, 2, task.ID)
+	require.True(t, task.Completed)
+	require.Equal(t, []string{"Study"}, task.Projects)
+
+	// Parse from original
+	err = json.Unmarshal([]byte(`{"original": "Water plants"}`), &task)
+	require.NoError(t, err)
+	require.Equal(t, "Water plants", task.Todo)
+
+	for _, input := range []string{
+		`{}`,
+		`{"todo": "foo", "priority": "AA"}`,
+		`{"todo": "foo", "due_date": "2024-02-30"}`,
+		`{"todo": "foo", "tags": {"due": "tomorrow"}}`,
+		`{"text": "2024-02-30 foo"}`,
+		`{"todo": 1}`,
+	} {
+		err = json.Unmarshal([]byte(input), &task)
+		require.Error(t, err, "invalid input should fail: %s", input)
+	}
+}
+
+func TestTaskList_JSON_round_trip(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{testInputTask, testInputSort, testInputFilter, testInputTasklist} {
+		expect, err := LoadFromPath(path)
+		require.NoError(t, err)
+
+		encoded, err := json.Marshal(expect)
+		require.NoError(t, err)
+
+		var actual TaskList
+
+		require.NoError(t, json.Unmarshal(encoded, &actual))
+		require.Len(t, actual, len(expect))
+
+		for i := range expect {
+			require.Equal(t, expect[i].String(), actual[i].String(), "%s: task should round-trip", path)
+			require.Equal(t, expect[i].Original, actual[i].Original)
+			require.Equal(t, expect[i].ID, actual[i].ID)
+		}
+	}
+}
+
+func TestTaskList_MarshalJSON_empty(t *testing.T) {
+	t.Parallel()
+
+	encoded, err := json.Marshal(TaskList(nil))
+	require.NoError(t, err)
+	require.Equal(t, "[]", string(encoded))
+
+	var tasklist TaskList
+
+	require.NoError(t, json.Unmarshal([]byte(`[{"todo": "foo"}, {"todo": "bar"}]`), &tasklist))
+	require.Equal(t, 1, tasklist[0].ID, "missing IDs should be the position")
+	require.Equal(t, 2, tasklist[1].ID)
+
+	require.Error(t, json.Unmarshal([]byte(`{}`), &tasklist))
+}
+
+func TestJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	var schema struct {
+		Defs struct {
+			Task struct {
+				Properties map[string]any `json:"properties"`
+				Required   []string       `json:"required"`
+			} `json:"task"`
+		} `json:"$defs"`
+	}
+
+	require.NoError(t, json.Unmarshal(JSONSchema, &schema))
+
+	encoded, err := json.Marshal(NewTask())
+	require.NoError(t, err)
+
+	var fields map[string]any
+
+	require.NoError(t, json.Unmarshal(encoded, &fields))
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+		require.Contains(t, schema.Defs.Task.Properties, key, "encoded field should be in the schema")
+	}
+
+	sort.Strings(keys)
+	sort.Strings(schema.Defs.Task.Required)
+	require.Equal(t, keys, schema.Defs.Task.Required, "all encoded fields should be required")
+}
@@ -0,0 +1,53 @@
+package todo
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Task
+// ----------------------------------------------------------------------------
+
+// MarshalText implements the encoding.TextMarshaler interface. The text is the
+// task in todo.txt format, the same as String().
+func (task Task) MarshalText() ([]byte, error) {
+	return []byte(task.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. The text is
+// parsed as ParseTask does. The ID of the receiver is kept, so a task of a
+// TaskList can be replaced in place. Empty text returns an error.
+func (task *Task) UnmarshalText(text []byte) error {
+	line := strings.Trim(string(text), whitespaces)
+	if isEmpty(line) {
+		return errors.New("failed to parse task: empty text")
+	}
+
+	parsed, err := ParseTask(line)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse task")
+	}
+
+	parsed.ID = task.ID
+	*task = *parsed
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+//  TaskList
+// ----------------------------------------------------------------------------
+
+// MarshalText implements the encoding.TextMarshaler interface. The text is the
+// list in todo.txt format, the same as String().
+func (tasklist TaskList) MarshalText() ([]byte, error) {
+	return []byte(tasklist.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. The text is
+// loaded as LoadFromString does, replacing the current tasks.
+func (tasklist *TaskList) UnmarshalText(text []byte) error {
+	return tasklist.LoadFromFile(strings.NewReader(string(text)))
+}
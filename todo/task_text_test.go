@@ -0,0 +1,138 @@
+package todo
+
+import (
+	"encoding"
+	"encoding/json"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Interface implementation checks.
+var (
+	_ encoding.TextMarshaler   = Task{}
+	_ encoding.TextUnmarshaler = (*Task)(nil)
+	_ encoding.TextMarshaler   = TaskList{}
+	_ encoding.TextUnmarshaler = (*TaskList)(nil)
+	_ encoding.TextMarshaler   = SortPriorityAsc
+	_ encoding.TextUnmarshaler = (*TaskSortByType)(nil)
+	_ encoding.TextMarshaler   = SegmentDueDate
+	_ encoding.TextUnmarshaler = (*TaskSegmentType)(nil)
+)
+
+func TestTask_MarshalText_round_trip(t *testing.T) {
+	t.Parallel()
+
+	for _, expect := range testLoadFromPath(t, testInputTask) {
+		text, err := expect.MarshalText()
+		require.NoError(t, err)
+		require.Equal(t, expect.String(), string(text))
+
+		actual := Task{ID: expect.ID}
+
+		require.NoError(t, actual.UnmarshalText(text))
+		require.Equal(t, expect.String(), actual.String())
+		require.Equal(t, expect.ID, actual.ID, "ID should be kept")
+	}
+}
+
+func TestTask_UnmarshalText_error(t *testing.T) {
+	t.Parallel()
+
+	var task Task
+
+	require.Error(t, task.UnmarshalText([]byte(" \t")), "empty text should fail")
+	require.Error(t, task.UnmarshalText([]byte("2024-02-30 foo")), "invalid date should fail")
+}
+
+func TestTask_flag_TextVar(t *testing.T) {
+	t.Parallel()
+
+	var task Task
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.TextVar(&task, "task", Task{}, "task to add")
+
+	require.NoError(t, flags.Parse([]string{"-task", "(A) Call Mom @Phone"}))
+	require.Equal(t, "A", task.Priority)
+	require.Equal(t, []string{"Phone"}, task.Contexts)
+}
+
+func TestTaskList_MarshalText_round_trip(t *testing.T) {
+	t.Parallel()
+
+	expect := testMustLoad(t, "(A) Call Mom\n# comment\n\nx 2024-01-02 Learn Go\n")
+
+	text, err := expect.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, expect.String(), string(text))
+
+	var actual TaskList
+
+	require.NoError(t, actual.UnmarshalText(text))
+	require.Equal(t, expect.String(), actual.String())
+	require.Equal(t, 2, actual[1].ID)
+
+	require.Error(t, actual.UnmarshalText([]byte("2024-02-30 foo")))
+}
+
+func TestTaskSortByType_Text(t *testing.T) {
+	t.Parallel()
+
+	for sortType := SortTaskIDAsc; sortType <= SortProjectDesc; sortType++ {
+		text, err := sortType.MarshalText()
+		require.NoError(t, err)
+
+		var actual TaskSortByType
+
+		require.NoError(t, actual.UnmarshalText(text))
+		require.Equal(t, sortType, actual)
+	}
+
+	var sortType TaskSortByType
+
+	for _, name := range []string{"priorityasc", "SortPriorityAsc", "PRIORITYASC"} {
+		require.NoError(t, sortType.UnmarshalText([]byte(name)))
+		require.Equal(t, SortPriorityAsc, sortType, "name %q should be accepted", name)
+	}
+
+	require.Error(t, sortType.UnmarshalText([]byte("Priority")))
+
+	_, err := TaskSortByType(0).MarshalText()
+	require.Error(t, err)
+
+	// As a map key
+	encoded, err := json.Marshal(map[TaskSortByType]int{SortDueDateAsc: 1})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"DueDateAsc": 1}`, string(encoded))
+
+	var decoded map[TaskSortByType]int
+
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.Equal(t, map[TaskSortByType]int{SortDueDateAsc: 1}, decoded)
+}
+
+func TestTaskSegmentType_Text(t *testing.T) {
+	t.Parallel()
+
+	for segType := SegmentIsCompleted; segType <= SegmentDueDate; segType++ {
+		text, err := segType.MarshalText()
+		require.NoError(t, err)
+
+		var actual TaskSegmentType
+
+		require.NoError(t, actual.UnmarshalText(text))
+		require.Equal(t, segType, actual)
+	}
+
+	var segType TaskSegmentType
+
+	require.NoError(t, segType.UnmarshalText([]byte("segmentduedate")))
+	require.Equal(t, SegmentDueDate, segType)
+
+	require.Error(t, segType.UnmarshalText([]byte("Due")))
+
+	_, err := TaskSegmentType(100).MarshalText()
+	require.Error(t, err)
+}
@@ -68,15 +68,7 @@ func NewTaskList() TaskList {
 // AddTask appends a Task to the current TaskList and takes care to set the Task.ID
 // correctly, modifying the Task by the given pointer!
 func (tasklist *TaskList) AddTask(task *Task) {
-	task.ID = 0
-
-	for _, t := range *tasklist {
-		if t.ID > task.ID {
-			task.ID = t.ID
-		}
-	}
-
-	task.ID++
+	task.ID = tasklist.nextID()
 
 	*tasklist = append(*tasklist, *task)
 }
@@ -241,3 +233,18 @@ func (tasklist *TaskList) WriteToPath(filename string) error {
 		"failed to save task list to the path: "+filename,
 	)
 }
+
+// ----------------------------------------------------------------------------
+//  Private methods
+// ----------------------------------------------------------------------------
+
+// nextID returns the ID following the highest Task.ID of the TaskList.
+func (tasklist *TaskList) nextID() int {
+	maxID := 0
+
+	for _, t := range *tasklist {
+		maxID = max(maxID, t.ID)
+	}
+
+	return maxID + 1
+}
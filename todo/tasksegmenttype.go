@@ -1,5 +1,11 @@
 package todo
 
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
 // ----------------------------------------------------------------------------
 //  Type: TaskSegmentType
 // ----------------------------------------------------------------------------
@@ -27,3 +33,34 @@ const (
 	SegmentTag
 	SegmentDueDate
 )
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// MarshalText implements the encoding.TextMarshaler interface. The text is the
+// same as String() (e.g. "DueDate"). Unknown values return an error.
+func (i TaskSegmentType) MarshalText() ([]byte, error) {
+	if i < SegmentIsCompleted || i > SegmentDueDate {
+		return nil, errors.New("unknown segment type: " + i.String())
+	}
+
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. It accepts
+// the names of String() case-insensitively, with or without the "Segment"
+// prefix (e.g. "DueDate", "duedate" or "SegmentDueDate").
+func (i *TaskSegmentType) UnmarshalText(text []byte) error {
+	name := strings.TrimPrefix(strings.ToLower(string(text)), "segment")
+
+	for segType := SegmentIsCompleted; segType <= SegmentDueDate; segType++ {
+		if strings.ToLower(segType.String()) == name {
+			*i = segType
+
+			return nil
+		}
+	}
+
+	return errors.New("unknown segment type: " + string(text))
+}
@@ -1,5 +1,11 @@
 package todo
 
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
 // ----------------------------------------------------------------------------
 //  Type: TaskSortByType
 // ----------------------------------------------------------------------------
@@ -29,3 +35,34 @@ const (
 	SortProjectAsc
 	SortProjectDesc
 )
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// MarshalText implements the encoding.TextMarshaler interface. The text is the
+// same as String() (e.g. "PriorityAsc"). Unknown values return an error.
+func (i TaskSortByType) MarshalText() ([]byte, error) {
+	if i < SortTaskIDAsc || i > SortProjectDesc {
+		return nil, errors.New("unknown sort type: " + i.String())
+	}
+
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. It accepts
+// the names of String() case-insensitively, with or without the "Sort" prefix
+// (e.g. "PriorityAsc", "priorityasc" or "SortPriorityAsc").
+func (i *TaskSortByType) UnmarshalText(text []byte) error {
+	name := strings.TrimPrefix(strings.ToLower(string(text)), "sort")
+
+	for sortType := SortTaskIDAsc; sortType <= SortProjectDesc; sortType++ {
+		if strings.ToLower(sortType.String()) == name {
+			*i = sortType
+
+			return nil
+		}
+	}
+
+	return errors.New("unknown sort type: " + string(text))
+}
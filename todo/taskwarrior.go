@@ -0,0 +1,648 @@
+package todo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Constants
+// ----------------------------------------------------------------------------
+
+// taskwarriorTimeLayout is the layout of the date attributes of Taskwarrior.
+const taskwarriorTimeLayout = "20060102T150405Z"
+
+var (
+	// taskwarriorUUIDRx matches a UUID as used by Taskwarrior.
+	taskwarriorUUIDRx = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	// taskwarriorRecurRx matches a recurrence duration, such as "2w" or "3months".
+	taskwarriorRecurRx = regexp.MustCompile(`^(\d*)\s*(d|days?|w|wks?|weeks?|mo|mths?|months?|q|qtrs?|quarters?|y|yrs?|years?)$`)
+)
+
+// taskwarriorUnsupported are the attributes of Taskwarrior without todo.txt
+// counterpart. They are reported as LossyField instead of being taken as UDAs.
+//
+//nolint:gochecknoglobals // it is intentionally global as a constant set
+var taskwarriorUnsupported = map[string]bool{
+	"annotations": true, "depends": true, "imask": true, "mask": true, "parent": true,
+	"scheduled": true, "start": true, "until": true, "wait": true,
+}
+
+// taskwarriorNamedRecur are the named recurrences of Taskwarrior and their
+// RecurrenceTag value.
+//
+//nolint:gochecknoglobals // it is intentionally global as a constant map
+var taskwarriorNamedRecur = map[string]string{
+	"daily": "1d", "day": "1d", "weekdays": "b", "weekly": "1w", "week": "1w",
+	"biweekly": "2w", "fortnight": "2w", "monthly": "1m", "month": "1m", "bimonthly": "2m",
+	"quarterly": "3m", "semiannual": "6m", "annual": "1y", "yearly": "1y", "year": "1y",
+	"biannual": "2y", "biyearly": "2y",
+}
+
+// ----------------------------------------------------------------------------
+//  Type: TaskwarriorTagMapping
+// ----------------------------------------------------------------------------
+
+// TaskwarriorTagMapping represents what the tags of Taskwarrior are mapped onto.
+type TaskwarriorTagMapping string
+
+// Mappings of the tags of Taskwarrior.
+const (
+	TaskwarriorTagsAsContexts TaskwarriorTagMapping = "context" // Tags are contexts (default).
+	TaskwarriorTagsAsProjects TaskwarriorTagMapping = "project" // Tags are projects, like the project attribute.
+)
+
+// DefaultTaskwarriorPriorities maps the priorities of Taskwarrior onto the
+// priorities of todo.txt. It is used if TaskwarriorOptions.Priorities is empty.
+//
+//nolint:gochecknoglobals // it is intentionally global as a default
+var DefaultTaskwarriorPriorities = map[string]string{"H": "A", "M": "B", "L": "C"}
+
+// DefaultTaskwarriorDateTags maps the date attributes of Taskwarrior onto
+// additional tags. It is used if TaskwarriorOptions.DateTags is nil. "wait",
+// which hides the task until the date, is the threshold date of todo.txt.
+//
+//nolint:gochecknoglobals // it is intentionally global as a default
+var DefaultTaskwarriorDateTags = map[string]string{"wait": ThresholdTag}
+
+// ----------------------------------------------------------------------------
+//  Type: TaskwarriorOptions
+// ----------------------------------------------------------------------------
+
+// TaskwarriorOptions are the options of the conversion between TaskList and the
+// JSON of "task export" and "task import" of Taskwarrior. The zero value uses
+// the defaults described on each field.
+type TaskwarriorOptions struct {
+	// Priorities maps the priorities of Taskwarrior onto todo.txt letters.
+	// Defaults to DefaultTaskwarriorPriorities. On export, the letters without
+	// a priority are reported as LossyField.
+	Priorities map[string]string
+	// DateTags maps the date attributes of Taskwarrior onto additional tags
+	// holding the date (e.g. {"scheduled": "scheduled"}). Defaults to
+	// DefaultTaskwarriorDateTags. Use an empty map to map none.
+	DateTags map[string]string
+	// TagsAs is what the tags of Taskwarrior are mapped onto. Defaults to
+	// TaskwarriorTagsAsContexts.
+	TagsAs TaskwarriorTagMapping
+	// UUIDTag is the key of the additional tag holding the uuid attribute.
+	// Defaults to UIDTag.
+	UUIDTag string
+	// IgnoreUDAs drops the user defined attributes on import and the
+	// additional tags on export, instead of mapping them onto each other.
+	IgnoreUDAs bool
+	// IncludeDeleted imports the deleted tasks as completed. By default, they
+	// are skipped and reported as LossyField.
+	IncludeDeleted bool
+}
+
+// dateTags returns the date tag mapping or the default one.
+func (opts TaskwarriorOptions) dateTags() map[string]string {
+	if opts.DateTags == nil {
+		return DefaultTaskwarriorDateTags
+	}
+
+	return opts.DateTags
+}
+
+// priorities returns the priority mapping or the default one.
+func (opts TaskwarriorOptions) priorities() map[string]string {
+	if len(opts.Priorities) == 0 {
+		return DefaultTaskwarriorPriorities
+	}
+
+	return opts.Priorities
+}
+
+// uuidTag returns the key of the uuid tag or the default one.
+func (opts TaskwarriorOptions) uuidTag() string {
+	if isEmpty(opts.UUIDTag) {
+		return UIDTag
+	}
+
+	return opts.UUIDTag
+}
+
+// validate returns an error if the options are invalid.
+func (opts TaskwarriorOptions) validate() error {
+	switch opts.TagsAs {
+	case emptyStr, TaskwarriorTagsAsContexts, TaskwarriorTagsAsProjects:
+		return nil
+	default:
+		return errors.New("unknown Taskwarrior tag mapping: " + string(opts.TagsAs))
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Public functions
+// ----------------------------------------------------------------------------
+
+// TaskwarriorUUID returns the Taskwarrior uuid of the task. It is the value of
+// the tag if it is a UUID, otherwise it is derived from the tag value or, if
+// not set, from the created date and the text of the task. The derived UUID is
+// stable across exports as long as those do not change.
+func TaskwarriorUUID(task *Task, tag string) string {
+	value := task.AdditionalTags[tag]
+	if taskwarriorUUIDRx.MatchString(value) {
+		return value
+	}
+
+	if isEmpty(value) {
+		value = formatDate(task.CreatedDate) + " " + task.Todo
+	}
+
+	sum := sha256.Sum256([]byte(value))
+	sum[6] = (sum[6] & 0x0f) | 0x50 // version 5, name-based
+	sum[8] = (sum[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	uuid := hex.EncodeToString(sum[:16])
+
+	return uuid[0:8] + "-" + uuid[8:12] + "-" + uuid[12:16] + "-" + uuid[16:20] + "-" + uuid[20:32]
+}
+
+// LoadFromTaskwarrior loads a TaskList from the JSON array of "task export" of
+// Taskwarrior. See TaskwarriorOptions for the mapping.
+//
+// The attributes are mapped as follows:
+//
+//   - description is the text, and status "completed" completes the task.
+//   - entry, end and due are the created, completed and due dates.
+//   - priority is mapped onto a letter by TaskwarriorOptions.Priorities.
+//   - project and tags are projects, and tags or contexts (TagsAs).
+//   - uuid is the UUIDTag tag, unless derived from the task (TaskwarriorUUID).
+//   - recur is the RecurrenceTag tag, such as "weekly" as "rec:1w".
+//   - date attributes of DateTags, such as wait, are tags of the date.
+//   - other attributes are user defined attributes (UDA) mapped onto tags.
+//
+// The values which could not be mapped, such as annotations or a time of the
+// day, are reported as LossyField. The ID of the tasks is their position,
+// starting from 1.
+func LoadFromTaskwarrior(reader io.Reader, opts TaskwarriorOptions) (TaskList, []LossyField, error) {
+	if err := opts.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var records []map[string]any
+
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&records); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to decode Taskwarrior JSON")
+	}
+
+	tasklist := NewTaskList()
+	lossy := []LossyField{}
+
+	for i, record := range records {
+		task, lossyTask, err := taskwarriorToTask(record, len(tasklist)+1, opts)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to load Taskwarrior task #%d", i+1)
+		}
+
+		lossy = append(lossy, lossyTask...)
+
+		if task != nil {
+			tasklist = append(tasklist, *task)
+		}
+	}
+
+	return tasklist, lossy, nil
+}
+
+// ----------------------------------------------------------------------------
+//  TaskList.WriteTaskwarrior()
+// ----------------------------------------------------------------------------
+
+// WriteTaskwarrior writes the TaskList as a JSON array for "task import" of
+// Taskwarrior, one task per line like "task export" does. The mapping is the
+// reverse of LoadFromTaskwarrior.
+//
+// Taskwarrior holds a single project, so the other projects are tags if TagsAs
+// is TaskwarriorTagsAsProjects, and reported as LossyField otherwise. The
+// values which could not be mapped are reported as well. Note that Taskwarrior
+// requires the UDAs to be defined in .taskrc to keep them.
+func (tasklist *TaskList) WriteTaskwarrior(writer io.Writer, opts TaskwarriorOptions) ([]LossyField, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	lossy := []LossyField{}
+	lines := make([]string, len(*tasklist))
+
+	for i := range *tasklist {
+		record, lossyTask := taskToTaskwarrior(&(*tasklist)[i], opts)
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode Taskwarrior JSON")
+		}
+
+		lines[i] = string(data)
+		lossy = append(lossy, lossyTask...)
+	}
+
+	output := "[\n" + strings.Join(lines, ",\n") + "\n]\n"
+	if len(lines) == 0 {
+		output = "[]\n"
+	}
+
+	_, err := io.WriteString(writer, output)
+
+	return lossy, errors.Wrap(err, "failed to write Taskwarrior JSON")
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// formatTaskwarriorDate returns the date at local midnight in the layout of
+// Taskwarrior.
+func formatTaskwarriorDate(date time.Time) string {
+	year, month, day := date.Date()
+
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local).UTC().Format(taskwarriorTimeLayout)
+}
+
+// parseTaskwarriorDate returns the local date of a date attribute and whether
+// it has a time of the day, which todo.txt can not hold.
+func parseTaskwarriorDate(value any) (time.Time, bool, error) {
+	str, _ := value.(string)
+
+	parsed, err := time.Parse(taskwarriorTimeLayout, str)
+	if err != nil {
+		return time.Time{}, false, errors.Wrap(err, "invalid date")
+	}
+
+	local := parsed.In(time.Local)
+	year, month, day := local.Date()
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+
+	return date, !local.Equal(date), nil
+}
+
+// recurrenceToTaskwarrior converts the value of RecurrenceTag to the recur
+// attribute. It returns false if it can not be converted.
+func recurrenceToTaskwarrior(rec string) (string, bool) {
+	match := recurrenceRx.FindStringSubmatch(rec)
+	if match == nil {
+		return emptyStr, false
+	}
+
+	interval := 1
+	if isNotEmpty(match[2]) {
+		interval, _ = strconv.Atoi(match[2]) // digits only
+	}
+
+	unit := match[3]
+
+	switch {
+	case interval < 1, unit == "b" && interval != 1:
+		return emptyStr, false
+	case unit == "b":
+		return "weekdays", true
+	case interval == 1:
+		return map[string]string{"d": "daily", "w": "weekly", "m": "monthly", "y": "yearly"}[unit], true
+	case unit == "m":
+		return strconv.Itoa(interval) + "mo", true // "m" is minutes in Taskwarrior
+	default:
+		return strconv.Itoa(interval) + unit, true
+	}
+}
+
+// taskwarriorToRecurrence converts the recur attribute to the value of
+// RecurrenceTag. It returns false if it can not be converted.
+func taskwarriorToRecurrence(recur string) (string, bool) {
+	recur = strings.ToLower(strings.TrimSpace(recur))
+
+	if rec, found := taskwarriorNamedRecur[recur]; found {
+		return rec, true
+	}
+
+	match := taskwarriorRecurRx.FindStringSubmatch(recur)
+	if match == nil {
+		return emptyStr, false
+	}
+
+	interval := 1
+	if isNotEmpty(match[1]) {
+		interval, _ = strconv.Atoi(match[1]) // digits only
+	}
+
+	unit := match[2][:1]
+
+	switch {
+	case interval < 1:
+		return emptyStr, false
+	case unit == "q":
+		interval, unit = interval*3, "m"
+	case strings.HasPrefix(match[2], "m"):
+		unit = "m"
+	}
+
+	return strconv.Itoa(interval) + unit, true
+}
+
+// taskToTaskwarrior converts the task to the attributes of a Taskwarrior task.
+//
+//nolint:cyclop,funlen // a simple mapping over the fields
+func taskToTaskwarrior(task *Task, opts TaskwarriorOptions) (map[string]any, []LossyField) {
+	lossy := []LossyField{}
+	report := func(field, value, reason string) {
+		lossy = append(lossy, LossyField{Field: field, Value: value, Reason: reason, TaskID: task.ID})
+	}
+
+	uuidTag := opts.uuidTag()
+	record := map[string]any{
+		"description": task.Todo,
+		"status":      "pending",
+		"uuid":        TaskwarriorUUID(task, uuidTag),
+	}
+
+	if value := task.AdditionalTags[uuidTag]; isNotEmpty(value) && !taskwarriorUUIDRx.MatchString(value) {
+		report(uuidTag, value, "exported as a UUID derived from the value")
+	}
+
+	if task.Completed {
+		record["status"] = "completed"
+
+		if task.HasCompletedDate() {
+			record["end"] = formatTaskwarriorDate(task.CompletedDate)
+		}
+	}
+
+	if task.HasCreatedDate() {
+		record["entry"] = formatTaskwarriorDate(task.CreatedDate)
+	}
+
+	if task.HasDueDate() {
+		record["due"] = formatTaskwarriorDate(task.DueDate)
+	}
+
+	if task.HasPriority() {
+		found := false
+
+		for priority, letter := range opts.priorities() {
+			if letter == task.Priority && (!found || priority < record["priority"].(string)) {
+				record["priority"], found = priority, true
+			}
+		}
+
+		if !found {
+			report("priority", task.Priority, "priority is not mapped")
+		}
+	}
+
+	tags := []string{}
+
+	if task.HasProjects() {
+		record["project"] = task.Projects[0]
+
+		for _, project := range task.Projects[1:] {
+			if opts.TagsAs == TaskwarriorTagsAsProjects {
+				tags = append(tags, project)
+			} else {
+				report("project", project, "Taskwarrior holds a single project")
+			}
+		}
+	}
+
+	for _, context := range task.Contexts {
+		if opts.TagsAs == TaskwarriorTagsAsProjects {
+			report("context", context, "contexts are not mapped with tags as projects")
+		} else {
+			tags = append(tags, context)
+		}
+	}
+
+	if len(tags) > 0 {
+		record["tags"] = tags
+	}
+
+	dateAttrs := map[string]string{}
+	for attr, tag := range opts.dateTags() {
+		dateAttrs[tag] = attr
+	}
+
+	for _, key := range sortedKeys(tagKeys(task.AdditionalTags)) {
+		value := task.AdditionalTags[key]
+
+		switch attr, isDate := dateAttrs[key]; {
+		case key == uuidTag:
+			continue
+		case key == RecurrenceTag:
+			recur, ok := recurrenceToTaskwarrior(value)
+			if !ok {
+				report(key, value, "recurrence is not supported by Taskwarrior")
+
+				continue
+			}
+
+			if strings.HasPrefix(value, "+") {
+				report(key, value, "strict recurrence is exported as normal recurrence")
+			}
+
+			record["recur"] = recur
+		case isDate:
+			date, err := parseTime(value)
+			if err != nil {
+				report(key, value, "invalid date")
+
+				continue
+			}
+
+			record[attr] = formatTaskwarriorDate(date)
+		case record[key] != nil || taskwarriorUnsupported[key] || taskwarriorIsCore(key):
+			report(key, value, "tag conflicts with a Taskwarrior attribute")
+		case opts.IgnoreUDAs:
+			report(key, value, "tag is not exported as UDA")
+		default:
+			record[key] = value
+		}
+	}
+
+	return record, lossy
+}
+
+// taskwarriorIsCore returns true if the key is a core attribute of Taskwarrior.
+func taskwarriorIsCore(key string) bool {
+	switch key {
+	case "description", "due", "end", "entry", "id", "modified", "priority", "project",
+		"recur", "status", "tags", "urgency", "uuid":
+		return true
+	default:
+		return false
+	}
+}
+
+// taskwarriorToTask converts the attributes of a Taskwarrior task to a Task. It
+// returns a nil task if the task is skipped.
+//
+//nolint:cyclop,funlen,gocognit // a simple switch over the attributes
+func taskwarriorToTask(record map[string]any, taskID int, opts TaskwarriorOptions) (*Task, []LossyField, error) {
+	lossy := []LossyField{}
+	report := func(field string, value any, reason string) {
+		lossy = append(lossy, LossyField{Field: field, Value: taskwarriorString(value), Reason: reason, TaskID: taskID})
+	}
+
+	task := new(Task)
+	status := "pending"
+	uuid := emptyStr
+
+	keys := make([]string, 0, len(record))
+	for key := range record {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var (
+			err     error
+			hasTime bool
+		)
+
+		value := record[key]
+		str, _ := value.(string)
+
+		switch tag, isDate := opts.dateTags()[key]; {
+		case key == "description":
+			task.Todo = strings.Join(strings.Fields(str), " ")
+		case key == "status":
+			status = str
+		case key == "entry":
+			task.CreatedDate, _, err = parseTaskwarriorDate(value)
+		case key == "end":
+			task.CompletedDate, _, err = parseTaskwarriorDate(value)
+		case key == "due":
+			task.DueDate, hasTime, err = parseTaskwarriorDate(value)
+		case key == "priority":
+			letter, found := opts.priorities()[str]
+			if !found {
+				report(key, value, "priority is not mapped")
+
+				continue
+			}
+
+			task.Priority = letter
+		case key == "project":
+			task.Projects = append(task.Projects, sanitizeWord(str))
+		case key == "tags":
+			items, _ := value.([]any)
+			for _, item := range items {
+				word := sanitizeWord(taskwarriorString(item))
+				if isEmpty(word) {
+					continue
+				}
+
+				if opts.TagsAs == TaskwarriorTagsAsProjects {
+					task.Projects = append(task.Projects, word)
+				} else {
+					task.Contexts = append(task.Contexts, word)
+				}
+			}
+		case key == "uuid":
+			uuid = str
+		case key == "recur":
+			rec, ok := taskwarriorToRecurrence(str)
+			if !ok {
+				report(key, value, "recurrence is not supported by todo.txt")
+
+				continue
+			}
+
+			setTag(task, RecurrenceTag, rec)
+		case isDate:
+			var date time.Time
+
+			date, hasTime, err = parseTaskwarriorDate(value)
+			if err == nil {
+				setTag(task, tag, formatDate(date))
+			}
+		case key == "id" || key == "urgency" || key == "modified":
+			continue // derived by Taskwarrior
+		case taskwarriorUnsupported[key]:
+			report(key, value, "attribute is not supported")
+		case opts.IgnoreUDAs:
+			report(key, value, "UDA is ignored")
+		default:
+			word := sanitizeWord(taskwarriorString(value))
+
+			switch value.(type) {
+			case string, json.Number, bool:
+				if isNotEmpty(word) {
+					setTag(task, sanitizeWord(key), word)
+				}
+			default:
+				report(key, value, "UDA is not a single value")
+			}
+		}
+
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid %s", key)
+		}
+
+		if hasTime {
+			report(key, value, "time of the day is dropped")
+		}
+	}
+
+	switch status {
+	case "pending", "waiting", "recurring":
+		task.CompletedDate = time.Time{}
+	case "completed":
+		task.Completed = true
+	case "deleted":
+		if !opts.IncludeDeleted {
+			report("status", status, "deleted task is skipped")
+
+			return nil, lossy, nil
+		}
+
+		report("status", status, "imported as completed")
+
+		task.Completed = true
+	default:
+		return nil, nil, errors.New("invalid status: " + status)
+	}
+
+	if isEmpty(task.Todo) {
+		return nil, nil, errors.New("missing description")
+	}
+
+	if isNotEmpty(uuid) && uuid != TaskwarriorUUID(task, emptyStr) {
+		setTag(task, opts.uuidTag(), sanitizeWord(uuid))
+	}
+
+	// Parse the todo.txt line to make sure it is valid
+	parsed, err := ParseTask(task.String())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parsed.ID = taskID
+
+	return parsed, lossy, nil
+}
+
+// taskwarriorString returns the value of an attribute as a string.
+func taskwarriorString(value any) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case json.Number:
+		return typed.String()
+	default:
+		data, _ := json.Marshal(typed)
+
+		return string(data)
+	}
+}
@@ -0,0 +1,213 @@
+package todo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskList_WriteTaskwarrior(t *testing.T) {
+	t.Parallel()
+
+	tasklist := testMustLoad(t, `(A) 2024-01-01 Call Mom @Phone @Home +Family +Chores due:2024-01-05 rec:+1w t:2024-01-03 effort:3
+x 2024-01-03 (D) 2024-01-02 Learn Go uid:42 rec:2b status:foo
+`)
+
+	var buf bytes.Buffer
+
+	lossy, err := tasklist.WriteTaskwarrior(&buf, TaskwarriorOptions{})
+	require.NoError(t, err)
+
+	local := func(day int) string {
+		return time.Date(2024, 1, day, 0, 0, 0, 0, time.Local).UTC().Format(taskwarriorTimeLayout)
+	}
+
+	expect := `[
+{"description":"Call Mom","due":"` + local(5) + `","effort":"3","entry":"` + local(1) +
+		`","priority":"H","project":"Chores","recur":"weekly","status":"pending","tags":["Home","Phone"],"uuid":"` +
+		TaskwarriorUUID(&tasklist[0], UIDTag) + `","wait":"` + local(3) + `"},
+{"description":"Learn Go","end":"` + local(3) + `","entry":"` + local(2) + `","status":"completed","uuid":"` +
+		TaskwarriorUUID(&tasklist[1], UIDTag) + `"}
+]
+`
+	require.Equal(t, expect, buf.String())
+	require.Equal(t, []LossyField{
+		{Field: "project", Value: "Family", Reason: "Taskwarrior holds a single project", TaskID: 1},
+		{Field: "rec", Value: "+1w", Reason: "strict recurrence is exported as normal recurrence", TaskID: 1},
+		{Field: "uid", Value: "42", Reason: "exported as a UUID derived from the value", TaskID: 2},
+		{Field: "priority", Value: "D", Reason: "priority is not mapped", TaskID: 2},
+		{Field: "rec", Value: "2b", Reason: "recurrence is not supported by Taskwarrior", TaskID: 2},
+		{Field: "status", Value: "foo", Reason: "tag conflicts with a Taskwarrior attribute", TaskID: 2},
+	}, lossy, "completed priority is kept as RemoveCompletedPriority is false in tests")
+
+	// Tags as projects
+	buf.Reset()
+
+	first := tasklist[:1]
+
+	lossy, err = first.WriteTaskwarrior(&buf, TaskwarriorOptions{
+		TagsAs: TaskwarriorTagsAsProjects, Priorities: map[string]string{"1": "A"}, IgnoreUDAs: true,
+	})
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), `"priority":"1","project":"Chores"`)
+	require.Contains(t, buf.String(), `"tags":["Family"]`)
+	require.NotContains(t, buf.String(), "effort")
+	require.Len(t, lossy, 4, "contexts, strict recurrence and the tag should be reported")
+
+	// Empty
+	buf.Reset()
+
+	empty := NewTaskList()
+
+	_, err = empty.WriteTaskwarrior(&buf, TaskwarriorOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "[]\n", buf.String())
+
+	_, err = empty.WriteTaskwarrior(&buf, TaskwarriorOptions{TagsAs: "unknown"})
+	require.ErrorContains(t, err, "unknown Taskwarrior tag mapping: unknown")
+}
+
+func TestLoadFromTaskwarrior_round_trip(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{testInputTask, testInputSort, testInputFilter, testInputTasklist} {
+		tasklist, err := LoadFromPath(path)
+		require.NoError(t, err)
+
+		// Keep what Taskwarrior can hold: a single project and priorities A to C
+		expect := NewTaskList()
+
+		for i := range tasklist {
+			task := tasklist[i]
+			if len(task.Projects) > 1 || task.Priority > "C" {
+				continue
+			}
+
+			task.ID = len(expect) + 1
+			expect = append(expect, task)
+		}
+
+		var buf bytes.Buffer
+
+		lossy, err := expect.WriteTaskwarrior(&buf, TaskwarriorOptions{})
+		require.NoError(t, err)
+		require.Empty(t, lossy, "%s should be exported without loss", path)
+
+		actual, lossy, err := LoadFromTaskwarrior(&buf, TaskwarriorOptions{})
+		require.NoError(t, err)
+		require.Empty(t, lossy)
+		require.Equal(t, expect.String(), actual.String(), "%s should round-trip", path)
+	}
+}
+
+func TestLoadFromTaskwarrior(t *testing.T) {
+	t.Parallel()
+
+	input := `[
+{"id":1,"description":"Call  Mom\nsoon","entry":"20240101T101500Z","due":"20240105T153000Z","priority":"M",
+ "project":"Home.Family","tags":["phone","next action"],"status":"pending","recur":"biweekly",
+ "uuid":"1f0e6d8c-3a42-4b6e-9d3c-0a1b2c3d4e5f","urgency":8.5,"estimate":2,"owner":"Bob Smith",
+ "wait":"20240103T000000Z","annotations":[{"entry":"20240101T101500Z","description":"Note"}]},
+{"id":0,"description":"Learn Go","status":"completed","end":"20240103T120000Z","priority":"X"},
+{"id":0,"description":"Old task","status":"deleted","end":"20240103T120000Z"},
+{"id":2,"description":"Pay bills","status":"waiting","recur":"3q","reviewed":false}
+]`
+
+	tasklist, lossy, err := LoadFromTaskwarrior(strings.NewReader(input), TaskwarriorOptions{})
+	require.NoError(t, err)
+
+	dueDate := time.Date(2024, 1, 5, 15, 30, 0, 0, time.UTC).In(time.Local).Format(DateLayout)
+	createdDate := time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC).In(time.Local).Format(DateLayout)
+	completedDate := time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC).In(time.Local).Format(DateLayout)
+	waitDate := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC).In(time.Local).Format(DateLayout)
+
+	expect := "(B) " + createdDate + " Call Mom soon @next_action @phone +Home.Family " +
+		"estimate:2 owner:Bob_Smith rec:2w t:" + waitDate + " uid:1f0e6d8c-3a42-4b6e-9d3c-0a1b2c3d4e5f due:" + dueDate + "\n" +
+		"x " + completedDate + " Learn Go\n" +
+		"Pay bills rec:9m reviewed:false\n"
+	require.Equal(t, strings.ReplaceAll(expect, "\n", NewLine), tasklist.String())
+	require.Equal(t, 3, tasklist[2].ID)
+
+	fields := []string{}
+	for _, field := range lossy {
+		fields = append(fields, field.Field+": "+field.Reason)
+	}
+
+	require.Contains(t, fields, "annotations: attribute is not supported")
+	require.Contains(t, fields, "priority: priority is not mapped")
+	require.Contains(t, fields, "status: deleted task is skipped")
+
+	// Options
+	tasklist, lossy, err = LoadFromTaskwarrior(strings.NewReader(input), TaskwarriorOptions{
+		TagsAs:         TaskwarriorTagsAsProjects,
+		UUIDTag:        "tw",
+		DateTags:       map[string]string{},
+		IgnoreUDAs:     true,
+		IncludeDeleted: true,
+		Priorities:     map[string]string{"M": "A", "X": "Z"},
+	})
+	require.NoError(t, err)
+	require.Len(t, tasklist, 4)
+	require.Equal(t, []string{"Home.Family", "next_action", "phone"}, tasklist[0].Projects)
+	require.Equal(t, map[string]string{"rec": "2w", "tw": "1f0e6d8c-3a42-4b6e-9d3c-0a1b2c3d4e5f"},
+		tasklist[0].AdditionalTags)
+	require.Equal(t, "A", tasklist[0].Priority)
+	require.Equal(t, "Z", tasklist[1].Priority)
+	require.True(t, tasklist[2].Completed, "deleted task should be completed")
+	require.NotEmpty(t, lossy)
+
+	// Errors
+	for _, input := range []string{
+		`{"description":"not an array"}`,
+		`[{"status":"pending"}]`,
+		`[{"description":"foo","status":"unknown"}]`,
+		`[{"description":"foo","status":"pending","due":"2024-01-05"}]`,
+	} {
+		_, _, err := LoadFromTaskwarrior(strings.NewReader(input), TaskwarriorOptions{})
+		require.Error(t, err, "invalid input should fail: %s", input)
+	}
+}
+
+func TestTaskwarriorUUID(t *testing.T) {
+	t.Parallel()
+
+	task := testMustLoad(t, "Call Mom uid:1f0e6d8c-3a42-4b6e-9d3c-0a1b2c3d4e5f\n")[0]
+	require.Equal(t, "1f0e6d8c-3a42-4b6e-9d3c-0a1b2c3d4e5f", TaskwarriorUUID(&task, UIDTag))
+
+	derived := TaskwarriorUUID(&task, "other")
+	require.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, derived)
+	require.Equal(t, derived, TaskwarriorUUID(&task, "other"), "derived UUID should be stable")
+}
+
+func TestRecurrence_Taskwarrior(t *testing.T) {
+	t.Parallel()
+
+	for rec, recur := range map[string]string{
+		"d": "daily", "1w": "weekly", "+1m": "monthly", "y": "yearly", "b": "weekdays", "3m": "3mo", "2w": "2w",
+	} {
+		actual, ok := recurrenceToTaskwarrior(rec)
+		require.True(t, ok)
+		require.Equal(t, recur, actual)
+	}
+
+	for _, rec := range []string{"0d", "2b", "1h"} {
+		_, ok := recurrenceToTaskwarrior(rec)
+		require.False(t, ok, "%q should not be converted", rec)
+	}
+
+	for recur, rec := range map[string]string{
+		"weekly": "1w", "Quarterly": "3m", "2 weeks": "2w", "3mo": "3m", "months": "1m", "q": "3m", "10days": "10d",
+	} {
+		actual, ok := taskwarriorToRecurrence(recur)
+		require.True(t, ok, recur)
+		require.Equal(t, rec, actual)
+	}
+
+	for _, recur := range []string{"0d", "2h", "P1D", "every day"} {
+		_, ok := taskwarriorToRecurrence(recur)
+		require.False(t, ok, "%q should not be converted", recur)
+	}
+}
@@ -0,0 +1,375 @@
+package todo
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Built-in templates
+// ----------------------------------------------------------------------------
+
+// Built-in templates of Formatter. They are executed with a TemplateListData.
+const (
+	// TemplateLS lists the tasks like "todo.sh ls", with zero-padded line
+	// numbers and a summary line.
+	TemplateLS = `{{range .Tasks}}{{pad .ID $.Width}} {{colored .}}
+{{end}}--
+TODO: {{len .Tasks}} of {{.Total}} tasks shown
+`
+
+	// TemplateAgenda lists the open tasks by due date, the overdue ones first
+	// and the ones without due date last.
+	TemplateAgenda = `{{range groupBy "due" (open .Tasks)}}
+{{- if .Name}}{{color "DueDate" .Name}} ({{relative .Date}}){{else}}No due date{{end}}
+{{range .Tasks}}  {{with segment . "Priority"}}{{color "Priority" .}} {{end}}{{.Todo}}
+{{- range .Projects}} {{color "Project" (print "+" .)}}{{end}}
+{{end}}{{end}}`
+
+	// TemplateByProject lists the tasks under a heading per project. A task
+	// with several projects is listed under each of them.
+	TemplateByProject = `{{range groupBy "project" .Tasks}}
+{{- if .Name}}{{color "Project" (print "+" .Name)}}{{else}}(no project){{end}}
+{{range .Tasks}}  {{pad .ID $.Width}} {{colored .}}
+{{end}}{{end}}`
+)
+
+// ----------------------------------------------------------------------------
+//  Type: ColorFunc
+// ----------------------------------------------------------------------------
+
+// ColorFunc decorates the text of a segment of the given type, such as with
+// ANSI escape sequences. It is the colour hook of Formatter.
+type ColorFunc func(segType TaskSegmentType, text string) string
+
+// ----------------------------------------------------------------------------
+//  Type: TemplateListData
+// ----------------------------------------------------------------------------
+
+// TemplateListData is the data of the templates executed by Formatter.FormatList.
+type TemplateListData struct {
+	// Tasks are the tasks to render.
+	Tasks []*Task
+	// Total is the number of tasks in the whole list, which may be more than
+	// Tasks if filtered.
+	Total int
+	// Width is the number of digits of the largest ID, to align line numbers.
+	Width int
+}
+
+// NewTemplateListData returns the data of the tasks, out of a list of total
+// tasks.
+func NewTemplateListData(tasklist TaskList, total int) TemplateListData {
+	data := TemplateListData{Tasks: make([]*Task, len(tasklist)), Total: total, Width: 1}
+
+	for i := range tasklist {
+		data.Tasks[i] = &tasklist[i]
+
+		if width := len(strconv.Itoa(tasklist[i].ID)); width > data.Width {
+			data.Width = width
+		}
+	}
+
+	return data
+}
+
+// ----------------------------------------------------------------------------
+//  Type: TemplateGroup
+// ----------------------------------------------------------------------------
+
+// TemplateGroup is a group of tasks returned by the "groupBy" function of the
+// templates.
+type TemplateGroup struct {
+	// Date is the due date of the group when grouped by "due".
+	Date time.Time
+	// Name is the project, the context or the due date of the group. It is
+	// empty for the tasks without any.
+	Name string
+	// Tasks are the tasks of the group.
+	Tasks []*Task
+}
+
+// ----------------------------------------------------------------------------
+//  Type: Formatter
+// ----------------------------------------------------------------------------
+
+// Formatter renders tasks and task lists with a text/template. Besides the
+// built-in functions of text/template, the templates can use:
+//
+//   - text TASK: the task in todo.txt format, as String() does.
+//   - colored TASK: the segments of the task (see Task.Segments) decorated by
+//     Color and joined by spaces.
+//   - segments TASK: the segments of the task.
+//   - segment TASK TYPE: the display text of the first segment of the type,
+//     such as "Priority" or "DueDate", or an empty string if none.
+//   - color TYPE TEXT: the text decorated by Color as a segment of the type.
+//   - relative DATE: the date relative to today, such as "today", "in 3 days"
+//     or "2 days ago". Empty for a zero date.
+//   - date DATE: the date in "YYYY-MM-DD" format. Empty for a zero date.
+//   - days DATE: the number of days from today to the date.
+//   - pad NUMBER WIDTH: the number zero-padded to the width, like todo.sh.
+//   - open TASKS: the tasks which are not completed.
+//   - groupBy KEY TASKS: the tasks grouped by "project", "context" or "due"
+//     as a list of TemplateGroup. The groups are sorted by name or date, and
+//     the group of the tasks without any is the last.
+//   - join, lower, upper: the functions of the strings package.
+//
+// The zero value is not usable, use NewFormatter.
+type Formatter struct {
+	// Clock is used for the relative dates. Defaults to the real time.
+	Clock Clock
+	// Color is the colour hook of the "color" and "colored" functions.
+	// Defaults to no decoration.
+	Color ColorFunc
+
+	tmpl *template.Template
+}
+
+// NewFormatter returns a Formatter of the template text, such as TemplateLS.
+func NewFormatter(text string) (*Formatter, error) {
+	formatter := &Formatter{Clock: nil, Color: nil, tmpl: nil}
+
+	tmpl, err := template.New("todo").Funcs(formatter.funcMap()).Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse template")
+	}
+
+	formatter.tmpl = tmpl
+
+	return formatter, nil
+}
+
+// Execute renders the template with any data.
+func (f *Formatter) Execute(writer io.Writer, data any) error {
+	return errors.Wrap(f.tmpl.Execute(writer, data), "failed to execute template")
+}
+
+// FormatTask returns the task rendered by the template. The data of the
+// template is the *Task.
+func (f *Formatter) FormatTask(task *Task) (string, error) {
+	var builder strings.Builder
+
+	if err := f.Execute(&builder, task); err != nil {
+		return emptyStr, err
+	}
+
+	return builder.String(), nil
+}
+
+// FormatList renders the task list to the writer. The data of the template is
+// the TemplateListData of all the tasks.
+func (f *Formatter) FormatList(writer io.Writer, tasklist TaskList) error {
+	return f.Execute(writer, NewTemplateListData(tasklist, len(tasklist)))
+}
+
+// ----------------------------------------------------------------------------
+//  Methods: template functions
+// ----------------------------------------------------------------------------
+
+// funcMap returns the functions of the templates.
+func (f *Formatter) funcMap() template.FuncMap {
+	return template.FuncMap{
+		"text":     func(task *Task) string { return task.String() },
+		"colored":  f.colored,
+		"segments": func(task *Task) []*TaskSegment { return task.Segments() },
+		"segment":  segmentDisplay,
+		"color":    f.color,
+		"relative": f.relative,
+		"date":     formatDate,
+		"days":     f.days,
+		"pad":      padNumber,
+		"open":     openTasks,
+		"groupBy":  groupTasks,
+		"join":     strings.Join,
+		"lower":    strings.ToLower,
+		"upper":    strings.ToUpper,
+	}
+}
+
+// color returns the text decorated by the colour hook. The type is a
+// TaskSegmentType, its value or its name.
+func (f *Formatter) color(segType any, text string) (string, error) {
+	var typed TaskSegmentType
+
+	switch value := segType.(type) {
+	case TaskSegmentType:
+		typed = value
+	case int:
+		typed = TaskSegmentType(value)
+	case string:
+		if err := typed.UnmarshalText([]byte(value)); err != nil {
+			return emptyStr, err
+		}
+	default:
+		return emptyStr, errors.Errorf("invalid segment type: %v", segType)
+	}
+
+	if f.Color == nil {
+		return text, nil
+	}
+
+	return f.Color(typed, text), nil
+}
+
+// colored returns the segments of the task decorated by the colour hook.
+func (f *Formatter) colored(task *Task) string {
+	segments := task.Segments()
+	parts := make([]string, len(segments))
+
+	for i, segment := range segments {
+		parts[i] = segment.Display
+		if f.Color != nil {
+			parts[i] = f.Color(segment.Type, segment.Display)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// days returns the number of days from today to the date.
+func (f *Formatter) days(date time.Time) int {
+	return daysFromToday(f.Clock, date)
+}
+
+// relative returns the date relative to today.
+func (f *Formatter) relative(date time.Time) string {
+	if date.IsZero() {
+		return emptyStr
+	}
+
+	switch days := f.days(date); {
+	case days == 0:
+		return "today"
+	case days == 1:
+		return "tomorrow"
+	case days == -1:
+		return "yesterday"
+	case days > 1:
+		return "in " + strconv.Itoa(days) + " days"
+	default:
+		return strconv.Itoa(-days) + " days ago"
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// daysFromToday returns the number of days from today of the clock to the
+// date. A nil clock is the real time.
+func daysFromToday(clock Clock, date time.Time) int {
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	now := clock.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	return int(day.Sub(today) / oneDay)
+}
+
+// groupTasks groups the tasks by "project", "context" or "due".
+func groupTasks(key string, tasks []*Task) ([]TemplateGroup, error) {
+	var keysOf func(task *Task) []string
+
+	switch key {
+	case "project":
+		keysOf = func(task *Task) []string { return task.Projects }
+	case "context":
+		keysOf = func(task *Task) []string { return task.Contexts }
+	case "due":
+		keysOf = func(task *Task) []string {
+			if !task.HasDueDate() {
+				return nil
+			}
+
+			return []string{formatDate(task.DueDate)}
+		}
+	default:
+		return nil, errors.New("unknown group key: " + key)
+	}
+
+	groups := map[string]*TemplateGroup{}
+	none := &TemplateGroup{Date: time.Time{}, Name: emptyStr, Tasks: nil}
+
+	for _, task := range tasks {
+		names := keysOf(task)
+		if len(names) == 0 {
+			none.Tasks = append(none.Tasks, task)
+
+			continue
+		}
+
+		for _, name := range names {
+			if groups[name] == nil {
+				groups[name] = &TemplateGroup{Date: time.Time{}, Name: name, Tasks: nil}
+
+				if key == "due" {
+					groups[name].Date = task.DueDate
+				}
+			}
+
+			groups[name].Tasks = append(groups[name].Tasks, task)
+		}
+	}
+
+	result := make([]TemplateGroup, 0, len(groups)+1)
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	if len(none.Tasks) > 0 {
+		result = append(result, *none)
+	}
+
+	return result, nil
+}
+
+// openTasks returns the tasks which are not completed.
+func openTasks(tasks []*Task) []*Task {
+	result := []*Task{}
+
+	for _, task := range tasks {
+		if !task.Completed {
+			result = append(result, task)
+		}
+	}
+
+	return result
+}
+
+// padNumber returns the number zero-padded to the width.
+func padNumber(number, width int) string {
+	str := strconv.Itoa(number)
+	if len(str) >= width {
+		return str
+	}
+
+	return strings.Repeat("0", width-len(str)) + str
+}
+
+// segmentDisplay returns the display text of the first segment of the type.
+func segmentDisplay(task *Task, name string) (string, error) {
+	var segType TaskSegmentType
+
+	if err := segType.UnmarshalText([]byte(name)); err != nil {
+		return emptyStr, err
+	}
+
+	for _, segment := range task.Segments() {
+		if segment.Type == segType {
+			return segment.Display, nil
+		}
+	}
+
+	return emptyStr, nil
+}
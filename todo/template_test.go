@@ -0,0 +1,166 @@
+package todo
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testTemplateList is the task list of the template tests.
+const testTemplateList = `(A) Call Mom @Phone +Family due:2024-01-17
+Plan garden +Garden +Family due:2024-01-10
+x 2024-01-03 Buy seeds +Garden
+(B) Read book due:2024-01-20
+Fix fence @Home
+Water plants
+Pay bills due:2024-01-16
+Clean desk
+Write report
+Book flights @Phone
+`
+
+// testFormatList renders the list with the template and a fake clock on
+// 2024-01-16.
+func testFormatList(t *testing.T, text string, color ColorFunc) string {
+	t.Helper()
+
+	formatter, err := NewFormatter(text)
+	require.NoError(t, err)
+
+	formatter.Clock = &fakeClock{now: time.Date(2024, 1, 16, 10, 0, 0, 0, time.Local)}
+	formatter.Color = color
+
+	var buf bytes.Buffer
+
+	require.NoError(t, formatter.FormatList(&buf, testMustLoad(t, testTemplateList)))
+
+	return buf.String()
+}
+
+func TestFormatter_TemplateLS(t *testing.T) {
+	t.Parallel()
+
+	expect := `01 (A) Call Mom @Phone +Family due:2024-01-17
+02 Plan garden +Family +Garden due:2024-01-10
+03 x 2024-01-03 Buy seeds +Garden
+04 (B) Read book due:2024-01-20
+05 Fix fence @Home
+06 Water plants
+07 Pay bills due:2024-01-16
+08 Clean desk
+09 Write report
+10 Book flights @Phone
+--
+TODO: 10 of 10 tasks shown
+`
+	require.Equal(t, expect, testFormatList(t, TemplateLS, nil))
+}
+
+func TestFormatter_TemplateAgenda(t *testing.T) {
+	t.Parallel()
+
+	expect := `2024-01-10 (6 days ago)
+  Plan garden +Family +Garden
+2024-01-16 (today)
+  Pay bills
+2024-01-17 (tomorrow)
+  (A) Call Mom +Family
+2024-01-20 (in 4 days)
+  (B) Read book
+No due date
+  Fix fence
+  Water plants
+  Clean desk
+  Write report
+  Book flights
+`
+	require.Equal(t, expect, testFormatList(t, TemplateAgenda, nil))
+}
+
+func TestFormatter_TemplateByProject(t *testing.T) {
+	t.Parallel()
+
+	color := func(segType TaskSegmentType, text string) string {
+		if segType == SegmentProject {
+			return "[" + text + "]"
+		}
+
+		return text
+	}
+
+	expect := `[+Family]
+  01 (A) Call Mom @Phone [+Family] due:2024-01-17
+  02 Plan garden [+Family] [+Garden] due:2024-01-10
+[+Garden]
+  02 Plan garden [+Family] [+Garden] due:2024-01-10
+  03 x 2024-01-03 Buy seeds [+Garden]
+(no project)
+  04 (B) Read book due:2024-01-20
+  05 Fix fence @Home
+  06 Water plants
+  07 Pay bills due:2024-01-16
+  08 Clean desk
+  09 Write report
+  10 Book flights @Phone
+`
+	require.Equal(t, expect, testFormatList(t, TemplateByProject, color))
+}
+
+func TestFormatter_FormatTask(t *testing.T) {
+	t.Parallel()
+
+	formatter, err := NewFormatter(`{{.ID}}: {{upper .Todo}} [{{segment . "Priority"}}]` +
+		`{{range segments .}}{{if eq .Type 10}} due {{relative $.DueDate}} ({{days $.DueDate}}){{end}}{{end}}` +
+		` {{date .CreatedDate}}|{{relative .CompletedDate}}|{{join .Contexts ","}}|{{color 6 "text"}}|{{text .}}`)
+	require.NoError(t, err)
+
+	formatter.Clock = &fakeClock{now: time.Date(2024, 1, 16, 23, 59, 0, 0, time.Local)}
+
+	task, err := ParseTask("2024-01-01 Call Mom @Phone @Home due:2024-01-19")
+	require.NoError(t, err)
+
+	task.ID = 3
+
+	actual, err := formatter.FormatTask(task)
+	require.NoError(t, err)
+	require.Equal(t, "3: CALL MOM [] due in 3 days (3) 2024-01-01||Home,Phone|text|"+
+		"2024-01-01 Call Mom @Home @Phone due:2024-01-19", actual)
+
+	// Relative dates
+	for days, expect := range map[int]string{0: "today", 1: "tomorrow", -1: "yesterday", -5: "5 days ago"} {
+		require.Equal(t, expect, formatter.relative(time.Date(2024, 1, 16+days, 0, 0, 0, 0, time.Local)))
+	}
+}
+
+func TestNewFormatter_error(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewFormatter("{{.Tasks")
+	require.ErrorContains(t, err, "failed to parse template")
+
+	formatter, err := NewFormatter(`{{groupBy "unknown" .Tasks}}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	err = formatter.FormatList(&buf, NewTaskList())
+	require.ErrorContains(t, err, "unknown group key: unknown")
+
+	formatter, err = NewFormatter(`{{color 1.5 "text"}}`)
+	require.NoError(t, err)
+
+	err = formatter.Execute(&buf, nil)
+	require.ErrorContains(t, err, "invalid segment type: 1.5")
+
+	formatter, err = NewFormatter(`{{segment . "prio"}}`)
+	require.NoError(t, err)
+
+	task := NewTask()
+
+	_, err = formatter.FormatTask(&task)
+	require.ErrorContains(t, err, "unknown segment type: prio")
+
+	require.Equal(t, "123", padNumber(123, 2), "long numbers should not be cut")
+}
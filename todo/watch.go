@@ -0,0 +1,316 @@
+package todo
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Constants
+// ----------------------------------------------------------------------------
+
+const (
+	// DefaultWatchDebounce is the default delay of WatchOptions.Debounce.
+	DefaultWatchDebounce = 100 * time.Millisecond
+	// DefaultWatchPollInterval is the default interval of WatchOptions.PollInterval.
+	DefaultWatchPollInterval = time.Second
+)
+
+// ----------------------------------------------------------------------------
+//  Type: WatchOptions
+// ----------------------------------------------------------------------------
+
+// WatchOptions configures WatchPath. The zero value uses the defaults.
+type WatchOptions struct {
+	// Debounce is the delay without further changes before the file is
+	// reloaded, so that an editor saving in several steps (truncate, write,
+	// rename, ...) results in a single event. Defaults to DefaultWatchDebounce.
+	Debounce time.Duration
+	// PollInterval is the interval to check the file when polling. Defaults to
+	// DefaultWatchPollInterval.
+	PollInterval time.Duration
+	// Poll checks the file periodically instead of using inotify. Polling is
+	// always used on platforms other than Linux, or if inotify fails.
+	Poll bool
+}
+
+// ----------------------------------------------------------------------------
+//  Type: WatchEvent
+// ----------------------------------------------------------------------------
+
+// WatchEvent is sent by a Watcher when the contents of the file changed.
+type WatchEvent struct {
+	// Changes are the semantic changes of the tasks since the previous event
+	// (added, removed, completed, reopened or modified), as reported by Diff.
+	Changes ChangeSet
+	// TaskList is the reloaded task list. A removed file is an empty list.
+	TaskList TaskList
+	// Stamp is the stamp of the reloaded file. Pass it to
+	// WriteToPathIfUnchanged to save changes on top of TaskList.
+	Stamp FileStamp
+	// Err is set if the file could not be reloaded. The other fields are then
+	// empty and the next event reports the changes since the last successful
+	// load.
+	Err error
+}
+
+// ----------------------------------------------------------------------------
+//  Type: Watcher
+// ----------------------------------------------------------------------------
+
+// Watcher monitors a todo.txt file and sends an event for each change of its
+// tasks. It is created by WatchPath and must be closed with Close.
+type Watcher struct {
+	// Events receives the changes of the file. It is closed by Close.
+	Events <-chan WatchEvent
+
+	events    chan WatchEvent
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	stop      func() error
+	filename  string
+	initial   TaskList
+	tasklist  TaskList
+	stamp     FileStamp
+	debounce  time.Duration
+	polling   bool
+}
+
+// ----------------------------------------------------------------------------
+//  Constructors
+// ----------------------------------------------------------------------------
+
+// WatchPath starts watching the given todo.txt file and returns the Watcher.
+//
+// The file is loaded once at the start, then reloaded with LoadFromPath after
+// each change and compared to the previous contents with Diff. An event is
+// sent only if the tasks changed, so rewriting the same contents or touching
+// the file sends nothing.
+//
+// On Linux, the directory of the file is watched with inotify, so that files
+// replaced atomically (as UpdatePath and most editors do) keep being watched.
+// Otherwise the file is polled every PollInterval. A missing file is watched
+// as an empty list until it is created.
+func WatchPath(filename string, opts WatchOptions) (*Watcher, error) {
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultWatchDebounce
+	}
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultWatchPollInterval
+	}
+
+	watcher, err := newWatcher(filename, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var notify <-chan struct{}
+
+	if !opts.Poll {
+		notify, watcher.stop, err = newNotifier(filename)
+		watcher.polling = err != nil
+	}
+
+	if watcher.polling {
+		notify, watcher.stop = newPoller(filename, opts.PollInterval)
+	}
+
+	go watcher.run(notify)
+
+	return watcher, nil
+}
+
+// newWatcher loads the file and returns the Watcher of it, not started yet.
+func newWatcher(filename string, opts WatchOptions) (*Watcher, error) {
+	tasklist, stamp, err := loadWatched(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan WatchEvent)
+
+	return &Watcher{
+		Events:    events,
+		events:    events,
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+		closeOnce: sync.Once{},
+		stop:      nil,
+		filename:  filename,
+		initial:   tasklist,
+		tasklist:  tasklist,
+		stamp:     stamp,
+		debounce:  opts.Debounce,
+		polling:   opts.Poll,
+	}, nil
+}
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// Close stops watching the file and closes the Events channel. It is safe to
+// call it several times.
+func (watcher *Watcher) Close() error {
+	var err error
+
+	watcher.closeOnce.Do(func() {
+		err = watcher.stop()
+
+		close(watcher.done)
+		<-watcher.stopped
+	})
+
+	return err
+}
+
+// Polling returns true if the file is polled instead of being watched with
+// file system notifications.
+func (watcher *Watcher) Polling() bool {
+	return watcher.polling
+}
+
+// TaskList returns the task list loaded at the start of the watch, to which
+// the changes of the first event apply.
+func (watcher *Watcher) TaskList() TaskList {
+	return watcher.initial
+}
+
+// check reloads the file if its stamp changed and returns the event to send,
+// if any.
+func (watcher *Watcher) check() (WatchEvent, bool) {
+	stamp, err := StampPath(watcher.filename)
+	if err == nil && stamp.Equal(watcher.stamp) {
+		return WatchEvent{}, false
+	}
+
+	tasklist, stamp, err := loadWatched(watcher.filename)
+	if err != nil {
+		return WatchEvent{Changes: nil, TaskList: nil, Stamp: FileStamp{}, Err: err}, true
+	}
+
+	changes := Diff(watcher.tasklist, tasklist)
+	watcher.tasklist, watcher.stamp = tasklist, stamp
+
+	if len(changes) == 0 {
+		return WatchEvent{}, false
+	}
+
+	return WatchEvent{Changes: changes, TaskList: tasklist, Stamp: stamp, Err: nil}, true
+}
+
+// run waits for the notifications and sends the events once the file has been
+// quiet for the debounce delay, until the watcher is closed.
+func (watcher *Watcher) run(notify <-chan struct{}) {
+	timer := time.NewTimer(watcher.debounce)
+	timer.Stop()
+
+	defer timer.Stop()
+
+	watcher.loop(notify, timer.C, func() { timer.Reset(watcher.debounce) })
+}
+
+// loop calls reset on each notification and checks the file each time quiet
+// fires, until the watcher is closed. Tests drive it with their own channels
+// instead of a timer.
+func (watcher *Watcher) loop(notify <-chan struct{}, quiet <-chan time.Time, reset func()) {
+	defer close(watcher.stopped)
+	defer close(watcher.events)
+
+	for {
+		select {
+		case <-watcher.done:
+			return
+		case _, ok := <-notify:
+			if !ok {
+				notify = nil // keep serving Close only
+
+				continue
+			}
+
+			reset()
+		case <-quiet:
+			event, changed := watcher.check()
+			if !changed {
+				continue
+			}
+
+			select {
+			case watcher.events <- event:
+			case <-watcher.done:
+				return
+			}
+		}
+	}
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// loadWatched loads the file with its stamp. A missing file is an empty list.
+func loadWatched(filename string) (TaskList, FileStamp, error) {
+	tasklist, stamp, err := LoadFromPathWithStamp(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewTaskList(), FileStamp{}, nil
+	}
+
+	return tasklist, stamp, err
+}
+
+// newPoller returns a channel notified when the size or the modification
+// time of the file changes, checked every interval, and the function to stop
+// it.
+func newPoller(filename string, interval time.Duration) (<-chan struct{}, func() error) {
+	notify := make(chan struct{}, 1)
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	last := statKey(filename)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if current := statKey(filename); current != last {
+					last = current
+					notifyChange(notify)
+				}
+			}
+		}
+	}()
+
+	return notify, func() error {
+		ticker.Stop()
+		close(done)
+
+		return nil
+	}
+}
+
+// notifyChange notifies the channel without blocking. A pending notification
+// is enough since the file is checked as a whole.
+func notifyChange(notify chan<- struct{}) {
+	select {
+	case notify <- struct{}{}:
+	default:
+	}
+}
+
+// statKey returns the size and the modification time of the file, or an empty
+// string if it does not exist.
+func statKey(filename string) string {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return emptyStr
+	}
+
+	return strconv.FormatInt(info.Size(), 10) + " " + info.ModTime().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,81 @@
+//go:build linux
+// +build linux
+
+package todo
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// inotifyMask are the inotify events of the directory which may change the
+// watched file, including the atomic replacement by a rename.
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ATTRIB
+
+// newNotifier watches the directory of the file with inotify. It returns a
+// channel notified when the file may have changed and the function to stop
+// watching.
+func newNotifier(filename string) (<-chan struct{}, func() error, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to initialize inotify")
+	}
+
+	_, err = syscall.InotifyAddWatch(fd, filepath.Dir(filename), inotifyMask)
+	if err != nil {
+		_ = syscall.Close(fd)
+
+		return nil, nil, errors.Wrap(err, "failed to watch directory of: "+filename)
+	}
+
+	// A non-blocking descriptor uses the runtime poller, so that Close
+	// interrupts the pending Read.
+	file := os.NewFile(uintptr(fd), "inotify")
+	notify := make(chan struct{}, 1)
+
+	go func() {
+		defer close(notify)
+
+		buf := make([]byte, (syscall.SizeofInotifyEvent+syscall.NAME_MAX+1)*16) //nolint:mnd // room for 16 events
+
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				return
+			}
+
+			if inotifyMatch(buf[:n], filepath.Base(filename)) {
+				notifyChange(notify)
+			}
+		}
+	}()
+
+	return notify, func() error {
+		return errors.Wrap(file.Close(), "failed to close inotify")
+	}, nil
+}
+
+// inotifyMatch returns true if one of the inotify events of the buffer is about
+// the file name, or if events were lost.
+func inotifyMatch(buf []byte, name string) bool {
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buf); {
+		// struct inotify_event: int32 wd, uint32 mask, uint32 cookie, uint32 len, name
+		mask := binary.NativeEndian.Uint32(buf[offset+4:])
+		size := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+
+		start := offset + syscall.SizeofInotifyEvent
+		offset = min(start+size, len(buf))
+
+		if mask&syscall.IN_Q_OVERFLOW != 0 || strings.TrimRight(string(buf[start:offset]), "\x00") == name {
+			return true
+		}
+	}
+
+	return false
+}
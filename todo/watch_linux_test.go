@@ -0,0 +1,29 @@
+//go:build linux
+// +build linux
+
+package todo
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_inotifyMatch(t *testing.T) {
+	t.Parallel()
+
+	// struct inotify_event with the name padded to 8 bytes
+	event := func(mask uint32, name string) []byte {
+		buf := make([]byte, 16, 24)
+		binary.NativeEndian.PutUint32(buf[4:], mask)
+		binary.NativeEndian.PutUint32(buf[12:], 8)
+
+		return append(buf, (name + "\x00\x00\x00\x00\x00\x00\x00\x00")[:8]...)
+	}
+
+	require.True(t, inotifyMatch(append(event(2, "a.txt"), event(2, "todo.txt")...), "todo.txt"))
+	require.False(t, inotifyMatch(event(2, "todo.tx"), "todo.txt"))
+	require.True(t, inotifyMatch(event(0x4000, ""), "todo.txt"), "lost events should match")
+	require.False(t, inotifyMatch([]byte{1, 2, 3}, "todo.txt"), "truncated event should be ignored")
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package todo
+
+import "github.com/pkg/errors"
+
+// errNotifyUnsupported is returned by newNotifier since the platform has no
+// supported file system notifications, so that the watcher falls back to
+// polling.
+var errNotifyUnsupported = errors.New("file system notifications are not supported")
+
+// newNotifier returns errNotifyUnsupported, so that the file is polled.
+func newNotifier(string) (<-chan struct{}, func() error, error) {
+	return nil, nil, errNotifyUnsupported
+}
@@ -0,0 +1,274 @@
+package todo
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testWatchOptions are short delays to keep the watch tests fast.
+func testWatchOptions(poll bool) WatchOptions {
+	return WatchOptions{Debounce: 20 * time.Millisecond, PollInterval: 5 * time.Millisecond, Poll: poll}
+}
+
+// testNextEvent returns the next event of the watcher, failing after a timeout.
+func testNextEvent(t *testing.T, watcher *Watcher) WatchEvent {
+	t.Helper()
+
+	select {
+	case event, ok := <-watcher.Events:
+		require.True(t, ok, "events should not be closed")
+
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for a watch event")
+	}
+
+	return WatchEvent{}
+}
+
+// testChangeKinds returns the kinds of the changes of the event.
+func testChangeKinds(event WatchEvent) []ChangeKind {
+	kinds := []ChangeKind{}
+	for _, change := range event.Changes {
+		kinds = append(kinds, change.Kind)
+	}
+
+	return kinds
+}
+
+// testCheck checks the file of the watcher like after the debounce delay and
+// returns the event, failing if there is none.
+func testCheck(t *testing.T, watcher *Watcher) WatchEvent {
+	t.Helper()
+
+	event, changed := watcher.check()
+	require.True(t, changed, "an event should be sent")
+
+	return event
+}
+
+// testStartWatcher starts the watcher of the file, driven by the returned
+// notify and quiet channels instead of the file system and a timer. The
+// returned counter holds the number of debounce resets, to be read once an
+// event is received.
+func testStartWatcher(t *testing.T, pathFile string) (*Watcher, chan<- struct{}, chan<- time.Time, *int) {
+	t.Helper()
+
+	watcher, err := newWatcher(pathFile, WatchOptions{})
+	require.NoError(t, err)
+
+	watcher.stop = func() error { return nil }
+
+	notify := make(chan struct{})
+	quiet := make(chan time.Time)
+	resets := 0
+
+	go watcher.loop(notify, quiet, func() { resets++ })
+
+	return watcher, notify, quiet, &resets
+}
+
+func TestWatchPath(t *testing.T) {
+	t.Parallel()
+
+	for name, poll := range map[string]bool{"notify": false, "poll": true} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			pathFile := testGetPathFileTemp(t, testOutput)
+			require.NoError(t, os.WriteFile(pathFile, []byte("Call Mom\n"), 0o600))
+
+			watcher, err := WatchPath(pathFile, testWatchOptions(poll))
+			require.NoError(t, err)
+
+			defer func() { require.NoError(t, watcher.Close()) }()
+
+			require.Equal(t, poll || runtime.GOOS != "linux", watcher.Polling())
+			require.Len(t, watcher.TaskList(), 1)
+
+			// Added via an atomic replacement, so no intermediate contents
+			require.NoError(t, UpdateLinesPath(pathFile, func(lines []string) ([]string, error) {
+				return append(lines, "Buy milk"), nil
+			}))
+
+			event := testNextEvent(t, watcher)
+			require.NoError(t, event.Err)
+			require.Equal(t, []ChangeKind{ChangeAdded}, testChangeKinds(event))
+			require.Equal(t, "Buy milk", event.Changes[0].New.String())
+			require.Len(t, event.TaskList, 2)
+
+			// Removed file
+			require.NoError(t, os.Remove(pathFile))
+
+			event = testNextEvent(t, watcher)
+			require.Equal(t, []ChangeKind{ChangeRemoved, ChangeRemoved}, testChangeKinds(event))
+			require.Empty(t, event.TaskList)
+			require.True(t, event.Stamp.IsZero())
+		})
+	}
+}
+
+func TestWatchPath_errors(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+	require.NoError(t, os.WriteFile(pathFile, []byte("Pay bills due:2024-02-31\n"), 0o600))
+
+	watcher, err := WatchPath(pathFile, WatchOptions{Debounce: 0, PollInterval: 0, Poll: false})
+	require.Error(t, err)
+	require.Nil(t, watcher)
+
+	// Missing directory falls back to polling
+	pathMissing := filepath.Join(filepath.Dir(pathFile), "missing", testOutput)
+
+	watcher, err = WatchPath(pathMissing, WatchOptions{Debounce: 0, PollInterval: 0, Poll: false})
+	require.NoError(t, err)
+	require.True(t, watcher.Polling())
+	require.Equal(t, DefaultWatchDebounce, watcher.debounce)
+	require.NoError(t, watcher.Close())
+}
+
+func TestWatcher_check(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+	require.NoError(t, os.WriteFile(pathFile, []byte("Call Mom\n"), 0o600))
+
+	watcher, err := newWatcher(pathFile, WatchOptions{})
+	require.NoError(t, err)
+
+	// Added
+	require.NoError(t, UpdateLinesPath(pathFile, func(lines []string) ([]string, error) {
+		return append(lines, "Buy milk"), nil
+	}))
+
+	event := testCheck(t, watcher)
+	require.NoError(t, event.Err)
+	require.Equal(t, []ChangeKind{ChangeAdded}, testChangeKinds(event))
+
+	stamp, err := StampPath(pathFile)
+	require.NoError(t, err)
+	require.True(t, stamp.Equal(event.Stamp))
+
+	// Editor saving in several steps, checked once settled
+	file, err := os.OpenFile(pathFile, os.O_WRONLY|os.O_TRUNC, 0o600)
+	require.NoError(t, err)
+
+	_, err = file.WriteString("x 2024-01-02 Call Mom\n")
+	require.NoError(t, err)
+
+	_, err = file.WriteString("(A) Buy milk\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	event = testCheck(t, watcher)
+	require.Equal(t, []ChangeKind{ChangeCompleted, ChangeModified}, testChangeKinds(event))
+
+	// Unchanged, then same tasks with other formatting
+	_, changed := watcher.check()
+	require.False(t, changed, "unchanged file should send nothing")
+
+	require.NoError(t, os.WriteFile(pathFile, []byte("x 2024-01-02 Call Mom\n\n(A) Buy milk\n"), 0o600))
+
+	_, changed = watcher.check()
+	require.False(t, changed, "same tasks should send nothing")
+
+	// Removed file
+	require.NoError(t, os.Remove(pathFile))
+
+	event = testCheck(t, watcher)
+	require.Equal(t, []ChangeKind{ChangeRemoved, ChangeRemoved}, testChangeKinds(event))
+	require.Empty(t, event.TaskList)
+	require.True(t, event.Stamp.IsZero())
+}
+
+func TestWatcher_check_reload_error(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+
+	watcher, err := newWatcher(pathFile, WatchOptions{})
+	require.NoError(t, err)
+	require.Empty(t, watcher.TaskList(), "missing file should be an empty list")
+
+	require.NoError(t, os.WriteFile(pathFile, []byte("Pay bills due:2024-02-31\n"), 0o600))
+
+	event := testCheck(t, watcher)
+	require.Error(t, event.Err)
+	require.Nil(t, event.TaskList)
+
+	// Fixed: the changes are since the last successful load
+	require.NoError(t, os.WriteFile(pathFile, []byte("Pay bills due:2024-02-28\n"), 0o600))
+
+	event = testCheck(t, watcher)
+	require.NoError(t, event.Err)
+	require.Equal(t, []ChangeKind{ChangeAdded}, testChangeKinds(event))
+}
+
+func TestWatcher_loop(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+	require.NoError(t, os.WriteFile(pathFile, []byte("Call Mom\n"), 0o600))
+
+	watcher, notify, quiet, resets := testStartWatcher(t, pathFile)
+
+	// Notifications only reset the debounce delay
+	for range 3 {
+		notify <- struct{}{}
+	}
+
+	require.NoError(t, os.WriteFile(pathFile, []byte("Call Mom\nBuy milk\n"), 0o600))
+
+	quiet <- time.Time{}
+
+	event := testNextEvent(t, watcher)
+	require.Equal(t, []ChangeKind{ChangeAdded}, testChangeKinds(event))
+	require.Equal(t, 3, *resets)
+
+	// Unchanged file sends nothing, so the next event is the next change. The
+	// notification is received once the check is done.
+	quiet <- time.Time{}
+	notify <- struct{}{}
+
+	require.NoError(t, os.WriteFile(pathFile, []byte("x 2024-01-02 Call Mom\nBuy milk\n"), 0o600))
+
+	quiet <- time.Time{}
+
+	event = testNextEvent(t, watcher)
+	require.Equal(t, []ChangeKind{ChangeCompleted}, testChangeKinds(event))
+
+	// Closed notifications keep serving Close
+	close(notify)
+
+	require.NoError(t, watcher.Close())
+	require.NoError(t, watcher.Close(), "closing twice should be a no-op")
+
+	_, ok := <-watcher.Events
+	require.False(t, ok, "events should be closed")
+}
+
+func Test_newPoller(t *testing.T) {
+	t.Parallel()
+
+	pathFile := testGetPathFileTemp(t, testOutput)
+
+	notify, stop := newPoller(pathFile, time.Millisecond)
+
+	defer func() { require.NoError(t, stop()) }()
+
+	require.NoError(t, os.WriteFile(pathFile, []byte("Call Mom\n"), 0o600))
+
+	select {
+	case <-notify:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the poller")
+	}
+
+	require.Equal(t, emptyStr, statKey(filepath.Join(filepath.Dir(pathFile), "missing")))
+}
@@ -0,0 +1,390 @@
+package todo
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ----------------------------------------------------------------------------
+//  Constants
+// ----------------------------------------------------------------------------
+
+// Default file names of a todo.txt directory, the same as todo.sh.
+const (
+	DefaultTodoFile   = "todo.txt"
+	DefaultDoneFile   = "done.txt"
+	DefaultReportFile = "report.txt"
+
+	// listExt is the extension of list files.
+	listExt = ".txt"
+)
+
+// ----------------------------------------------------------------------------
+//  Type: Workspace
+// ----------------------------------------------------------------------------
+
+// Workspace represents a todo.txt directory, usually containing "todo.txt",
+// "done.txt", "report.txt" and any other lists (e.g. "someday.txt").
+//
+// Lists are referred by their name, which is the file name relative to Dir,
+// with or without the ".txt" extension (e.g. "someday" or "someday.txt").
+// An empty name refers to TodoFile. All the updates are done via UpdatePath,
+// so they are safe against other programs using the same locking.
+type Workspace struct {
+	Clock      Clock  // Clock is used for the report. Defaults to the real time.
+	Dir        string // Dir is the todo.txt directory.
+	TodoFile   string // TodoFile is the path of the active tasks.
+	DoneFile   string // DoneFile is the path of the archived tasks.
+	ReportFile string // ReportFile is the path of the report.
+}
+
+// ----------------------------------------------------------------------------
+//  Type: WorkspaceTask
+// ----------------------------------------------------------------------------
+
+// WorkspaceTask is a Task found in a list of a Workspace.
+type WorkspaceTask struct {
+	List string // List is the path of the list file.
+	Task Task   // Task is the found task. Task.ID is the ID in the list.
+}
+
+// ----------------------------------------------------------------------------
+//  Constructors
+// ----------------------------------------------------------------------------
+
+// OpenWorkspace returns a Workspace of the given directory with the default
+// file names of todo.sh. The directory must exist, but the files do not need
+// to.
+func OpenWorkspace(dir string) (*Workspace, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open workspace: "+dir)
+	}
+
+	if !info.IsDir() {
+		return nil, errors.New("workspace is not a directory: " + dir)
+	}
+
+	return &Workspace{
+		Clock:      realClock{},
+		Dir:        dir,
+		TodoFile:   filepath.Join(dir, DefaultTodoFile),
+		DoneFile:   filepath.Join(dir, DefaultDoneFile),
+		ReportFile: filepath.Join(dir, DefaultReportFile),
+	}, nil
+}
+
+// OpenWorkspaceFromEnv returns a Workspace located by the environment variables
+// of todo.sh.
+//
+//   - TODO_DIR is the todo.txt directory. If not set, the directory of TODO_FILE
+//     is used, or "~/.todo" if neither is set.
+//   - TODO_FILE, DONE_FILE and REPORT_FILE override the paths of the files.
+func OpenWorkspaceFromEnv() (*Workspace, error) {
+	dir := os.Getenv("TODO_DIR")
+	if isEmpty(dir) {
+		if todoFile := os.Getenv("TODO_FILE"); isNotEmpty(todoFile) {
+			dir = filepath.Dir(todoFile)
+		} else {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to locate the todo.txt directory")
+			}
+
+			dir = filepath.Join(home, ".todo")
+		}
+	}
+
+	workspace, err := OpenWorkspace(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for env, field := range map[string]*string{
+		"TODO_FILE":   &workspace.TodoFile,
+		"DONE_FILE":   &workspace.DoneFile,
+		"REPORT_FILE": &workspace.ReportFile,
+	} {
+		if value := os.Getenv(env); isNotEmpty(value) {
+			*field = value
+		}
+	}
+
+	return workspace, nil
+}
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+// Archive moves the completed tasks from TodoFile to DoneFile. See ArchivePath.
+func (ws *Workspace) Archive(opts ArchiveOptions) (TaskList, error) {
+	return ArchivePath(ws.TodoFile, ws.DoneFile, opts)
+}
+
+// Done loads the archived tasks. A non-existing DoneFile is an empty list.
+func (ws *Workspace) Done() (TaskList, error) {
+	return loadOrEmpty(ws.DoneFile)
+}
+
+// Lists returns the paths of all the list files in Dir (files with the ".txt"
+// extension except ReportFile), sorted by name.
+func (ws *Workspace) Lists() ([]string, error) {
+	entries, err := os.ReadDir(ws.Dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read workspace: "+ws.Dir)
+	}
+
+	lists := []string{}
+
+	for _, entry := range entries {
+		path := filepath.Join(ws.Dir, entry.Name())
+
+		if entry.IsDir() || filepath.Ext(path) != listExt || samePath(path, ws.ReportFile) {
+			continue
+		}
+
+		lists = append(lists, path)
+	}
+
+	sort.Strings(lists)
+
+	return lists, nil
+}
+
+// Load loads the tasks of the named list. A non-existing list is an empty list.
+func (ws *Workspace) Load(name string) (TaskList, error) {
+	return loadOrEmpty(ws.Path(name))
+}
+
+// Move moves the task with the given ID from one list to another, for example
+// from "todo.txt" to "someday.txt". The line of the task is appended as it is
+// to the target list and the ID of the returned task is its ID there.
+//
+// Only the line of the task is changed in the source list: it is left blank,
+// unless it is the last line, like a deletion of todo.sh, so that the comments
+// and the line numbers of the other tasks are kept in both lists.
+//
+// Both lists are locked during the move. The target list is written first, and
+// restored if the source list fails to be written.
+func (ws *Workspace) Move(taskID int, from, to string) (*Task, error) {
+	pathFrom, pathTo := ws.Path(from), ws.Path(to)
+	if samePath(pathFrom, pathTo) {
+		return nil, errors.New("source and target lists are the same: " + pathFrom)
+	}
+
+	unlock, err := lockPaths(pathFrom, pathTo)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = unlock() }()
+
+	sourceRaw, source, err := readListOrEmpty(pathFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	targetRaw, target, err := readListOrEmpty(pathTo)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := source.GetTask(taskID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to move task from "+pathFrom)
+	}
+
+	moved := cloneTask(*task)
+	moved.ID = target.nextID()
+
+	sourceLines := &lineTarget{lines: splitLines(sourceRaw)}
+	index := taskLineIndex(sourceLines.lines, taskID)
+	line := strings.Trim(sourceLines.lines[index], whitespaces)
+
+	sourceLines.remove(index)
+
+	err = writeFileAtomic(pathTo, appendLines(targetRaw, []string{line}))
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeFileAtomic(pathFrom, []byte(joinLines(sourceLines.lines)))
+	if err != nil {
+		if targetRaw == nil {
+			_ = os.Remove(pathTo)
+		} else {
+			_ = writeFileAtomic(pathTo, targetRaw)
+		}
+
+		return nil, err
+	}
+
+	return &moved, nil
+}
+
+// Path returns the path of the named list. An empty name and "todo.txt" are
+// TodoFile, "done.txt" is DoneFile and absolute paths are returned as they are.
+func (ws *Workspace) Path(name string) string {
+	switch {
+	case isEmpty(name):
+		return ws.TodoFile
+	case filepath.IsAbs(name):
+		return name
+	case filepath.Ext(name) != listExt:
+		name += listExt
+	}
+
+	switch name {
+	case DefaultTodoFile:
+		return ws.TodoFile
+	case DefaultDoneFile:
+		return ws.DoneFile
+	}
+
+	return filepath.Join(ws.Dir, name)
+}
+
+// Query returns the tasks of TodoFile and DoneFile, in this order, which match
+// any of the given filters. It allows to search active and archived tasks at
+// once.
+func (ws *Workspace) Query(filter Predicate, filters ...Predicate) ([]WorkspaceTask, error) {
+	found := []WorkspaceTask{}
+
+	for _, path := range []string{ws.TodoFile, ws.DoneFile} {
+		tasklist, err := loadOrEmpty(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, task := range tasklist.Filter(filter, filters...) {
+			found = append(found, WorkspaceTask{List: path, Task: task})
+		}
+	}
+
+	return found, nil
+}
+
+// Report archives the completed tasks and appends the number of open and done
+// tasks to ReportFile, like the "report" command of todo.sh. If the numbers did
+// not change since the last report, nothing is appended and the last entry is
+// returned. See AppendReportPath.
+//
+// As todo.sh does, the numbers are the counts of lines of TodoFile and DoneFile
+// after the archive, so that the comment lines are counted too, unlike with
+// NewReportEntry.
+func (ws *Workspace) Report() (ReportEntry, error) {
+	_, err := ws.Archive(ArchiveOptions{})
+	if err != nil {
+		return ReportEntry{}, err
+	}
+
+	open, err := countLines(ws.TodoFile)
+	if err != nil {
+		return ReportEntry{}, err
+	}
+
+	done, err := countLines(ws.DoneFile)
+	if err != nil {
+		return ReportEntry{}, err
+	}
+
+	clock := ws.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	return AppendReportPath(ws.ReportFile, ReportEntry{Time: clock.Now(), Open: open, Done: done})
+}
+
+// ReportHistory loads the entries of ReportFile. See LoadReportFromPath.
+func (ws *Workspace) ReportHistory() (Report, error) {
+	return LoadReportFromPath(ws.ReportFile)
+}
+
+// Todo loads the active tasks. A non-existing TodoFile is an empty list.
+func (ws *Workspace) Todo() (TaskList, error) {
+	return loadOrEmpty(ws.TodoFile)
+}
+
+// Update applies a locked read-modify-write cycle to the named list. See
+// UpdatePath.
+func (ws *Workspace) Update(name string, update func(tasklist *TaskList) error) error {
+	return UpdatePath(ws.Path(name), update)
+}
+
+// ----------------------------------------------------------------------------
+//  Private functions
+// ----------------------------------------------------------------------------
+
+// countLines returns the number of lines of the file, like "sed -n '$ ='" used
+// by todo.sh. A non-existing file has no lines.
+func countLines(filename string) (int, error) {
+	lines, err := readLines(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+
+	return len(lines), err
+}
+
+// loadOrEmpty loads the TaskList from the file. A non-existing file is an empty
+// TaskList.
+func loadOrEmpty(filename string) (TaskList, error) {
+	tasklist, err := LoadFromPath(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewTaskList(), nil
+	}
+
+	return tasklist, err
+}
+
+// readListOrEmpty reads the file and loads its TaskList. A non-existing file is
+// empty.
+func readListOrEmpty(filename string) ([]byte, TaskList, error) {
+	//nolint:gosec // filename is provided by user, same as LoadFromPath
+	raw, err := os.ReadFile(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, errors.Wrap(err, "failed to read file: "+filename)
+	}
+
+	tasklist, err := LoadFromString(string(raw))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to load file: "+filename)
+	}
+
+	return raw, tasklist, nil
+}
+
+// samePath returns true if both paths refer to the same file path.
+func samePath(pathA, pathB string) bool {
+	absA, errA := filepath.Abs(pathA)
+	absB, errB := filepath.Abs(pathB)
+
+	return errA == nil && errB == nil && absA == absB
+}
+
+// taskLineIndex returns the index in the lines of the task with the given ID,
+// as assigned by LoadFromPath, or -1 if not found.
+func taskLineIndex(lines []string, taskID int) int {
+	count := 0
+
+	for i, line := range lines {
+		text := strings.Trim(line, whitespaces)
+		if isEmpty(text) || isComment(text) {
+			continue
+		}
+
+		count++
+
+		if count == taskID {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,366 @@
+package todo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testWorkspace returns a Workspace of a temporary directory with the given
+// files.
+func testWorkspace(t *testing.T, files map[string]string) *Workspace {
+	t.Helper()
+
+	pathDir := t.TempDir()
+
+	for name, contents := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(pathDir, name), []byte(contents), PermReadWrite))
+	}
+
+	workspace, err := OpenWorkspace(pathDir)
+	require.NoError(t, err)
+
+	return workspace
+}
+
+// ----------------------------------------------------------------------------
+//  Constructors
+// ----------------------------------------------------------------------------
+
+func TestOpenWorkspace(t *testing.T) {
+	t.Parallel()
+
+	pathDir := t.TempDir()
+
+	workspace, err := OpenWorkspace(pathDir)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(pathDir, "todo.txt"), workspace.TodoFile)
+	require.Equal(t, filepath.Join(pathDir, "done.txt"), workspace.DoneFile)
+	require.Equal(t, filepath.Join(pathDir, "report.txt"), workspace.ReportFile)
+
+	_, err = OpenWorkspace(filepath.Join(pathDir, "unknown"))
+	require.Error(t, err, "missing directory should be an error")
+
+	_, err = OpenWorkspace(testInputTasklist)
+	require.Error(t, err, "file should be an error")
+	require.Contains(t, err.Error(), "workspace is not a directory")
+}
+
+//nolint:paralleltest // do not parallel due to environment variables
+func TestOpenWorkspaceFromEnv(t *testing.T) {
+	pathDir := t.TempDir()
+	pathOther := t.TempDir()
+
+	t.Setenv("HOME", pathOther)
+	t.Setenv("TODO_DIR", pathDir)
+	t.Setenv("TODO_FILE", "")
+	t.Setenv("DONE_FILE", filepath.Join(pathOther, "archive.txt"))
+	t.Setenv("REPORT_FILE", "")
+
+	workspace, err := OpenWorkspaceFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, pathDir, workspace.Dir)
+	require.Equal(t, filepath.Join(pathDir, "todo.txt"), workspace.TodoFile)
+	require.Equal(t, filepath.Join(pathOther, "archive.txt"), workspace.DoneFile)
+
+	// Directory of TODO_FILE
+	t.Setenv("TODO_DIR", "")
+	t.Setenv("TODO_FILE", filepath.Join(pathOther, "tasks.txt"))
+
+	workspace, err = OpenWorkspaceFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, pathOther, workspace.Dir)
+	require.Equal(t, filepath.Join(pathOther, "tasks.txt"), workspace.TodoFile)
+
+	// Default "~/.todo" does not exist
+	t.Setenv("TODO_FILE", "")
+
+	_, err = OpenWorkspaceFromEnv()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), filepath.Join(pathOther, ".todo"))
+}
+
+// ----------------------------------------------------------------------------
+//  Methods
+// ----------------------------------------------------------------------------
+
+func TestWorkspace_Path(t *testing.T) {
+	t.Parallel()
+
+	workspace := &Workspace{
+		Clock:      nil,
+		Dir:        "dir",
+		TodoFile:   "tasks.txt",
+		DoneFile:   "archive.txt",
+		ReportFile: "report.txt",
+	}
+
+	pathAbs, err := filepath.Abs("someday.txt")
+	require.NoError(t, err)
+
+	for name, expect := range map[string]string{
+		"":            "tasks.txt",
+		"todo":        "tasks.txt",
+		"todo.txt":    "tasks.txt",
+		"done":        "archive.txt",
+		"someday":     filepath.Join("dir", "someday.txt"),
+		"someday.txt": filepath.Join("dir", "someday.txt"),
+		pathAbs:       pathAbs,
+	} {
+		require.Equal(t, expect, workspace.Path(name), "name: %q", name)
+	}
+}
+
+func TestWorkspace_Lists(t *testing.T) {
+	t.Parallel()
+
+	workspace := testWorkspace(t, map[string]string{
+		"todo.txt":    "Call Mom\n",
+		"someday.txt": "Learn Go\n",
+		"report.txt":  "",
+		"config":      "",
+	})
+
+	require.NoError(t, os.Mkdir(filepath.Join(workspace.Dir, "sub.txt"), PermReadWriteExec))
+
+	lists, err := workspace.Lists()
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(workspace.Dir, "someday.txt"),
+		filepath.Join(workspace.Dir, "todo.txt"),
+	}, lists)
+
+	workspace.Dir = filepath.Join(workspace.Dir, "unknown")
+
+	_, err = workspace.Lists()
+	require.Error(t, err)
+}
+
+func TestWorkspace_Load(t *testing.T) {
+	t.Parallel()
+
+	workspace := testWorkspace(t, map[string]string{
+		"todo.txt": "Call Mom\n",
+		"done.txt": "x Pick up milk\n",
+	})
+
+	todo, err := workspace.Todo()
+	require.NoError(t, err)
+	checkTaskListOrder(t, todo, []string{"Call Mom"})
+
+	done, err := workspace.Done()
+	require.NoError(t, err)
+	checkTaskListOrder(t, done, []string{"x Pick up milk"})
+
+	someday, err := workspace.Load("someday")
+	require.NoError(t, err, "non-existing list should be empty")
+	require.Empty(t, someday)
+
+	require.NoError(t, os.WriteFile(workspace.Path("broken"), []byte("2020-13-01 Task\n"), PermReadWrite))
+
+	_, err = workspace.Load("broken")
+	require.Error(t, err)
+}
+
+func TestWorkspace_Update(t *testing.T) {
+	t.Parallel()
+
+	workspace := testWorkspace(t, nil)
+
+	err := workspace.Update("someday", func(tasklist *TaskList) error {
+		task, err := ParseTask("Learn Go")
+		require.NoError(t, err)
+
+		tasklist.AddTask(task)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	someday, err := workspace.Load("someday")
+	require.NoError(t, err)
+	checkTaskListOrder(t, someday, []string{"Learn Go"})
+}
+
+func TestWorkspace_Query(t *testing.T) {
+	t.Parallel()
+
+	workspace := testWorkspace(t, map[string]string{
+		"todo.txt": "Call Mom +Family\nPick up milk\n",
+		"done.txt": "x Call Dad +Family\n",
+	})
+
+	found, err := workspace.Query(FilterByProject("family"))
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+
+	require.Equal(t, workspace.TodoFile, found[0].List)
+	require.Equal(t, "Call Mom +Family", found[0].Task.String())
+	require.Equal(t, workspace.DoneFile, found[1].List)
+	require.Equal(t, "x Call Dad +Family", found[1].Task.String())
+
+	require.NoError(t, os.WriteFile(workspace.DoneFile, []byte("2020-13-01 Task\n"), PermReadWrite))
+
+	_, err = workspace.Query(FilterCompleted)
+	require.Error(t, err)
+}
+
+func TestWorkspace_Move(t *testing.T) {
+	t.Parallel()
+
+	workspace := testWorkspace(t, map[string]string{
+		"todo.txt":    "Call Mom\nLearn Go\n",
+		"someday.txt": "Learn Rust\n",
+	})
+
+	moved, err := workspace.Move(2, "todo", "someday")
+	require.NoError(t, err)
+	require.Equal(t, "Learn Go", moved.String())
+	require.Equal(t, 2, moved.ID, "the ID should be the ID in the target list")
+
+	todo, err := workspace.Todo()
+	require.NoError(t, err)
+	checkTaskListOrder(t, todo, []string{"Call Mom"})
+
+	someday, err := workspace.Load("someday")
+	require.NoError(t, err)
+	checkTaskListOrder(t, someday, []string{"Learn Rust", "Learn Go"})
+
+	// Errors
+	_, err = workspace.Move(1, "todo", "todo.txt")
+	require.Error(t, err, "moving to the same list should be an error")
+
+	_, err = workspace.Move(99, "todo", "someday")
+	require.Error(t, err, "unknown task should be an error")
+
+	_, err = workspace.Move(1, "todo", filepath.Join(workspace.Dir, "unknown", "list.txt"))
+	require.Error(t, err, "lock error should be returned")
+
+	require.NoError(t, os.WriteFile(workspace.Path("broken"), []byte("2020-13-01 Task\n"), PermReadWrite))
+
+	_, err = workspace.Move(1, "broken", "someday")
+	require.Error(t, err, "source load error should be returned")
+
+	_, err = workspace.Move(1, "todo", "broken")
+	require.Error(t, err, "target load error should be returned")
+}
+
+func TestWorkspace_Move_keeps_lines(t *testing.T) {
+	t.Parallel()
+
+	workspace := testWorkspace(t, map[string]string{
+		"todo.txt":    "# Home\nBuy +Shop milk\n\nCall @Phone Mom\nPay bills\n",
+		"someday.txt": "# Later\nLearn +Go now\n",
+	})
+
+	moved, err := workspace.Move(2, "todo", "someday")
+	require.NoError(t, err)
+	require.Equal(t, "Call Mom @Phone", moved.String())
+	require.Equal(t, 2, moved.ID)
+
+	raw, err := os.ReadFile(workspace.TodoFile)
+	require.NoError(t, err)
+	require.Equal(t, "# Home\nBuy +Shop milk\n\n\nPay bills\n", string(raw),
+		"only the line of the task should be left blank")
+
+	raw, err = os.ReadFile(workspace.Path("someday"))
+	require.NoError(t, err)
+	require.Equal(t, "# Later\nLearn +Go now\nCall @Phone Mom\n", string(raw), "line should be appended as is")
+
+	// Last line is removed, new target list is created
+	_, err = workspace.Move(2, "todo", "new")
+	require.NoError(t, err)
+
+	raw, err = os.ReadFile(workspace.TodoFile)
+	require.NoError(t, err)
+	require.Equal(t, "# Home\nBuy +Shop milk\n\n\n", string(raw))
+
+	raw, err = os.ReadFile(workspace.Path("new"))
+	require.NoError(t, err)
+	require.Equal(t, "Pay bills\n", string(raw))
+}
+
+func TestWorkspace_Move_concurrent_archive(t *testing.T) {
+	t.Parallel()
+
+	workspace := testWorkspace(t, map[string]string{"todo.txt": strings.Repeat("x Done\nOpen\n", 20)})
+	errs := make(chan error, 40)
+
+	// Move locks the done file first by name order, Archive the todo file first
+	// by argument order: both must lock in the same order.
+	for range 20 {
+		go func() {
+			_, err := workspace.Move(1, "todo", "done")
+			errs <- err
+		}()
+
+		go func() {
+			_, err := workspace.Archive(ArchiveOptions{})
+			errs <- err
+		}()
+	}
+
+	for range 40 {
+		select {
+		case err := <-errs:
+			if err != nil {
+				require.ErrorContains(t, err, "failed to move task", "only a missing task should fail")
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("Move and Archive should not dead lock")
+		}
+	}
+}
+
+func TestWorkspace_Archive_and_Report(t *testing.T) {
+	t.Parallel()
+
+	workspace := testWorkspace(t, map[string]string{
+		"todo.txt": "Call Mom\nx Pick up milk\nLearn Go\n",
+		"done.txt": "x Water plants\n",
+	})
+	workspace.Clock = &fakeClock{now: time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local)}
+
+	entry, err := workspace.Report()
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-02T15:04:05 2 2", entry.String(), "report should archive first")
+
+	// No changes
+	workspace.Clock = &fakeClock{now: time.Date(2024, 1, 3, 0, 0, 0, 0, time.Local)}
+
+	entry, err = workspace.Report()
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-02T15:04:05 2 2", entry.String(), "unchanged report should return the last line")
+
+	// Changed
+	require.NoError(t, workspace.Update("", func(tasklist *TaskList) error {
+		(*tasklist)[0].Complete()
+
+		return nil
+	}))
+
+	entry, err = workspace.Report()
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-03T00:00:00 1 3", entry.String())
+
+	require.Equal(t, "2024-01-02T15:04:05 2 2\n2024-01-03T00:00:00 1 3\n", testReadFile(t, workspace.ReportFile))
+
+	history, err := workspace.ReportHistory()
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	// Lines are counted like todo.sh, comments included
+	workspace = testWorkspace(t, map[string]string{
+		"todo.txt": "# Home\nCall Mom\n\nx Pick up milk\n",
+		"done.txt": "# Archive\nx Water plants",
+	})
+	workspace.Clock = &fakeClock{now: time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local)}
+
+	entry, err = workspace.Report()
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-02T15:04:05 2 3", entry.String())
+}